@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/client"
@@ -12,16 +14,27 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: mcp-client <project_path> <query> [persona]")
+	format := flag.String("format", "markdown", "出力形式（markdown | sarif）")
+	scope := flag.String("scope", "full", "レビュー範囲（full | diff）")
+	mergeBase := flag.String("merge-base", "", "指定ブランチとの merge-base からの差分をレビューする（例: main）")
+	model := flag.String("model", "", "使用するモデル名（省略時はペルソナまたはサーバーの設定）")
+	panel := flag.String("panel", "", "パネルレビューに参加させるペルソナ名のカンマ区切り（例: architect,go-expert）。指定すると persona 引数は使わない")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mcp-client [--format markdown|sarif] [--scope full|diff] [--merge-base branch] [--model name] [--panel personas] <project_path> <query> [persona]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	projectPath := os.Args[1]
-	query := os.Args[2]
+	projectPath := flag.Arg(0)
+	query := flag.Arg(1)
 	personaName := "architect"
-	if len(os.Args) >= 4 {
-		personaName = os.Args[3]
+	if flag.NArg() >= 3 {
+		personaName = flag.Arg(2)
 	}
 
 	serverBin := os.Getenv("MCP_SERVER_BIN")
@@ -57,15 +70,32 @@ func main() {
 	fmt.Fprintf(os.Stderr, "Connected to: %s %s\n", initResult.ServerInfo.Name, initResult.ServerInfo.Version)
 
 	// --- review ツールの呼び出し ---
-	toolReq := mcp.CallToolRequest{}
-	toolReq.Params.Name = "review"
-	toolReq.Params.Arguments = map[string]any{
+	args := map[string]any{
 		"project_path": projectPath,
 		"query":        query,
 		"persona":      personaName,
+		"format":       *format,
+		"scope":        *scope,
+	}
+	if *mergeBase != "" {
+		args["merge_base"] = *mergeBase
+	}
+	if *model != "" {
+		args["model"] = *model
+	}
+	if *panel != "" {
+		args["panel"] = strings.Split(*panel, ",")
 	}
 
-	fmt.Fprintf(os.Stderr, "Reviewing %s with persona %q...\n", projectPath, personaName)
+	toolReq := mcp.CallToolRequest{}
+	toolReq.Params.Name = "review"
+	toolReq.Params.Arguments = args
+
+	if *panel != "" {
+		fmt.Fprintf(os.Stderr, "Reviewing %s with panel %q...\n", projectPath, *panel)
+	} else {
+		fmt.Fprintf(os.Stderr, "Reviewing %s with persona %q...\n", projectPath, personaName)
+	}
 
 	result, err := c.CallTool(ctx, toolReq)
 	if err != nil {
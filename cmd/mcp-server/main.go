@@ -5,17 +5,20 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	mcpserver "github.com/mark3labs/mcp-go/server"
 
+	"github.com/0muji4/llm-reviewer/internal/agent"
 	"github.com/0muji4/llm-reviewer/internal/server"
 )
 
 func main() {
 	// --- 環境変数の読み込み ---
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GEMINI_API_KEY is required")
+	provider, err := loadProviderConfig()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	personaDir := os.Getenv("PERSONA_DIR")
@@ -28,13 +31,21 @@ func main() {
 		personaDir = filepath.Join(wd, "configs", "personas")
 	}
 	// 相対パスを絶対パスに解決（子プロセスの CWD が異なる場合に備える）
-	personaDir, err := filepath.Abs(personaDir)
+	personaDir, err = filepath.Abs(personaDir)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// シンボル索引の保存先（SYMBOL_INDEX_DIR、デフォルト: ユーザーキャッシュディレクトリ）
+	indexDir := os.Getenv("SYMBOL_INDEX_DIR")
+	if indexDir == "" {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			indexDir = filepath.Join(cacheDir, "llm-reviewer", "symbols")
+		}
+	}
+
 	// --- DI: Adapter 層の組み立て ---
-	handler := server.NewReviewHandler(apiKey, personaDir)
+	handler := server.NewReviewHandler(provider, personaDir, indexDir)
 	s := server.New(handler)
 
 	// --- Framework: MCP stdio サーバーの起動 ---
@@ -43,3 +54,110 @@ func main() {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// loadProviderConfig は環境変数から LLM プロバイダの設定を組み立てます。
+//
+//	LLM_PROVIDER    gemini（デフォルト）| openai
+//	LLM_MODEL       モデル名（省略時はプロバイダごとのデフォルト）
+//	GEMINI_API_KEY  provider=gemini のとき必須
+//	OPENAI_API_KEY  provider=openai のときの API キー（ローカルサーバーなら省略可）
+//	OPENAI_BASE_URL OpenAI 互換エンドポイント（例: http://localhost:11434/v1）
+//
+// 生成パラメータの既定値（ペルソナや review ツールの引数で上書きできる）:
+//
+//	LLM_TEMPERATURE        サンプリングの温度
+//	LLM_TOP_P              top-p サンプリングの値
+//	LLM_MAX_OUTPUT_TOKENS  1回の応答の最大出力トークン数
+//	LLM_THINKING_BUDGET    思考に使うトークン数（Gemini のみ）
+//	LLM_SAFETY             有害カテゴリごとのブロックのしきい値（例: dangerous_content=block_only_high,harassment=block_none。Gemini のみ）
+func loadProviderConfig() (agent.ProviderConfig, error) {
+	cfg := agent.ProviderConfig{
+		Provider: os.Getenv("LLM_PROVIDER"),
+		Model:    os.Getenv("LLM_MODEL"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = agent.ProviderGemini
+	}
+
+	gen, err := loadGenerationConfig()
+	if err != nil {
+		return cfg, err
+	}
+	if err := gen.Validate(); err != nil {
+		return cfg, err
+	}
+	cfg.Generation = gen
+
+	switch cfg.Provider {
+	case agent.ProviderGemini:
+		cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+		if cfg.APIKey == "" {
+			return cfg, fmt.Errorf("GEMINI_API_KEY is required")
+		}
+	case agent.ProviderOpenAI:
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		cfg.BaseURL = os.Getenv("OPENAI_BASE_URL")
+	default:
+		return cfg, fmt.Errorf("unknown LLM_PROVIDER %q", cfg.Provider)
+	}
+	return cfg, nil
+}
+
+// loadGenerationConfig は環境変数から生成パラメータの既定値を読み込みます。
+func loadGenerationConfig() (agent.GenerationConfig, error) {
+	var gen agent.GenerationConfig
+	if v := os.Getenv("LLM_TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return gen, fmt.Errorf("invalid LLM_TEMPERATURE %q: %w", v, err)
+		}
+		gen.Temperature = &t
+	}
+	if v := os.Getenv("LLM_TOP_P"); v != "" {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return gen, fmt.Errorf("invalid LLM_TOP_P %q: %w", v, err)
+		}
+		gen.TopP = &p
+	}
+	if v := os.Getenv("LLM_MAX_OUTPUT_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return gen, fmt.Errorf("invalid LLM_MAX_OUTPUT_TOKENS %q: %w", v, err)
+		}
+		gen.MaxOutputTokens = n
+	}
+	if v := os.Getenv("LLM_THINKING_BUDGET"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return gen, fmt.Errorf("invalid LLM_THINKING_BUDGET %q: %w", v, err)
+		}
+		gen.ThinkingBudget = &n
+	}
+	if v := os.Getenv("LLM_SAFETY"); v != "" {
+		safety, err := parseSafety(v)
+		if err != nil {
+			return gen, fmt.Errorf("invalid LLM_SAFETY %q: %w", v, err)
+		}
+		gen.SafetySettings = safety
+	}
+	return gen, nil
+}
+
+// parseSafety は "カテゴリ=しきい値" をカンマで区切った安全性設定を読み込みます。
+// カテゴリ名としきい値の検証はモデルの生成時に行います。
+func parseSafety(s string) (map[string]string, error) {
+	safety := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		category, threshold, ok := strings.Cut(pair, "=")
+		category, threshold = strings.TrimSpace(category), strings.TrimSpace(threshold)
+		if !ok || category == "" || threshold == "" {
+			return nil, fmt.Errorf("want category=threshold, got %q", pair)
+		}
+		safety[category] = threshold
+	}
+	return safety, nil
+}
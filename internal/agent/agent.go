@@ -4,135 +4,192 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/0muji4/llm-reviewer/internal/depgraph"
 	"github.com/0muji4/llm-reviewer/internal/lsp"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+	"github.com/0muji4/llm-reviewer/internal/review"
 	"github.com/0muji4/llm-reviewer/internal/symbol"
 	"github.com/0muji4/llm-reviewer/internal/workspace"
-
-	"google.golang.org/genai"
 )
 
-const model = "gemini-2.5-flash"
-
 // L5Agent はLLMとコード解析ツールを統括する構造体です
 type L5Agent struct {
-	client       *genai.Client
-	analyzer     lsp.CodeAnalyzer
-	reader       workspace.FileReader
-	differ       workspace.DiffProvider
-	resolver     symbol.Resolver
-	systemPrompt string
-	history      []*genai.Content
-	rootPath     string
+	model    ChatModel
+	analyzer lsp.CodeAnalyzer
+	reader   workspace.FileReader
+	differ   workspace.DiffProvider
+	resolver symbol.Resolver
+	revs     workspace.RevisionReader // nil なら read-file はリビジョンを指定できない
+	searcher workspace.Searcher       // nil なら search-code ツールは公開しない
+	types    symbol.TypeChecker       // nil なら型情報ツールは公開しない
+	graph    depgraph.Source          // nil なら import-graph ツールは公開しない
+	layering *depgraph.Rules
+	apiDiff  symbol.APIComparer // nil なら api-diff ツールは公開しない
+	apiBase  string
+	apiHead  string
+	extra    []Tool    // WithTools で追加されたツール
+	tools    *Registry // モデルに公開するツール（ペルソナの許可リストで絞り込み済み）
+	limits   persona.ToolLimits
+	cache    *ToolCache // nil ならツールの結果を共有しない
+	persona  *persona.Persona
+	history  []Message
+	calls    []review.Evidence // モデルが行ったツール呼び出しのログ
+	rootPath string
+
+	diffOptions workspace.DiffOptions // get-diff を引数なしで呼んだときの差分
+	scope       workspace.Diff        // 差分スコープのレビューで指摘対象となる変更
+}
+
+// Option は L5Agent の任意設定です
+type Option func(*L5Agent)
+
+// WithDiffOptions はレビュー対象の差分を設定します。
+// get-diff ツールは引数が省略されたとき、この差分を返します。
+func WithDiffOptions(opts workspace.DiffOptions) Option {
+	return func(a *L5Agent) {
+		a.diffOptions = opts
+	}
+}
+
+// WithRevisionReader は read-file ツールで過去のリビジョンのファイルを読めるようにします
+func WithRevisionReader(r workspace.RevisionReader) Option {
+	return func(a *L5Agent) {
+		a.revs = r
+	}
+}
+
+// WithTools は組み込みのツールに加えてモデルに公開するツールを追加します
+func WithTools(tools ...Tool) Option {
+	return func(a *L5Agent) {
+		a.extra = append(a.extra, tools...)
+	}
+}
+
+// WithChangeScope は差分スコープのレビューを設定します。
+// モデルには変更されたハンクの行範囲が伝えられ、そこに対してのみ指摘するよう指示されます。
+func WithChangeScope(diff workspace.Diff) Option {
+	return func(a *L5Agent) {
+		a.scope = diff
+	}
 }
 
 func NewL5Agent(
-	ctx context.Context,
-	apiKey string,
+	model ChatModel,
 	rootPath string,
-	systemPrompt string,
+	p *persona.Persona,
 	analyzer lsp.CodeAnalyzer,
 	reader workspace.FileReader,
 	differ workspace.DiffProvider,
 	resolver symbol.Resolver,
-) (*L5Agent, error) {
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	opts ...Option,
+) *L5Agent {
+	a := &L5Agent{
+		model:    model,
+		analyzer: analyzer,
+		reader:   reader,
+		differ:   differ,
+		resolver: resolver,
+		limits:   p.Tools.Limits,
+		persona:  p,
+		rootPath: rootPath,
 	}
-
-	return &L5Agent{
-		client:       client,
-		analyzer:     analyzer,
-		reader:       reader,
-		differ:       differ,
-		resolver:     resolver,
-		systemPrompt: systemPrompt,
-		rootPath:     rootPath,
-	}, nil
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.tools = a.buildTools()
+	return a
 }
 
-// Run はユーザーの問いかけに対してReActループを実行します
-func (a *L5Agent) Run(ctx context.Context, userQuery string) (string, error) {
-	a.history = append(a.history, genai.NewContentFromText(userQuery, "user"))
+// buildTools はモデルに公開するツールを登録します。
+// 任意の依存（型情報・import グラフなど）を使うツールは、その依存が設定されたときだけ登録し、
+// ペルソナの tools.allow / tools.deny で許可されないツールは除きます
+func (a *L5Agent) buildTools() *Registry {
+	tools := a.lspTools()
+	tools = append(tools, a.callHierarchyTool(), a.diagnosticsTool())
+	tools = append(tools, a.fileTools()...)
+	tools = append(tools, a.diffTools()...)
+	tools = append(tools, a.findSymbolTool())
+
+	// 依存がなく公開しないツールも、ペルソナの設定の検証に使うので名前は集めておく
+	known := slices.Clone(tools)
+	optional := []struct {
+		enabled bool
+		tools   []Tool
+	}{
+		{a.types != nil, a.typeTools()},
+		{a.graph != nil, []Tool{a.importGraphTool()}},
+		{a.apiDiff != nil, []Tool{a.apiDiffTool()}},
+		{a.searcher != nil, []Tool{a.searchCodeTool()}},
+	}
+	for _, o := range optional {
+		known = append(known, o.tools...)
+		if o.enabled {
+			tools = append(tools, o.tools...)
+		}
+	}
+	tools = append(tools, a.extra...)
+	known = append(known, a.extra...)
+
+	r := NewRegistry()
+	for _, t := range tools {
+		if a.persona.Tools.Allows(t.Spec().Name) {
+			r.Register(t)
+		}
+	}
+	a.warnUnknownTools(known)
+	return r
+}
 
-	tools := []*genai.Tool{
-		{
-			FunctionDeclarations: []*genai.FunctionDeclaration{
-				{
-					Name:        "find-references",
-					Description: "指定されたファイル内の特定の行・文字位置にあるシンボルの参照元（References）を検索します。",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"file_path": {
-								Type:        genai.TypeString,
-								Description: "対象のファイルパス（プロジェクトルートからの相対パス）",
-							},
-							"line": {
-								Type:        genai.TypeInteger,
-								Description: "対象の行番号（1から始まる人間用の行番号）",
-							},
-							"character": {
-								Type:        genai.TypeInteger,
-								Description: "対象の文字位置（1から始まる文字カラム）",
-							},
-						},
-						Required: []string{"file_path", "line", "character"},
-					},
-				},
-				{
-					Name:        "read-file",
-					Description: "指定されたファイルの内容を読み取ります。コードの中身を確認したいときに使用してください。",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"file_path": {
-								Type:        genai.TypeString,
-								Description: "対象のファイルパス（プロジェクトルートからの相対パス）",
-							},
-						},
-						Required: []string{"file_path"},
-					},
-				},
-				{
-					Name:        "get-diff",
-					Description: "現在のGit差分（git diff HEAD）を取得します。コードレビューや変更内容の確認に使用してください。",
-					Parameters: &genai.Schema{
-						Type:       genai.TypeObject,
-						Properties: map[string]*genai.Schema{},
-					},
-				},
-				{
-					Name:        "find-symbol",
-					Description: "シンボル名（関数名、型名、変数名など）からソースコード上の定義位置（ファイルパス、行番号、文字位置）を検索します。シンボルの参照元を調べたいがファイルや行番号が不明な場合、まずこのツールで位置を特定してからfind_referencesを使ってください。",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"name": {
-								Type:        genai.TypeString,
-								Description: "検索するシンボル名（例: SurahService, NewClient, ListSurahs）",
-							},
-						},
-						Required: []string{"name"},
-					},
-				},
-			},
-		},
+// warnUnknownTools はペルソナの allow / deny のパターンと tool_guide のツール名のうち、
+// どのツールにも一致しないものを警告します。このレビューで公開していないツールも含めて照合するので、
+// 警告が出るのはツール名の誤記などで設定が効いていない場合だけです
+func (a *L5Agent) warnUnknownTools(known []Tool) {
+	matches := func(pattern string) bool {
+		return slices.ContainsFunc(known, func(t Tool) bool {
+			ok, _ := path.Match(pattern, t.Spec().Name)
+			return ok
+		})
+	}
+	for _, pattern := range append(slices.Clone(a.persona.Tools.Allow), a.persona.Tools.Deny...) {
+		if !matches(pattern) {
+			fmt.Fprintf(os.Stderr, "Warning: persona %q: tool pattern %q matches no known tool\n", a.persona.Name, pattern)
+		}
 	}
+	for _, h := range a.persona.ToolGuide {
+		for _, name := range h.Tools {
+			if !slices.ContainsFunc(known, func(t Tool) bool { return t.Spec().Name == name }) {
+				fmt.Fprintf(os.Stderr, "Warning: persona %q: tool_guide names unknown tool %q\n", a.persona.Name, name)
+			}
+		}
+	}
+}
 
-	config := &genai.GenerateContentConfig{
-		Tools: tools,
-		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{genai.NewPartFromText(a.systemPrompt)},
-		},
+// systemPrompt はペルソナのプロンプトを、このエージェントが公開しているツールのヒントだけを含めて返します
+func (a *L5Agent) systemPrompt() string {
+	return a.persona.Prompt(func(name string) bool {
+		_, ok := a.tools.Lookup(name)
+		return ok
+	})
+}
+
+// Tools はモデルに公開するツールを返します
+func (a *L5Agent) Tools() *Registry {
+	return a.tools
+}
+
+// Run はユーザーの問いかけに対してReActループを実行し、構造化されたレビュー結果を返します
+func (a *L5Agent) Run(ctx context.Context, userQuery string) (*review.Report, error) {
+	if a.scope != nil {
+		userQuery += "\n\n" + scopePrompt(a.scope)
 	}
+	a.history = append(a.history, Message{Role: RoleUser, Text: userQuery})
+
+	tools := a.tools.Specs()
 
 	const maxIterations = 10
 
@@ -140,140 +197,76 @@ func (a *L5Agent) Run(ctx context.Context, userQuery string) (string, error) {
 	for i := 0; i < maxIterations; i++ {
 		fmt.Fprintf(os.Stderr, "[%d/%d] Thinking...\n", i+1, maxIterations)
 
-		// レート制限対応: 429 エラー時はリトライ（最大2回）
-		var resp *genai.GenerateContentResponse
-		var err error
-		for retry := 0; retry < 3; retry++ {
-			resp, err = a.client.Models.GenerateContent(ctx, model, a.history, config)
-			if err == nil {
-				break
-			}
-			if strings.Contains(err.Error(), "429") && retry < 2 {
-				wait := time.Duration(30*(retry+1)) * time.Second
-				fmt.Fprintf(os.Stderr, "  Rate limited. Waiting %v...\n", wait)
-				select {
-				case <-time.After(wait):
-				case <-ctx.Done():
-					return "", ctx.Err()
-				}
-				continue
-			}
-			return "", fmt.Errorf("agent: generate content: %w", err)
+		resp, err := a.chat(ctx, &ChatRequest{
+			SystemPrompt: a.systemPrompt(),
+			Messages:     a.history,
+			Tools:        tools,
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		functionCalls := resp.FunctionCalls()
+		a.history = append(a.history, resp.Message)
+
+		functionCalls := resp.Message.ToolCalls
 		if len(functionCalls) == 0 {
-			return resp.Text(), nil
+			return a.finalize(ctx, resp.Message.Text)
 		}
 
-		a.history = append(a.history, resp.Candidates[0].Content)
-
-		var responseParts []*genai.Part
+		var results []ToolResult
 		for _, call := range functionCalls {
-			var resultText string
-			var execErr error
-
-			switch call.Name {
-			case "find-references":
-				filePath, _ := call.Args["file_path"].(string)
-				line := int(call.Args["line"].(float64))
-				char := int(call.Args["character"].(float64))
-				fmt.Fprintf(os.Stderr, "  Tool: find-references(%s, %d, %d)\n", filePath, line, char)
-				resultText, execErr = a.executeFindReferences(filePath, line, char)
-
-			case "read-file":
-				filePath, _ := call.Args["file_path"].(string)
-				fmt.Fprintf(os.Stderr, "  Tool: read-file(%s)\n", filePath)
-				resultText, execErr = a.reader.ReadFile(filePath)
-
-			case "get-diff":
-				fmt.Fprintln(os.Stderr, "  Tool: get-diff")
-				resultText, execErr = a.differ.Diff()
-				if resultText == "" && execErr == nil {
-					resultText = "No changes detected (working tree is clean)."
-				}
-
-			case "find-symbol":
-				name, _ := call.Args["name"].(string)
-				fmt.Fprintf(os.Stderr, "  Tool: find-symbol(%s)\n", name)
-				resultText, execErr = a.executeFindSymbol(name)
-			}
-
+			a.recordCall(call)
+			resultText, execErr := a.executeTool(ctx, call)
 			if execErr != nil {
 				resultText = fmt.Sprintf("Error: %v", execErr)
 			}
 
-			responseParts = append(responseParts, genai.NewPartFromFunctionResponse(
-				call.Name,
-				map[string]any{"result": resultText},
-			))
+			results = append(results, ToolResult{
+				CallID:  call.ID,
+				Name:    call.Name,
+				Content: resultText,
+			})
 		}
 
-		a.history = append(a.history, &genai.Content{
-			Role:  "tool",
-			Parts: responseParts,
+		a.history = append(a.history, Message{
+			Role:        RoleTool,
+			ToolResults: results,
 		})
 
 		// ループ終盤で最終回答を促す
 		if i == maxIterations-2 {
-			a.history = append(a.history, genai.NewContentFromText(
-				"残りのツール呼び出しは1回です。これまでに収集した情報に基づいて、最終的なレビュー結果をテキストで出力してください。",
-				"user",
-			))
+			a.history = append(a.history, Message{
+				Role: RoleUser,
+				Text: "残りのツール呼び出しは1回です。これまでに収集した情報に基づいて、最終的なレビュー結果をテキストで出力してください。",
+			})
 		}
 	}
 
-	return "", fmt.Errorf("agent: loop limit exceeded")
+	return nil, fmt.Errorf("agent: loop limit exceeded")
 }
 
-func (a *L5Agent) executeFindSymbol(name string) (string, error) {
-	locations, err := a.resolver.FindSymbol(name)
-	if err != nil {
-		return "", fmt.Errorf("agent: find symbol %q: %w", name, err)
-	}
-
-	if len(locations) == 0 {
-		return fmt.Sprintf("Symbol %q not found.", name), nil
-	}
-
-	var result []string
-	for _, loc := range locations {
-		result = append(result, fmt.Sprintf("%s:%d:%d", loc.FilePath, loc.Line, loc.Character))
-	}
-
-	output := fmt.Sprintf("Found symbol %q at:\n%s", name, strings.Join(result, "\n"))
-	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
-	return output, nil
+// chat はモデルを呼び出します
+func (a *L5Agent) chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return chatWithRetry(ctx, a.model, req)
 }
 
-func (a *L5Agent) executeFindReferences(relPath string, line, char int) (string, error) {
-	absPath := filepath.Join(a.rootPath, relPath)
-
-	// LSPは 0-based index なので -1 する
-	lspLine := line - 1
-	lspChar := char - 1
-
-	fmt.Fprintf(os.Stderr, "   -> Searching in %s at %d:%d\n", relPath, lspLine, lspChar)
-
-	refs, err := a.analyzer.References(absPath, lspLine, lspChar)
-	if err != nil {
-		return "", fmt.Errorf("agent: find references %s:%d:%d: %w", relPath, line, char, err)
-	}
-
-	var result []string
-	for _, ref := range refs {
-		path := strings.TrimPrefix(ref.URI, "file://")
-		if rel, err := filepath.Rel(a.rootPath, path); err == nil {
-			path = rel
+// chatWithRetry はモデルを呼び出します。レート制限（429）時は待機してリトライします（最大2回）
+func chatWithRetry(ctx context.Context, model ChatModel, req *ChatRequest) (*ChatResponse, error) {
+	for retry := 0; ; retry++ {
+		resp, err := model.Chat(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if !strings.Contains(err.Error(), "429") || retry >= 2 {
+			return nil, fmt.Errorf("agent: generate content: %w", err)
 		}
-		result = append(result, fmt.Sprintf("%s:%d", path, ref.Range.Start.Line+1))
-	}
 
-	if len(result) == 0 {
-		return "No references found.", nil
+		wait := time.Duration(30*(retry+1)) * time.Second
+		fmt.Fprintf(os.Stderr, "  Rate limited. Waiting %v...\n", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-
-	output := fmt.Sprintf("Found references:\n%s", strings.Join(result, "\n"))
-	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
-	return output, nil
 }
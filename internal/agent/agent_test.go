@@ -0,0 +1,298 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/agent"
+	"github.com/0muji4/llm-reviewer/internal/agent/agenttest"
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+)
+
+const (
+	root        = "/project"
+	emptyReport = `{"summary": "ok", "findings": []}`
+	nudge       = "残りのツール呼び出しは1回です"
+)
+
+// deps はテスト用エージェントの依存です。nil のフィールドは空のスタブになります
+type deps struct {
+	root     string // 空なら root
+	persona  *persona.Persona
+	analyzer *agenttest.StubAnalyzer
+	reader   agenttest.StubReader
+	differ   *agenttest.StubDiffer
+	resolver agenttest.StubResolver
+}
+
+func newAgent(model agent.ChatModel, d deps) *agent.L5Agent {
+	if d.persona == nil {
+		d.persona = &persona.Persona{Name: "tester"}
+	}
+	if d.analyzer == nil {
+		d.analyzer = &agenttest.StubAnalyzer{}
+	}
+	if d.differ == nil {
+		d.differ = &agenttest.StubDiffer{}
+	}
+	if d.root == "" {
+		d.root = root
+	}
+	return agent.NewL5Agent(model, d.root, d.persona, d.analyzer, d.reader, d.differ, d.resolver)
+}
+
+// runTool はツールを1回呼んでから最終回答する台本でエージェントを実行し、ツールの結果を返します
+func runTool(t *testing.T, d deps, name string, args map[string]any) string {
+	t.Helper()
+	model := agenttest.NewScriptedModel(agenttest.Call(name, args), agenttest.Reply("done"), agenttest.Reply(emptyReport))
+	if _, err := newAgent(model, d).Run(context.Background(), "review"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	results := model.ToolResults()
+	if len(results) != 1 {
+		t.Fatalf("got %d tool results, want 1", len(results))
+	}
+	return results[0].Content
+}
+
+func TestRunDispatchesToolCalls(t *testing.T) {
+	d := deps{
+		reader:   agenttest.StubReader{"main.go": "package main\n"},
+		differ:   &agenttest.StubDiffer{Patch: "diff --git a/main.go b/main.go\n"},
+		resolver: agenttest.StubResolver{"main": {{FilePath: "main.go", Line: 3, Character: 6, Kind: symbol.KindFunction}}},
+	}
+	model := agenttest.NewScriptedModel(
+		agenttest.Calls(
+			agent.ToolCall{Name: "find-symbol", Args: map[string]any{"name": "main"}},
+			agent.ToolCall{ID: "given", Name: "read-file", Args: map[string]any{"file_path": "main.go"}},
+		),
+		agenttest.Call("get-diff", nil),
+		agenttest.Call("no-such-tool", nil),
+		agenttest.Call("read-file", map[string]any{}),
+		agenttest.Reply("done"),
+		agenttest.Reply(emptyReport),
+	)
+
+	report, err := newAgent(model, d).Run(context.Background(), "review")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Summary != "ok" {
+		t.Errorf("Summary = %q, want %q", report.Summary, "ok")
+	}
+	if n := model.Remaining(); n != 0 {
+		t.Errorf("%d scripted steps left unused", n)
+	}
+
+	want := []struct {
+		id, name, content string
+	}{
+		{"call-1", "find-symbol", "main.go:3:6 function"},
+		{"given", "read-file", "1\tpackage main"},
+		{"call-2", "get-diff", "diff --git a/main.go b/main.go"},
+		{"call-3", "no-such-tool", `Error: unknown tool "no-such-tool"`},
+		{"call-4", "read-file", "Error: "},
+	}
+	results := model.ToolResults()
+	if len(results) != len(want) {
+		t.Fatalf("got %d tool results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.CallID != w.id || r.Name != w.name || !strings.Contains(r.Content, w.content) {
+			t.Errorf("results[%d] = %s %s %q; want %s %s containing %q", i, r.CallID, r.Name, r.Content, w.id, w.name, w.content)
+		}
+	}
+}
+
+func TestRunLoop(t *testing.T) {
+	call := agenttest.Call("read-file", map[string]any{"file_path": "main.go"})
+	script := func(toolTurns int, final ...agenttest.Step) []agenttest.Step {
+		var steps []agenttest.Step
+		for range toolTurns {
+			steps = append(steps, call)
+		}
+		return append(steps, final...)
+	}
+
+	tests := []struct {
+		name      string
+		steps     []agenttest.Step
+		wantErr   string
+		nudgedAt  int // 最後に促しを含むリクエストの番号（1始まり）。0 なら促さない
+		wantChats int
+	}{
+		{
+			name:      "answers before the limit",
+			steps:     script(3, agenttest.Reply("done"), agenttest.Reply(emptyReport)),
+			wantChats: 5,
+		},
+		{
+			name:      "nudged on the last iteration",
+			steps:     script(9, agenttest.Reply("done"), agenttest.Reply(emptyReport)),
+			nudgedAt:  10,
+			wantChats: 11,
+		},
+		{
+			name:      "loop limit exceeded",
+			steps:     script(10),
+			wantErr:   "agent: loop limit exceeded",
+			nudgedAt:  10,
+			wantChats: 10,
+		},
+		{
+			name:      "model error",
+			steps:     script(1, agenttest.Fail(errors.New("backend down"))),
+			wantErr:   "backend down",
+			wantChats: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := agenttest.NewScriptedModel(tt.steps...)
+			d := deps{reader: agenttest.StubReader{"main.go": "package main\n"}}
+			_, err := newAgent(model, d).Run(context.Background(), "review")
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Run error = %v, want %q", err, tt.wantErr)
+			}
+
+			requests := model.Requests()
+			if len(requests) != tt.wantChats {
+				t.Fatalf("model called %d times, want %d", len(requests), tt.wantChats)
+			}
+			for i, req := range requests {
+				last := req.Messages[len(req.Messages)-1]
+				nudged := last.Role == agent.RoleUser && strings.Contains(last.Text, nudge)
+				if want := i+1 == tt.nudgedAt; nudged != want {
+					t.Errorf("request %d: nudged = %v, want %v", i+1, nudged, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindSymbol(t *testing.T) {
+	resolver := agenttest.StubResolver{
+		"Close": {
+			{FilePath: "lsp/client.go", Line: 40, Character: 18, Name: "lsp.Client.Close", Kind: symbol.KindMethod, Signature: "func (c *Client) Close() error"},
+			{FilePath: "lsp/conn.go", Line: 12, Character: 6, Name: "closeAll", Kind: symbol.KindFunction, Fuzzy: true},
+		},
+		"Clos": {
+			{FilePath: "lsp/client.go", Line: 40, Character: 18, Name: "lsp.Client.Close", Kind: symbol.KindMethod, Fuzzy: true},
+		},
+		"Config": {
+			{FilePath: "config.go", Line: 5, Character: 6, Kind: symbol.KindStruct},
+		},
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{
+			name: "exact and fuzzy matches",
+			args: map[string]any{"name": "Close"},
+			want: `Found symbol "Close" at:
+lsp/client.go:40:18 method lsp.Client.Close
+    func (c *Client) Close() error
+
+Other symbols with similar names:
+lsp/conn.go:12:6 function closeAll`,
+		},
+		{
+			name: "fuzzy matches only",
+			args: map[string]any{"name": "Clos"},
+			want: `No exact match for symbol "Clos"; closest candidates:
+lsp/client.go:40:18 method lsp.Client.Close`,
+		},
+		{
+			name: "filtered by kind",
+			args: map[string]any{"name": "Close", "kind": symbol.KindFunction},
+			want: `No exact match for symbol "Close"; closest candidates:
+lsp/conn.go:12:6 function closeAll`,
+		},
+		{
+			name: "unqualified name",
+			args: map[string]any{"name": "Config"},
+			want: `Found symbol "Config" at:
+config.go:5:6 struct`,
+		},
+		{
+			name: "not found",
+			args: map[string]any{"name": "Missing"},
+			want: `Symbol "Missing" not found.`,
+		},
+		{
+			name: "no match for kind",
+			args: map[string]any{"name": "Config", "kind": symbol.KindInterface},
+			want: `Symbol "Config" not found.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runTool(t, deps{resolver: resolver}, "find-symbol", tt.args)
+			if got != tt.want {
+				t.Errorf("find-symbol =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	loc := func(path string, line int) lsp.Location {
+		return lsp.Location{URI: "file://" + path, Range: lsp.Range{Start: lsp.Position{Line: line}}}
+	}
+	// find-references の line・character は1始まり、LSP へは0始まりで渡る
+	at := agenttest.Position{FilePath: root + "/a.go", Line: 2, Character: 4}
+	refs := []lsp.Location{loc(root+"/a.go", 2), loc(root+"/b/b.go", 9), loc("/elsewhere/c.go", 0)}
+	args := map[string]any{"file_path": "a.go", "line": 3, "character": 5}
+
+	tests := []struct {
+		name          string
+		analyzer      *agenttest.StubAnalyzer
+		maxReferences int
+		want          string
+	}{
+		{
+			name:     "references relative to the root",
+			analyzer: &agenttest.StubAnalyzer{Refs: map[agenttest.Position][]lsp.Location{at: refs}},
+			want:     "Found references:\na.go:3\nb/b.go:10\n/elsewhere/c.go:1",
+		},
+		{
+			name:          "limited by the persona",
+			analyzer:      &agenttest.StubAnalyzer{Refs: map[agenttest.Position][]lsp.Location{at: refs}},
+			maxReferences: 2,
+			want:          "Found references:\na.go:3\nb/b.go:10\n... and 1 more",
+		},
+		{
+			name:     "no references",
+			analyzer: &agenttest.StubAnalyzer{},
+			want:     "No references found.",
+		},
+		{
+			name:     "analyzer error",
+			analyzer: &agenttest.StubAnalyzer{Err: errors.New("gopls crashed")},
+			want:     "Error: agent: find references a.go:3:5: gopls crashed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &persona.Persona{Name: "tester", Tools: persona.Tools{Limits: persona.ToolLimits{MaxReferences: tt.maxReferences}}}
+			got := runTool(t, deps{persona: p, analyzer: tt.analyzer}, "find-references", args)
+			if got != tt.want {
+				t.Errorf("find-references =\n%s\nwant\n%s", got, tt.want)
+			}
+			if queries := tt.analyzer.Queries(); len(queries) != 1 || queries[0] != at {
+				t.Errorf("analyzer queried at %+v, want [%+v]", queries, at)
+			}
+		})
+	}
+}
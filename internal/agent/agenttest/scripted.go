@@ -0,0 +1,128 @@
+// Package agenttest provides deterministic stand-ins for the LLM backend and
+// the code analysis dependencies of agent.L5Agent, so the ReAct loop can be
+// exercised offline.
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/0muji4/llm-reviewer/internal/agent"
+)
+
+var _ agent.ChatModel = (*ScriptedModel)(nil)
+
+// Step is one canned reply of a ScriptedModel.
+// Exactly one of Text, ToolCalls or Err is normally set.
+type Step struct {
+	Text      string
+	ToolCalls []agent.ToolCall
+	Err       error
+}
+
+// Reply returns a step answering with plain text (a final answer).
+func Reply(text string) Step {
+	return Step{Text: text}
+}
+
+// Call returns a step requesting a single tool call.
+func Call(name string, args map[string]any) Step {
+	return Calls(agent.ToolCall{Name: name, Args: args})
+}
+
+// Calls returns a step requesting several tool calls in one turn.
+// When the step is replayed, Chat gives calls without an ID a sequential one
+// so tool results can be matched.
+func Calls(calls ...agent.ToolCall) Step {
+	return Step{ToolCalls: calls}
+}
+
+// Fail returns a step whose Chat call fails with err.
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// ScriptedModel replays a fixed sequence of replies and records every request
+// it receives. It is safe for concurrent use.
+type ScriptedModel struct {
+	mu       sync.Mutex
+	steps    []Step
+	requests []agent.ChatRequest
+	callSeq  int
+}
+
+// NewScriptedModel returns a model that answers with steps in order.
+func NewScriptedModel(steps ...Step) *ScriptedModel {
+	return &ScriptedModel{steps: steps}
+}
+
+func (m *ScriptedModel) Chat(_ context.Context, req *agent.ChatRequest) (*agent.ChatResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 履歴スライスは呼び出し側で追記され続けるのでコピーして記録する
+	snapshot := *req
+	snapshot.Messages = append([]agent.Message(nil), req.Messages...)
+	snapshot.Tools = append([]agent.ToolSpec(nil), req.Tools...)
+	m.requests = append(m.requests, snapshot)
+
+	turn := len(m.requests)
+	if turn > len(m.steps) {
+		return nil, fmt.Errorf("agenttest: script exhausted after %d steps", len(m.steps))
+	}
+
+	step := m.steps[turn-1]
+	if step.Err != nil {
+		return nil, step.Err
+	}
+
+	msg := agent.Message{Role: agent.RoleModel, Text: step.Text}
+	for _, call := range step.ToolCalls {
+		if call.ID == "" {
+			m.callSeq++
+			call.ID = fmt.Sprintf("call-%d", m.callSeq)
+		}
+		msg.ToolCalls = append(msg.ToolCalls, call)
+	}
+	return &agent.ChatResponse{Message: msg}, nil
+}
+
+// Requests returns the requests received so far, in order.
+func (m *ScriptedModel) Requests() []agent.ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]agent.ChatRequest(nil), m.requests...)
+}
+
+// LastRequest returns the most recent request, or nil if none was received.
+func (m *ScriptedModel) LastRequest() *agent.ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		return nil
+	}
+	req := m.requests[len(m.requests)-1]
+	return &req
+}
+
+// ToolResults returns every tool result the agent sent back to the model,
+// in the order they appeared in the final recorded conversation.
+func (m *ScriptedModel) ToolResults() []agent.ToolResult {
+	last := m.LastRequest()
+	if last == nil {
+		return nil
+	}
+	var results []agent.ToolResult
+	for _, msg := range last.Messages {
+		results = append(results, msg.ToolResults...)
+	}
+	return results
+}
+
+// Remaining reports how many scripted steps have not been consumed.
+func (m *ScriptedModel) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return max(len(m.steps)-len(m.requests), 0)
+}
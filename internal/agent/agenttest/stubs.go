@@ -0,0 +1,172 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+	"github.com/0muji4/llm-reviewer/internal/workspace"
+)
+
+var (
+	_ lsp.CodeAnalyzer       = (*StubAnalyzer)(nil)
+	_ workspace.FileReader   = StubReader(nil)
+	_ workspace.DiffProvider = (*StubDiffer)(nil)
+	_ symbol.Resolver        = StubResolver(nil)
+)
+
+// Position identifies a 0-based LSP position in a file, as passed to
+// lsp.CodeAnalyzer.References.
+type Position struct {
+	FilePath  string // absolute path
+	Line      int
+	Character int
+}
+
+// StubAnalyzer answers queries from fixed tables and records them.
+// It is safe for concurrent use.
+type StubAnalyzer struct {
+	Refs            map[Position][]lsp.Location
+	Definitions     map[Position][]lsp.Location
+	TypeDefinitions map[Position][]lsp.Location
+	Implementations map[Position][]lsp.Location
+	Hovers          map[Position]*lsp.Hover
+	CallItems       map[Position][]lsp.CallHierarchyItem
+	Incoming        map[string][]lsp.CallHierarchyIncomingCall // CallHierarchyItem.Name で引く
+	Outgoing        map[string][]lsp.CallHierarchyOutgoingCall // CallHierarchyItem.Name で引く
+	Diags           map[string][]lsp.Diagnostic                // 絶対パスで引く
+	Err             error
+
+	mu      sync.Mutex
+	queries []Position
+}
+
+func (s *StubAnalyzer) record(filePath string, line, char int) (Position, error) {
+	pos := Position{FilePath: filePath, Line: line, Character: char}
+	s.mu.Lock()
+	s.queries = append(s.queries, pos)
+	s.mu.Unlock()
+	return pos, s.Err
+}
+
+// Queries returns the positions queried so far, in order.
+func (s *StubAnalyzer) Queries() []Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Position(nil), s.queries...)
+}
+
+func (s *StubAnalyzer) References(_ context.Context, filePath string, line, char int) ([]lsp.Location, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.Refs[pos], nil
+}
+
+func (s *StubAnalyzer) Definition(_ context.Context, filePath string, line, char int) ([]lsp.Location, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.Definitions[pos], nil
+}
+
+func (s *StubAnalyzer) TypeDefinition(_ context.Context, filePath string, line, char int) ([]lsp.Location, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.TypeDefinitions[pos], nil
+}
+
+func (s *StubAnalyzer) Implementation(_ context.Context, filePath string, line, char int) ([]lsp.Location, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.Implementations[pos], nil
+}
+
+func (s *StubAnalyzer) Hover(_ context.Context, filePath string, line, char int) (*lsp.Hover, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.Hovers[pos], nil
+}
+
+func (s *StubAnalyzer) PrepareCallHierarchy(_ context.Context, filePath string, line, char int) ([]lsp.CallHierarchyItem, error) {
+	pos, err := s.record(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+	return s.CallItems[pos], nil
+}
+
+func (s *StubAnalyzer) IncomingCalls(_ context.Context, item lsp.CallHierarchyItem) ([]lsp.CallHierarchyIncomingCall, error) {
+	return s.Incoming[item.Name], s.Err
+}
+
+func (s *StubAnalyzer) OutgoingCalls(_ context.Context, item lsp.CallHierarchyItem) ([]lsp.CallHierarchyOutgoingCall, error) {
+	return s.Outgoing[item.Name], s.Err
+}
+
+func (s *StubAnalyzer) Diagnostics(_ context.Context, filePaths []string) (map[string][]lsp.Diagnostic, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	result := make(map[string][]lsp.Diagnostic, len(filePaths))
+	for _, p := range filePaths {
+		result[p] = s.Diags[p]
+	}
+	return result, nil
+}
+
+func (s *StubAnalyzer) Close() error {
+	return nil
+}
+
+// StubReader serves file contents from memory, keyed by relative path.
+type StubReader map[string]string
+
+func (r StubReader) ReadFile(path string) (string, error) {
+	content, ok := r[path]
+	if !ok {
+		return "", fmt.Errorf("open %s: no such file", path)
+	}
+	return content, nil
+}
+
+// StubDiffer returns a fixed diff and records the options it was asked for.
+// It is safe for concurrent use.
+type StubDiffer struct {
+	Patch string
+	Err   error
+
+	mu      sync.Mutex
+	queries []workspace.DiffOptions
+}
+
+func (d *StubDiffer) Diff(opts workspace.DiffOptions) (string, error) {
+	d.mu.Lock()
+	d.queries = append(d.queries, opts)
+	d.mu.Unlock()
+	return d.Patch, d.Err
+}
+
+// Queries returns the options Diff was called with so far, in order.
+func (d *StubDiffer) Queries() []workspace.DiffOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]workspace.DiffOptions(nil), d.queries...)
+}
+
+// StubResolver resolves symbols from a fixed table keyed by name.
+type StubResolver map[string][]symbol.SymbolLocation
+
+func (r StubResolver) FindSymbol(_ context.Context, name string) ([]symbol.SymbolLocation, error) {
+	return r[name], nil
+}
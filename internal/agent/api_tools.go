@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+)
+
+// maxAPIChanges はツール結果に含める公開 API の変更の最大件数です
+const maxAPIChanges = 200
+
+// WithAPIDiff は api-diff ツールを有効にします。base と head は引数が省略されたときの比較対象で、
+// head が空なら作業ツリーと比較します
+func WithAPIDiff(comparer symbol.APIComparer, base, head string) Option {
+	return func(a *L5Agent) {
+		a.apiDiff = comparer
+		a.apiBase = base
+		a.apiHead = head
+	}
+}
+
+type apiDiffArgs struct {
+	Base            string `json:"base"`
+	Head            string `json:"head"`
+	Package         string `json:"package"`
+	IncludeInternal bool   `json:"include_internal"`
+}
+
+func (a *L5Agent) apiDiffTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "api-diff",
+		Description: "2つのリビジョン間でパッケージの公開 API を型検査に基づいて比較し、追加・削除・互換性のない変更を返します。変更がライブラリの利用者を壊さないかを確認するときに使用してください。引数を省略するとレビュー対象の差分の比較元と比較先を使います。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"base": {
+					Type:        TypeString,
+					Description: "比較元のリビジョン（ブランチ名・タグ・コミット）",
+				},
+				"head": {
+					Type:        TypeString,
+					Description: "比較先のリビジョン（省略時はレビュー対象の差分の比較先。通常は作業ツリー）",
+				},
+				"package": {
+					Type:        TypeString,
+					Description: "比較するパッケージ（インポートパスまたはモジュールルートからのディレクトリ。末尾 /... で配下すべて）",
+				},
+				"include_internal": {
+					Type:        TypeBoolean,
+					Description: "internal パッケージも比較する（既定ではモジュール外から import できる公開パッケージのみ）",
+				},
+			},
+		},
+	}, func(ctx context.Context, args apiDiffArgs) (string, error) {
+		return a.executeAPIDiff(ctx, symbol.APIDiffOptions{
+			Base:            cmp.Or(args.Base, a.apiBase),
+			Head:            cmp.Or(args.Head, a.apiHead),
+			Package:         args.Package,
+			IncludeInternal: args.IncludeInternal,
+		})
+	})
+}
+
+func (a *L5Agent) executeAPIDiff(ctx context.Context, opts symbol.APIDiffOptions) (string, error) {
+	diff, err := a.apiDiff.Diff(ctx, opts)
+	if err != nil {
+		return "", fmt.Errorf("agent: api diff %s..%s: %w", opts.Base, opts.Head, err)
+	}
+
+	var b strings.Builder
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(&b, "No exported API changes between %s and %s.\n", diff.Base, diff.HeadName())
+	} else {
+		breaking := diff.Breaking()
+		fmt.Fprintf(&b, "Exported API changes between %s and %s (%d, %d incompatible):\n", diff.Base, diff.HeadName(), len(diff.Changes), len(breaking))
+		// 互換性のない変更を先に並べ、件数の上限で切られないようにする
+		ordered := append(breaking, nonBreaking(diff.Changes)...)
+		for i, c := range ordered {
+			if i == maxAPIChanges {
+				fmt.Fprintf(&b, "... and %d more\n", len(ordered)-maxAPIChanges)
+				break
+			}
+			mark := ""
+			if c.Breaking {
+				mark = " [incompatible]"
+			}
+			fmt.Fprintf(&b, "%s%s\n", c, mark)
+		}
+	}
+	if len(diff.Incomplete) > 0 {
+		fmt.Fprintf(&b, "\nType errors in %s; results for them may be incomplete.\n", strings.Join(diff.Incomplete, ", "))
+	}
+	return b.String(), nil
+}
+
+func nonBreaking(changes []symbol.APIChange) []symbol.APIChange {
+	var out []symbol.APIChange
+	for _, c := range changes {
+		if !c.Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ツール引数（JSON をデコードした map）をスキーマに照らして検証します。
+// モデルの出力は型が保証されないので、デコード前にここで弾き、モデルが直せるエラーを返します。
+
+// validateArgs は未知の引数・必須引数の欠落・型と列挙値の不一致をまとめて報告します。
+// 値が null の引数は省略されたものとして扱います
+func validateArgs(schema *Schema, args map[string]any) error {
+	if schema == nil {
+		schema = &Schema{Type: TypeObject}
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(args) {
+		if _, ok := schema.Properties[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown argument %q (expected: %s)", name, strings.Join(sortedKeys(schema.Properties), ", ")))
+		}
+	}
+	for _, name := range schema.Required {
+		if v, ok := args[name]; !ok || v == nil || v == "" {
+			errs = append(errs, fmt.Errorf("missing argument %q", name))
+		}
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		v, ok := args[name]
+		if !ok || v == nil {
+			continue
+		}
+		if err := checkValue(schema.Properties[name], v); err != nil {
+			errs = append(errs, fmt.Errorf("argument %q %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeArgs は配列の引数に文字列1つが渡されたとき、要素1つの配列として扱います。
+// モデルは paths: "internal/agent" のように単一の値を配列にせず渡すことがあるためです
+func normalizeArgs(schema *Schema, args map[string]any) map[string]any {
+	if schema == nil {
+		return args
+	}
+	out := make(map[string]any, len(args))
+	for name, v := range args {
+		if prop, ok := schema.Properties[name]; ok && prop.Type == TypeArray {
+			switch v := v.(type) {
+			case string:
+				out[name] = []any{v}
+				continue
+			case []string:
+				items := make([]any, len(v))
+				for i, s := range v {
+					items[i] = s
+				}
+				out[name] = items
+				continue
+			}
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// checkValue は値がスキーマの型（と列挙値）に合うかを調べます
+func checkValue(s *Schema, v any) error {
+	switch s.Type {
+	case TypeString:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("must be a string, got %s", jsonType(v))
+		}
+		if len(s.Enum) > 0 && str != "" && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("must be one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+	case TypeInteger:
+		f, ok := v.(float64)
+		if !ok {
+			if _, isInt := v.(int); isInt {
+				return nil
+			}
+			return fmt.Errorf("must be an integer, got %s", jsonType(v))
+		}
+		if f != math.Trunc(f) {
+			return fmt.Errorf("must be an integer, got %v", f)
+		}
+	case TypeNumber:
+		switch v.(type) {
+		case float64, int:
+		default:
+			return fmt.Errorf("must be a number, got %s", jsonType(v))
+		}
+	case TypeBoolean:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("must be a boolean, got %s", jsonType(v))
+		}
+	case TypeArray:
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("must be an array, got %s", jsonType(v))
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range items {
+			if err := checkValue(s.Items, item); err != nil {
+				return fmt.Errorf("[%d] %w", i, err)
+			}
+		}
+	case TypeObject:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("must be an object, got %s", jsonType(v))
+		}
+		if err := validateArgs(s, obj); err != nil {
+			return fmt.Errorf("is invalid: %w", err)
+		}
+	}
+	return nil
+}
+
+// jsonType はエラーメッセージ用に値の JSON での型名を返します
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return TypeString
+	case float64, int:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case []any:
+		return TypeArray
+	case map[string]any:
+		return TypeObject
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testSchema = &Schema{
+	Type: TypeObject,
+	Properties: map[string]*Schema{
+		"file_path": {Type: TypeString},
+		"line":      {Type: TypeInteger},
+		"ratio":     {Type: TypeNumber},
+		"verbose":   {Type: TypeBoolean},
+		"kind":      {Type: TypeString, Enum: []string{"function", "method"}},
+		"paths":     {Type: TypeArray, Items: &Schema{Type: TypeString}},
+		"range": {
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"start": {Type: TypeInteger},
+				"end":   {Type: TypeInteger},
+			},
+			Required: []string{"start"},
+		},
+	},
+	Required: []string{"file_path"},
+}
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want []string // エラーメッセージに含まれるべき文字列。空なら成功
+	}{
+		{
+			name: "valid",
+			args: map[string]any{
+				"file_path": "a.go", "line": float64(3), "ratio": 0.5, "verbose": true, "kind": "method",
+				"paths": []any{"a", "b"}, "range": map[string]any{"start": float64(1)},
+			},
+		},
+		{
+			name: "null counts as omitted",
+			args: map[string]any{"file_path": "a.go", "line": nil},
+		},
+		{
+			name: "empty enum value is allowed",
+			args: map[string]any{"file_path": "a.go", "kind": ""},
+		},
+		{
+			name: "missing required",
+			args: map[string]any{"line": float64(1)},
+			want: []string{`missing argument "file_path"`},
+		},
+		{
+			name: "empty required string",
+			args: map[string]any{"file_path": ""},
+			want: []string{`missing argument "file_path"`},
+		},
+		{
+			name: "unknown argument lists the expected ones",
+			args: map[string]any{"file_path": "a.go", "filepath": "a.go"},
+			want: []string{`unknown argument "filepath" (expected: file_path, kind, line, paths, range, ratio, verbose)`},
+		},
+		{
+			name: "type mismatches are all reported",
+			args: map[string]any{"file_path": float64(1), "line": "3", "verbose": "yes", "ratio": "x"},
+			want: []string{
+				`argument "file_path" must be a string, got number`,
+				`argument "line" must be an integer, got string`,
+				`argument "verbose" must be a boolean, got string`,
+				`argument "ratio" must be a number, got string`,
+			},
+		},
+		{
+			name: "fractional integer",
+			args: map[string]any{"file_path": "a.go", "line": 2.5},
+			want: []string{`argument "line" must be an integer, got 2.5`},
+		},
+		{
+			name: "enum",
+			args: map[string]any{"file_path": "a.go", "kind": "struct"},
+			want: []string{`argument "kind" must be one of function, method, got "struct"`},
+		},
+		{
+			name: "array item",
+			args: map[string]any{"file_path": "a.go", "paths": []any{"a", float64(2)}},
+			want: []string{`argument "paths" [1] must be a string, got number`},
+		},
+		{
+			name: "nested object",
+			args: map[string]any{"file_path": "a.go", "range": map[string]any{"end": float64(2)}},
+			want: []string{`argument "range" is invalid: missing argument "start"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArgs(testSchema, tt.args)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("validateArgs: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateArgs: want error containing %q", tt.want)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeArgs(t *testing.T) {
+	type args struct {
+		FilePath string   `json:"file_path"`
+		Line     int      `json:"line"`
+		Paths    []string `json:"paths"`
+	}
+
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		want    args
+		wantErr string
+	}{
+		{
+			name: "decodes by json tag",
+			raw:  map[string]any{"file_path": "a.go", "line": float64(7), "paths": []any{"x", "y"}},
+			want: args{FilePath: "a.go", Line: 7, Paths: []string{"x", "y"}},
+		},
+		{
+			name: "single string for an array",
+			raw:  map[string]any{"file_path": "a.go", "paths": "internal/agent"},
+			want: args{FilePath: "a.go", Paths: []string{"internal/agent"}},
+		},
+		{
+			name: "string slice from Go callers",
+			raw:  map[string]any{"file_path": "a.go", "paths": []string{"x"}},
+			want: args{FilePath: "a.go", Paths: []string{"x"}},
+		},
+		{
+			name:    "invalid arguments are not decoded",
+			raw:     map[string]any{"line": "7"},
+			wantErr: `missing argument "file_path"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got args
+			err := decodeArgs(testSchema, tt.raw, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeArgs error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeArgs: %v", err)
+			}
+			if got.FilePath != tt.want.FilePath || got.Line != tt.want.Line || !slices.Equal(got.Paths, tt.want.Paths) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
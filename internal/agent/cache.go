@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ToolCache はツールの結果をエージェント間で共有するキャッシュです。
+// パネルレビューで同じ変更を調べる複数のペルソナが、同じツール呼び出しを繰り返さないようにします。
+// 実行中の呼び出しと同じ呼び出しは、その完了を待って結果を共有します。エラーはキャッシュしません
+type ToolCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	hits    int
+	misses  int
+}
+
+type cacheEntry struct {
+	done   chan struct{} // 実行が終わったら close する
+	result string
+	err    error
+}
+
+func NewToolCache() *ToolCache {
+	return &ToolCache{entries: make(map[string]*cacheEntry)}
+}
+
+// WithToolCache はツールの結果を cache で共有します
+func WithToolCache(cache *ToolCache) Option {
+	return func(a *L5Agent) {
+		a.cache = cache
+	}
+}
+
+// Do は key の結果があればそれを返し、なければ run を実行して結果を保存します
+func (c *ToolCache) Do(ctx context.Context, key string, run func() (string, error)) (string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.hits++
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+			return e.result, e.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	e := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.misses++
+	c.mu.Unlock()
+
+	e.result, e.err = run()
+	if e.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(e.done)
+	return e.result, e.err
+}
+
+// Stats はキャッシュが使われた回数と、実際にツールを実行した回数を返します
+func (c *ToolCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// executeTool はツール呼び出しを実行します。キャッシュがあれば結果を共有します。
+// ツールの出力はペルソナの上限設定で変わるため、上限もキーに含めます。
+// このエージェントに公開していないツールは、他のペルソナの結果がキャッシュにあっても実行できません
+func (a *L5Agent) executeTool(ctx context.Context, call ToolCall) (string, error) {
+	if _, ok := a.tools.Lookup(call.Name); !ok || a.cache == nil {
+		return a.tools.Execute(ctx, call)
+	}
+	args, err := json.Marshal(call.Args)
+	if err != nil {
+		return a.tools.Execute(ctx, call)
+	}
+	key := fmt.Sprintf("%s %s %+v", call.Name, args, a.limits)
+	return a.cache.Do(ctx, key, func() (string, error) {
+		return a.tools.Execute(ctx, call)
+	})
+}
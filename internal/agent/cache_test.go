@@ -0,0 +1,130 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/agent"
+	"github.com/0muji4/llm-reviewer/internal/agent/agenttest"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+)
+
+func TestToolCacheDo(t *testing.T) {
+	ctx := context.Background()
+	c := agent.NewToolCache()
+
+	runs := 0
+	run := func() (string, error) {
+		runs++
+		return "result", nil
+	}
+	for range 3 {
+		got, err := c.Do(ctx, "key", run)
+		if err != nil || got != "result" {
+			t.Fatalf("Do = %q, %v; want %q, nil", got, err, "result")
+		}
+	}
+	if runs != 1 {
+		t.Errorf("run called %d times, want 1", runs)
+	}
+	if hits, misses := c.Stats(); hits != 2 || misses != 1 {
+		t.Errorf("Stats = %d hits, %d misses; want 2, 1", hits, misses)
+	}
+}
+
+func TestToolCacheDoDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	c := agent.NewToolCache()
+
+	if _, err := c.Do(ctx, "key", func() (string, error) { return "", errors.New("boom") }); err == nil {
+		t.Fatal("Do: want error from run")
+	}
+	got, err := c.Do(ctx, "key", func() (string, error) { return "retried", nil })
+	if err != nil || got != "retried" {
+		t.Errorf("Do after error = %q, %v; want %q, nil", got, err, "retried")
+	}
+}
+
+func TestToolCacheDoSharesInFlightCall(t *testing.T) {
+	ctx := context.Background()
+	c := agent.NewToolCache()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go c.Do(ctx, "key", func() (string, error) {
+		close(started)
+		<-release
+		return "shared", nil
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Go(func() {
+			results[i], _ = c.Do(ctx, "key", func() (string, error) {
+				return "", errors.New("in-flight call was not shared")
+			})
+		})
+	}
+	close(release)
+	wg.Wait()
+
+	for i, got := range results {
+		if got != "shared" {
+			t.Errorf("results[%d] = %q, want %q", i, got, "shared")
+		}
+	}
+}
+
+func TestToolCacheDoCanceledWhileWaiting(t *testing.T) {
+	c := agent.NewToolCache()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go c.Do(context.Background(), "key", func() (string, error) {
+		close(started)
+		<-release
+		return "late", nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Do(ctx, "key", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do with canceled context: err = %v, want context.Canceled", err)
+	}
+}
+
+// 他のペルソナの結果がキャッシュにあっても、自分に許可されていないツールは実行できない
+func TestCachedResultRespectsToolDenyList(t *testing.T) {
+	cache := agent.NewToolCache()
+	reader := agenttest.StubReader{"main.go": "package main\n"}
+	readMain := agenttest.Call("read-file", map[string]any{"file_path": "main.go"})
+
+	run := func(p *persona.Persona) string {
+		t.Helper()
+		model := agenttest.NewScriptedModel(readMain, agenttest.Reply("done"), agenttest.Reply(`{"summary": "ok", "findings": []}`))
+		a := agent.NewL5Agent(model, "/project", p, &agenttest.StubAnalyzer{}, reader, &agenttest.StubDiffer{}, agenttest.StubResolver{}, agent.WithToolCache(cache))
+		if _, err := a.Run(context.Background(), "review"); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		results := model.ToolResults()
+		if len(results) != 1 {
+			t.Fatalf("got %d tool results, want 1", len(results))
+		}
+		return results[0].Content
+	}
+
+	if got := run(&persona.Persona{Name: "reader"}); !strings.Contains(got, "package main") {
+		t.Fatalf("allowed persona: read-file = %q, want the file contents", got)
+	}
+	denied := &persona.Persona{Name: "denied", Tools: persona.Tools{Deny: []string{"read-file"}}}
+	if got := run(denied); !strings.Contains(got, `unknown tool "read-file"`) {
+		t.Errorf("denied persona: read-file = %q, want an unknown tool error", got)
+	}
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+const (
+	defaultCallDepth = 2
+	maxCallDepth     = 4
+	// 呼び出し階層が爆発しないよう、ノード数と1ノードあたりの子の数を制限する
+	maxCallNodes    = 80
+	maxCallChildren = 15
+)
+
+// 呼び出し階層の向きです
+const (
+	callsIncoming = "incoming"
+	callsOutgoing = "outgoing"
+	callsBoth     = "both"
+)
+
+// callHierarchy は呼び出し階層ツリーを組み立てる1回分の状態です
+type callHierarchy struct {
+	ctx      context.Context
+	agent    *L5Agent
+	b        strings.Builder
+	nodes    int
+	expanded map[string]bool // 展開済みのノード（再帰呼び出しの循環を防ぐ）
+}
+
+type callHierarchyArgs struct {
+	positionArgs
+	Direction string `json:"direction"`
+	Depth     int    `json:"depth"`
+}
+
+func (a *L5Agent) callHierarchyTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "call-hierarchy",
+		Description: "指定位置の関数・メソッドについて、呼び出し元（Callers）と呼び出し先（Callees）をツリーで返します。変更した関数の影響範囲（blast radius）を確認するときに使用してください。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: withProperty(withProperty(positionParams(), "direction", &Schema{
+				Type:        TypeString,
+				Enum:        []string{callsIncoming, callsOutgoing, callsBoth},
+				Description: "incoming: 呼び出し元のみ、outgoing: 呼び出し先のみ、both: 両方（デフォルト）",
+			}), "depth", &Schema{
+				Type:        TypeInteger,
+				Description: fmt.Sprintf("辿る深さ（デフォルト: %d、最大: %d）", min(defaultCallDepth, a.maxCallDepth()), a.maxCallDepth()),
+			}),
+			Required: positionRequired,
+		},
+	}, func(ctx context.Context, args callHierarchyArgs) (string, error) {
+		return a.executeCallHierarchy(ctx, args.FilePath, args.Line, args.Character, args.Direction, args.Depth)
+	})
+}
+
+// maxCallDepth はペルソナの設定を反映した、辿れる深さの上限です
+func (a *L5Agent) maxCallDepth() int {
+	return cmp.Or(a.limits.MaxCallDepth, maxCallDepth)
+}
+
+func (a *L5Agent) executeCallHierarchy(ctx context.Context, relPath string, line, char int, direction string, depth int) (string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+
+	if depth <= 0 {
+		depth = defaultCallDepth
+	}
+	depth = min(depth, a.maxCallDepth())
+	if direction == "" {
+		direction = callsBoth
+	}
+	if direction != callsIncoming && direction != callsOutgoing && direction != callsBoth {
+		return "", fmt.Errorf("unknown direction %q", direction)
+	}
+
+	items, err := a.analyzer.PrepareCallHierarchy(ctx, absPath, line-1, char-1)
+	if err != nil {
+		return "", fmt.Errorf("agent: call hierarchy %s:%d:%d: %w", relPath, line, char, err)
+	}
+	if len(items) == 0 {
+		return "No function or method found at the given position.", nil
+	}
+
+	h := &callHierarchy{ctx: ctx, agent: a}
+	for _, item := range items {
+		fmt.Fprintf(&h.b, "%s\n", h.label(item))
+		if direction != callsOutgoing {
+			h.b.WriteString("Callers:\n")
+			h.expanded = map[string]bool{itemKey(item): true}
+			if err := h.incoming(item, 1, depth); err != nil {
+				return "", err
+			}
+		}
+		if direction != callsIncoming {
+			h.b.WriteString("Callees:\n")
+			h.expanded = map[string]bool{itemKey(item): true}
+			if err := h.outgoing(item, 1, depth); err != nil {
+				return "", err
+			}
+		}
+	}
+	if h.nodes >= maxCallNodes {
+		fmt.Fprintf(&h.b, "(truncated at %d nodes; narrow the depth or start from a caller)\n", maxCallNodes)
+	}
+
+	output := h.b.String()
+	fmt.Fprintf(os.Stderr, "   -> %d nodes\n", h.nodes)
+	return output, nil
+}
+
+func (h *callHierarchy) incoming(item lsp.CallHierarchyItem, level, depth int) error {
+	calls, err := h.agent.analyzer.IncomingCalls(h.ctx, item)
+	if err != nil {
+		return fmt.Errorf("agent: incoming calls of %s: %w", item.Name, err)
+	}
+	children := make([]lsp.CallHierarchyItem, 0, len(calls))
+	for _, c := range calls {
+		children = append(children, c.From)
+	}
+	return h.walk(children, level, depth, h.incoming)
+}
+
+func (h *callHierarchy) outgoing(item lsp.CallHierarchyItem, level, depth int) error {
+	calls, err := h.agent.analyzer.OutgoingCalls(h.ctx, item)
+	if err != nil {
+		return fmt.Errorf("agent: outgoing calls of %s: %w", item.Name, err)
+	}
+	children := make([]lsp.CallHierarchyItem, 0, len(calls))
+	for _, c := range calls {
+		children = append(children, c.To)
+	}
+	return h.walk(children, level, depth, h.outgoing)
+}
+
+// walk は子ノードを1行ずつ出力し、深さの上限まで next で再帰します
+func (h *callHierarchy) walk(children []lsp.CallHierarchyItem, level, depth int, next func(lsp.CallHierarchyItem, int, int) error) error {
+	indent := strings.Repeat("  ", level)
+	if len(children) == 0 && level == 1 {
+		fmt.Fprintf(&h.b, "%s(none)\n", indent)
+		return nil
+	}
+
+	for i, child := range children {
+		if h.nodes >= maxCallNodes {
+			return nil
+		}
+		if i >= maxCallChildren {
+			fmt.Fprintf(&h.b, "%s... and %d more\n", indent, len(children)-i)
+			return nil
+		}
+		h.nodes++
+
+		key := itemKey(child)
+		if h.expanded[key] {
+			fmt.Fprintf(&h.b, "%s%s (seen)\n", indent, h.label(child))
+			continue
+		}
+		fmt.Fprintf(&h.b, "%s%s\n", indent, h.label(child))
+
+		// 標準ライブラリや外部モジュールの中までは辿らない
+		if level >= depth || !h.agent.inProject(child.URI) {
+			continue
+		}
+		h.expanded[key] = true
+		if err := next(child, level+1, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// label はプロジェクト内のノードを "name  path:line"、外部のノードを "pkg.name" 形式で表示します
+func (h *callHierarchy) label(item lsp.CallHierarchyItem) string {
+	if !h.agent.inProject(item.URI) {
+		// gopls の detail は "import/path • file.go" 形式
+		if pkg, _, ok := strings.Cut(item.Detail, " • "); ok {
+			return pkg + "." + item.Name
+		}
+		return item.Name
+	}
+	return fmt.Sprintf("%s  %s:%d", item.Name, h.agent.relPath(item.URI), item.SelectionRange.Start.Line+1)
+}
+
+func itemKey(item lsp.CallHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
+
+// inProject は URI がプロジェクトルート配下のファイルを指すかを返します
+func (a *L5Agent) inProject(uri string) bool {
+	return !filepath.IsAbs(a.relPath(uri))
+}
@@ -0,0 +1,145 @@
+package agent_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/agent/agenttest"
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+// callItem はプロジェクト内の a.go の line 行目（1始まり）にある関数です
+func callItem(name string, line int) lsp.CallHierarchyItem {
+	pos := lsp.Position{Line: line - 1, Character: 5}
+	return lsp.CallHierarchyItem{Name: name, URI: "file://" + root + "/a.go", SelectionRange: lsp.Range{Start: pos, End: pos}}
+}
+
+func callers(items ...lsp.CallHierarchyItem) []lsp.CallHierarchyIncomingCall {
+	calls := make([]lsp.CallHierarchyIncomingCall, 0, len(items))
+	for _, item := range items {
+		calls = append(calls, lsp.CallHierarchyIncomingCall{From: item})
+	}
+	return calls
+}
+
+func callees(items ...lsp.CallHierarchyItem) []lsp.CallHierarchyOutgoingCall {
+	calls := make([]lsp.CallHierarchyOutgoingCall, 0, len(items))
+	for _, item := range items {
+		calls = append(calls, lsp.CallHierarchyOutgoingCall{To: item})
+	}
+	return calls
+}
+
+// fanIn は line 行目から並ぶ n 個の関数 prefix1..prefixN を返します
+func fanIn(prefix string, n, line int) []lsp.CallHierarchyItem {
+	items := make([]lsp.CallHierarchyItem, n)
+	for i := range items {
+		items[i] = callItem(fmt.Sprintf("%s%d", prefix, i+1), line+i)
+	}
+	return items
+}
+
+func TestCallHierarchy(t *testing.T) {
+	target := callItem("Target", 10)
+	at := agenttest.Position{FilePath: root + "/a.go", Line: 9, Character: 5}
+	args := func(direction string, depth int) map[string]any {
+		args := map[string]any{"file_path": "a.go", "line": 10, "character": 6, "direction": direction}
+		if depth > 0 {
+			args["depth"] = depth
+		}
+		return args
+	}
+
+	// 呼び出し元の連鎖 Target <- C1 <- C2 <- C3 <- C4 <- C5
+	chain := map[string][]lsp.CallHierarchyIncomingCall{"Target": callers(callItem("C1", 21))}
+	for i := 1; i < 5; i++ {
+		chain[fmt.Sprintf("C%d", i)] = callers(callItem(fmt.Sprintf("C%d", i+1), 21+i))
+	}
+
+	wide := fanIn("F", 20, 100)
+	crowded := map[string][]lsp.CallHierarchyIncomingCall{"Target": callers(fanIn("G", 10, 100)...)}
+	for i := range 10 {
+		crowded[fmt.Sprintf("G%d", i+1)] = callers(fanIn(fmt.Sprintf("G%d.", i+1), 10, 200+i*10)...)
+	}
+
+	external := lsp.CallHierarchyItem{Name: "Println", Detail: "fmt • print.go", URI: "file:///usr/lib/go/src/fmt/print.go"}
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		analyzer *agenttest.StubAnalyzer
+		want     string // 空でなければ出力全体
+		contains []string
+	}{
+		{
+			name:     "default depth",
+			args:     args("incoming", 0),
+			analyzer: &agenttest.StubAnalyzer{CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}}, Incoming: chain},
+			want:     "Target  a.go:10\nCallers:\n  C1  a.go:21\n    C2  a.go:22\n",
+		},
+		{
+			name:     "depth is capped",
+			args:     args("incoming", 10),
+			analyzer: &agenttest.StubAnalyzer{CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}}, Incoming: chain},
+			want:     "Target  a.go:10\nCallers:\n  C1  a.go:21\n    C2  a.go:22\n      C3  a.go:23\n        C4  a.go:24\n",
+		},
+		{
+			name: "too many children",
+			args: args("incoming", 1),
+			analyzer: &agenttest.StubAnalyzer{
+				CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}},
+				Incoming:  map[string][]lsp.CallHierarchyIncomingCall{"Target": callers(wide...)},
+			},
+			contains: []string{"  F15  a.go:114\n  ... and 5 more\n"},
+		},
+		{
+			name:     "too many nodes",
+			args:     args("incoming", 2),
+			analyzer: &agenttest.StubAnalyzer{CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}}, Incoming: crowded},
+			// G1〜G7 とそれぞれの呼び出し元 10 個で 77 ノード、G8 と G8.1、G8.2 で 80 ノード
+			contains: []string{"  G8  a.go:107\n    G8.1  a.go:270\n    G8.2  a.go:271\n(truncated at 80 nodes; narrow the depth or start from a caller)\n"},
+		},
+		{
+			name: "cycles are marked as seen",
+			args: args("outgoing", 3),
+			analyzer: &agenttest.StubAnalyzer{
+				CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}},
+				Outgoing: map[string][]lsp.CallHierarchyOutgoingCall{
+					"Target": callees(callItem("A", 30), external),
+					"A":      callees(callItem("B", 40), callItem("A", 30)),
+					"B":      callees(target),
+				},
+			},
+			want: "Target  a.go:10\nCallees:\n  A  a.go:30\n    B  a.go:40\n      Target  a.go:10 (seen)\n    A  a.go:30 (seen)\n  fmt.Println\n",
+		},
+		{
+			name: "both directions",
+			args: args("", 1),
+			analyzer: &agenttest.StubAnalyzer{
+				CallItems: map[agenttest.Position][]lsp.CallHierarchyItem{at: {target}},
+				Outgoing:  map[string][]lsp.CallHierarchyOutgoingCall{"Target": callees(external)},
+			},
+			want: "Target  a.go:10\nCallers:\n  (none)\nCallees:\n  fmt.Println\n",
+		},
+		{
+			name:     "no function",
+			args:     args("incoming", 1),
+			analyzer: &agenttest.StubAnalyzer{},
+			want:     "No function or method found at the given position.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runTool(t, deps{analyzer: tt.analyzer}, "call-hierarchy", tt.args)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("call-hierarchy =\n%s\nwant\n%s", got, tt.want)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("call-hierarchy =\n%s\nwant it to contain\n%s", got, s)
+				}
+			}
+		})
+	}
+}
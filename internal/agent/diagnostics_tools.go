@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+// maxDiagnostics はツール結果に含める診断の既定の最大件数です
+const maxDiagnostics = 100
+
+type diagnosticsArgs struct {
+	Path        string `json:"path"`
+	MinSeverity string `json:"min_severity"`
+}
+
+func (a *L5Agent) diagnosticsTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "get-diagnostics",
+		Description: "gopls が報告する診断（コンパイルエラー、go vet の指摘、静的解析の警告）を返します。ファイルまたはパッケージのディレクトリを指定してください。レビューの最初にツールチェーンが既に把握している問題を確認するときに使用してください。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"path": {
+					Type:        TypeString,
+					Description: "対象のファイルまたはパッケージのディレクトリ（プロジェクトルートからの相対パス）",
+				},
+				"min_severity": {
+					Type:        TypeString,
+					Enum:        []string{lsp.SeverityError.String(), lsp.SeverityWarning.String(), lsp.SeverityInformation.String(), lsp.SeverityHint.String()},
+					Description: "返す診断の最低の重大度（error: エラーのみ、warning: 警告以上、info: 情報以上、hint: すべて）。デフォルト: hint",
+				},
+			},
+			Required: []string{"path"},
+		},
+	}, func(ctx context.Context, args diagnosticsArgs) (string, error) {
+		return a.executeGetDiagnostics(ctx, args.Path, args.MinSeverity)
+	})
+}
+
+// parseMinSeverity は min_severity 引数を重大度に変換します。空ならすべての診断を返す hint です
+func parseMinSeverity(name string) (lsp.DiagnosticSeverity, error) {
+	if name == "" {
+		return lsp.SeverityHint, nil
+	}
+	for s := lsp.SeverityError; s <= lsp.SeverityHint; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// diagnosticFiles は path（ファイルまたはパッケージのディレクトリ）を診断対象の .go ファイルの絶対パスに展開します。
+// ディレクトリの場合はサブディレクトリを辿りません
+func (a *L5Agent) diagnosticFiles(relPath string) ([]string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{absPath}, nil
+	}
+
+	entries, err := os.ReadDir(absPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			files = append(files, filepath.Join(absPath, e.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", relPath)
+	}
+	return files, nil
+}
+
+func (a *L5Agent) executeGetDiagnostics(ctx context.Context, relPath, minSeverity string) (string, error) {
+	threshold, err := parseMinSeverity(minSeverity)
+	if err != nil {
+		return "", err
+	}
+	files, err := a.diagnosticFiles(relPath)
+	if err != nil {
+		return "", fmt.Errorf("agent: get diagnostics %s: %w", relPath, err)
+	}
+
+	diags, err := a.analyzer.Diagnostics(ctx, files)
+	if err != nil {
+		return "", fmt.Errorf("agent: get diagnostics %s: %w", relPath, err)
+	}
+
+	paths := make([]string, 0, len(diags))
+	for absPath := range diags {
+		paths = append(paths, absPath)
+	}
+	sort.Strings(paths)
+
+	var lines []string
+	for _, absPath := range paths {
+		path := a.relPath("file://" + absPath)
+		ds := append([]lsp.Diagnostic(nil), diags[absPath]...)
+		sort.SliceStable(ds, func(i, j int) bool {
+			if ds[i].Range.Start.Line != ds[j].Range.Start.Line {
+				return ds[i].Range.Start.Line < ds[j].Range.Start.Line
+			}
+			return ds[i].Range.Start.Character < ds[j].Range.Start.Character
+		})
+		for _, d := range ds {
+			// 重大度の数値は小さいほど重い。省略された診断（0）は重大度がわからないので残す
+			if d.Severity > threshold {
+				continue
+			}
+			source := d.Source
+			if source == "" {
+				source = "gopls"
+			}
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: [%s] %s: %s",
+				path, d.Range.Start.Line+1, d.Range.Start.Character+1, d.Severity, source, strings.TrimSpace(d.Message)))
+		}
+	}
+	if len(lines) == 0 {
+		return "No diagnostics.", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d diagnostics:\n", len(lines))
+	limit := cmp.Or(a.limits.MaxDiagnostics, maxDiagnostics)
+	for i, line := range lines {
+		if i == limit {
+			fmt.Fprintf(&b, "... and %d more\n", len(lines)-limit)
+			break
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
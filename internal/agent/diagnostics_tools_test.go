@@ -0,0 +1,138 @@
+package agent_test
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/agent/agenttest"
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+)
+
+func TestGetDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pkg/a.go", "pkg/b.go", "pkg/notes.txt", "pkg/sub/c.go", "empty/README"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diag := func(line, char int, severity lsp.DiagnosticSeverity, source, message string) lsp.Diagnostic {
+		return lsp.Diagnostic{Range: lsp.Range{Start: lsp.Position{Line: line, Character: char}}, Severity: severity, Source: source, Message: message}
+	}
+	diags := map[string][]lsp.Diagnostic{
+		filepath.Join(dir, "pkg/b.go"): {
+			diag(9, 0, lsp.SeverityWarning, "unusedresult", "result of fmt.Sprintf call not used"),
+			diag(2, 4, lsp.SeverityError, "compiler", "undefined: x\n"),
+			diag(2, 1, lsp.SeverityHint, "", "simplify"),
+		},
+		filepath.Join(dir, "pkg/a.go"): {
+			diag(0, 0, lsp.SeverityInformation, "stylecheck", "package comment"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		analyzer *agenttest.StubAnalyzer
+		maxDiags int
+		want     string
+	}{
+		{
+			name:     "package directory",
+			args:     map[string]any{"path": "pkg"},
+			analyzer: &agenttest.StubAnalyzer{Diags: diags},
+			want: "Found 4 diagnostics:\n" +
+				"pkg/a.go:1:1: [info] stylecheck: package comment\n" +
+				"pkg/b.go:3:2: [hint] gopls: simplify\n" +
+				"pkg/b.go:3:5: [error] compiler: undefined: x\n" +
+				"pkg/b.go:10:1: [warning] unusedresult: result of fmt.Sprintf call not used\n",
+		},
+		{
+			name:     "limit",
+			args:     map[string]any{"path": "pkg"},
+			analyzer: &agenttest.StubAnalyzer{Diags: diags},
+			maxDiags: 2,
+			want: "Found 4 diagnostics:\n" +
+				"pkg/a.go:1:1: [info] stylecheck: package comment\n" +
+				"pkg/b.go:3:2: [hint] gopls: simplify\n" +
+				"... and 2 more\n",
+		},
+		{
+			name:     "file",
+			args:     map[string]any{"path": "pkg/a.go"},
+			analyzer: &agenttest.StubAnalyzer{Diags: diags},
+			want:     "Found 1 diagnostics:\npkg/a.go:1:1: [info] stylecheck: package comment\n",
+		},
+		{
+			name:     "no diagnostics",
+			args:     map[string]any{"path": "pkg/sub"},
+			analyzer: &agenttest.StubAnalyzer{Diags: diags},
+			want:     "No diagnostics.",
+		},
+		{
+			name:     "no Go files",
+			args:     map[string]any{"path": "empty"},
+			analyzer: &agenttest.StubAnalyzer{},
+			want:     "Error: agent: get diagnostics empty: no Go files in empty",
+		},
+		{
+			name:     "analyzer error",
+			args:     map[string]any{"path": "pkg/a.go"},
+			analyzer: &agenttest.StubAnalyzer{Err: errors.New("gopls crashed")},
+			want:     "Error: agent: get diagnostics pkg/a.go: gopls crashed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &persona.Persona{Name: "tester", Tools: persona.Tools{Limits: persona.ToolLimits{MaxDiagnostics: tt.maxDiags}}}
+			got := runTool(t, deps{root: dir, persona: p, analyzer: tt.analyzer}, "get-diagnostics", tt.args)
+			if got != tt.want {
+				t.Errorf("get-diagnostics =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDiagnosticsMinSeverity(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var diags []lsp.Diagnostic
+	for i, severity := range []lsp.DiagnosticSeverity{lsp.SeverityHint, lsp.SeverityInformation, lsp.SeverityWarning, lsp.SeverityError, 0} {
+		diags = append(diags, lsp.Diagnostic{Range: lsp.Range{Start: lsp.Position{Line: i}}, Severity: severity, Source: "s", Message: "m"})
+	}
+	analyzer := &agenttest.StubAnalyzer{Diags: map[string][]lsp.Diagnostic{file: diags}}
+
+	tests := []struct {
+		minSeverity string
+		want        string
+	}{
+		{"", "Found 5 diagnostics:\na.go:1:1: [hint] s: m\na.go:2:1: [info] s: m\na.go:3:1: [warning] s: m\na.go:4:1: [error] s: m\na.go:5:1: [unknown] s: m\n"},
+		{"info", "Found 4 diagnostics:\na.go:2:1: [info] s: m\na.go:3:1: [warning] s: m\na.go:4:1: [error] s: m\na.go:5:1: [unknown] s: m\n"},
+		{"warning", "Found 3 diagnostics:\na.go:3:1: [warning] s: m\na.go:4:1: [error] s: m\na.go:5:1: [unknown] s: m\n"},
+		// 重大度のない診断は重大度がわからないので絞り込まない
+		{"error", "Found 2 diagnostics:\na.go:4:1: [error] s: m\na.go:5:1: [unknown] s: m\n"},
+		{"fatal", `Error: argument "min_severity" must be one of error, warning, info, hint, got "fatal"`},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.minSeverity, "default"), func(t *testing.T) {
+			args := map[string]any{"path": "a.go"}
+			if tt.minSeverity != "" {
+				args["min_severity"] = tt.minSeverity
+			}
+			got := runTool(t, deps{root: dir, analyzer: analyzer}, "get-diagnostics", args)
+			if got != tt.want {
+				t.Errorf("get-diagnostics =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
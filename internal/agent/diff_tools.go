@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/workspace"
+)
+
+// diffToolArgs は差分を取得するツールに共通の引数です
+type diffToolArgs struct {
+	Base      string   `json:"base"`
+	MergeBase string   `json:"merge_base"`
+	Range     string   `json:"range"`
+	Mode      string   `json:"mode"`
+	Paths     []string `json:"paths"`
+}
+
+type fileDiffArgs struct {
+	diffToolArgs
+	FilePath string `json:"file_path"`
+}
+
+// diffTools は Git 差分を取得するツールです
+func (a *L5Agent) diffTools() []Tool {
+	return []Tool{
+		NewTool(ToolSpec{
+			Name:        "get-diff",
+			Description: "Git差分を取得します。コードレビューや変更内容の確認に使用してください。引数を省略するとレビュー対象の差分（既定: git diff HEAD）を返します。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: diffParams(),
+			},
+		}, func(_ context.Context, args diffToolArgs) (string, error) {
+			opts := a.diffArgs(args)
+			resultText, err := a.differ.Diff(opts)
+			if resultText == "" && err == nil {
+				resultText = fmt.Sprintf("No changes detected (%s).", opts)
+			}
+			return resultText, err
+		}),
+		NewTool(ToolSpec{
+			Name:        "list-changed-files",
+			Description: "差分に含まれる変更ファイルの一覧（変更種別・追加/削除行数）を返します。差分全体が大きいときは、まずこれで全体像を把握してから「get-file-diff」でファイルごとに確認してください。引数は get-diff と同じです。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: diffParams(),
+			},
+		}, func(_ context.Context, args diffToolArgs) (string, error) {
+			return a.executeListChangedFiles(a.diffArgs(args))
+		}),
+		NewTool(ToolSpec{
+			Name:        "get-file-diff",
+			Description: "指定ファイルの差分を、変更後の行番号付きで返します。指摘の行番号はこの出力の行番号を使ってください。比較対象の引数は get-diff と同じです。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: withFilePath(diffParams()),
+				Required:   []string{"file_path"},
+			},
+		}, func(_ context.Context, args fileDiffArgs) (string, error) {
+			return a.executeGetFileDiff(args.FilePath, a.diffArgs(args.diffToolArgs))
+		}),
+	}
+}
+
+// diffParams は差分を取得するツールに共通の引数スキーマです
+func diffParams() map[string]*Schema {
+	return map[string]*Schema{
+		"base": {
+			Type:        TypeString,
+			Description: "比較元の ref（例: main, HEAD~3）",
+		},
+		"merge_base": {
+			Type:        TypeString,
+			Description: "このブランチと HEAD の merge-base を比較元にする（例: main）",
+		},
+		"range": {
+			Type:        TypeString,
+			Description: "コミット範囲（例: main..feature, v1.0...HEAD）。base・merge_base・mode とは併用不可",
+		},
+		"mode": {
+			Type:        TypeString,
+			Enum:        []string{"all", string(workspace.DiffModeStaged), string(workspace.DiffModeUnstaged)},
+			Description: "all: ステージ済み＋未ステージ、staged: ステージ済みのみ、unstaged: 未ステージのみ",
+		},
+		"paths": {
+			Type:        TypeArray,
+			Items:       &Schema{Type: TypeString},
+			Description: "対象パスの絞り込み（プロジェクトルートからの相対パス）",
+		},
+	}
+}
+
+// withFilePath は引数スキーマに file_path を追加します
+func withFilePath(props map[string]*Schema) map[string]*Schema {
+	return withProperty(props, "file_path", &Schema{
+		Type:        TypeString,
+		Description: "対象のファイルパス（プロジェクトルートからの相対パス）",
+	})
+}
+
+// diffArgs は get-diff の引数をレビュー対象の差分設定に重ねます。
+// 比較対象（base/merge_base/range/mode）が1つでも指定されたら既定の比較対象は使いません。
+func (a *L5Agent) diffArgs(args diffToolArgs) workspace.DiffOptions {
+	opts := a.diffOptions
+
+	mode := args.Mode
+	override := workspace.DiffOptions{
+		Base:      args.Base,
+		MergeBase: args.MergeBase,
+		Range:     args.Range,
+		Paths:     opts.Paths,
+	}
+	if mode != "all" {
+		override.Mode = workspace.DiffMode(mode)
+	}
+	if override.HasRevision() || mode != "" {
+		opts = override
+	}
+	if len(args.Paths) > 0 {
+		opts.Paths = args.Paths
+	}
+	return opts
+}
+
+// parsedDiff は差分を取得して解析します
+func (a *L5Agent) parsedDiff(opts workspace.DiffOptions) (workspace.Diff, error) {
+	text, err := a.differ.Diff(opts)
+	if err != nil {
+		return nil, err
+	}
+	return workspace.ParseDiff(text)
+}
+
+func (a *L5Agent) executeListChangedFiles(opts workspace.DiffOptions) (string, error) {
+	diff, err := a.parsedDiff(opts)
+	if err != nil {
+		return "", fmt.Errorf("agent: list changed files: %w", err)
+	}
+	if len(diff) == 0 {
+		return fmt.Sprintf("No changes detected (%s).", opts), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changed files (%s):\n", opts)
+	for _, f := range diff {
+		switch {
+		case f.Status == workspace.FileRenamed || f.Status == workspace.FileCopied:
+			fmt.Fprintf(&b, "%s %s -> %s", f.Status, f.OldPath, f.NewPath)
+		default:
+			fmt.Fprintf(&b, "%s %s", f.Status, f.Path())
+		}
+		if f.Binary {
+			b.WriteString(" (binary)")
+		} else {
+			added, deleted := f.Stats()
+			fmt.Fprintf(&b, " (+%d -%d)", added, deleted)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func (a *L5Agent) executeGetFileDiff(relPath string, opts workspace.DiffOptions) (string, error) {
+	diff, err := a.parsedDiff(opts)
+	if err != nil {
+		return "", fmt.Errorf("agent: get file diff %s: %w", relPath, err)
+	}
+
+	f := diff.File(relPath)
+	if f == nil {
+		return fmt.Sprintf("%s has no changes (%s).", relPath, opts), nil
+	}
+	return renderFileDiff(f), nil
+}
+
+// renderFileDiff は差分に行番号を付けて表示します。
+// 追加行とコンテキスト行には変更後の行番号を、削除行には変更前の行番号を括弧付きで表示します。
+func renderFileDiff(f *workspace.FileDiff) string {
+	var b strings.Builder
+	switch f.Status {
+	case workspace.FileRenamed, workspace.FileCopied:
+		fmt.Fprintf(&b, "%s: %s -> %s\n", f.Status, f.OldPath, f.NewPath)
+	default:
+		fmt.Fprintf(&b, "%s: %s\n", f.Status, f.Path())
+	}
+	if f.Binary {
+		b.WriteString("(binary file)\n")
+		return b.String()
+	}
+
+	for _, h := range f.Hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			if l.Kind == workspace.LineDeleted {
+				fmt.Fprintf(&b, "(%4d) -%s\n", l.OldLine, l.Text)
+				continue
+			}
+			fmt.Fprintf(&b, " %4d  %c%s\n", l.NewLine, l.Kind, l.Text)
+		}
+	}
+	return b.String()
+}
+
+// scopePrompt はレビュー範囲（変更されたハンクの行範囲）をモデルに伝えるプロンプトを組み立てます
+func scopePrompt(diff workspace.Diff) string {
+	var b strings.Builder
+	b.WriteString("## レビュー範囲\n")
+	b.WriteString("このレビューは差分スコープです。指摘は以下の変更箇所（変更後の行番号。削除されたファイルは変更前の行番号）に対してのみ行ってください。")
+	b.WriteString("範囲外の既存コードへの指摘は、レビュー結果ではなく参考情報として扱われます。\n")
+	for _, f := range diff {
+		switch {
+		case f.Status == workspace.FileDeleted && len(f.Hunks) > 0:
+			fmt.Fprintf(&b, "- %s: 削除されたファイル全体\n", f.Path())
+		case f.Binary:
+			fmt.Fprintf(&b, "- %s: バイナリファイル全体\n", f.Path())
+		default:
+			ranges := f.HunkRanges()
+			if len(ranges) == 0 {
+				continue
+			}
+			parts := make([]string, 0, len(ranges))
+			for _, r := range ranges {
+				parts = append(parts, r.String())
+			}
+			fmt.Fprintf(&b, "- %s: %s\n", f.Path(), strings.Join(parts, ", "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+)
+
+// maxReadFileBytes は read-file の1回の結果に含める本文の既定の上限です（おおよそ 8k トークン）
+const maxReadFileBytes = 32 << 10
+
+// fileParams は read-file と file-outline に共通の引数スキーマです
+func fileParams() map[string]*Schema {
+	return map[string]*Schema{
+		"file_path": {
+			Type:        TypeString,
+			Description: "対象のファイルパス（プロジェクトルートからの相対パス）",
+		},
+		"revision": {
+			Type:        TypeString,
+			Description: "読み取るリビジョン（ブランチ名・タグ・コミット。例: HEAD, main, HEAD~1）。省略時は作業ツリーの内容",
+		},
+	}
+}
+
+type readFileArgs struct {
+	FilePath  string `json:"file_path"`
+	Revision  string `json:"revision"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+type fileOutlineArgs struct {
+	FilePath string `json:"file_path"`
+	Revision string `json:"revision"`
+}
+
+// fileTools はファイルの内容を読むツールです
+func (a *L5Agent) fileTools() []Tool {
+	return []Tool{
+		NewTool(ToolSpec{
+			Name:        "read-file",
+			Description: "指定されたファイルの内容を行番号付きで読み取ります。コードの中身を確認したいときに使用してください。大きなファイルは先に「file-outline」で宣言の行範囲を調べ、start_line と end_line で必要な部分だけを読んでください。revision を指定すると、そのリビジョン時点の内容を読めるので、変更前後のコードを比較できます。",
+			Parameters: &Schema{
+				Type: TypeObject,
+				Properties: withProperty(withProperty(fileParams(), "start_line", &Schema{
+					Type:        TypeInteger,
+					Description: "読み始める行（1始まり）。省略時はファイルの先頭",
+				}), "end_line", &Schema{
+					Type:        TypeInteger,
+					Description: "読み終える行（この行を含む）。省略時はファイルの末尾",
+				}),
+				Required: []string{"file_path"},
+			},
+		}, func(_ context.Context, args readFileArgs) (string, error) {
+			return a.executeReadFile(args.FilePath, args.Revision, args.StartLine, args.EndLine)
+		}),
+		NewTool(ToolSpec{
+			Name:        "file-outline",
+			Description: "Go ファイルのトップレベルの宣言（import・型・関数・メソッド・変数・定数）を行範囲付きで一覧します。ファイル全体を読まずに構成を把握し、「read-file」で読む範囲を決めるときに使用してください。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: fileParams(),
+				Required:   []string{"file_path"},
+			},
+		}, func(_ context.Context, args fileOutlineArgs) (string, error) {
+			return a.executeFileOutline(args.FilePath, args.Revision)
+		}),
+	}
+}
+
+// readSource は作業ツリー、または rev が指定されればそのリビジョンからファイルを読みます
+func (a *L5Agent) readSource(relPath, rev string) (string, error) {
+	if rev == "" {
+		return a.reader.ReadFile(relPath)
+	}
+	if a.revs == nil {
+		return "", fmt.Errorf("reading files at a revision is not available")
+	}
+	return a.revs.ReadFileAt(rev, relPath)
+}
+
+// sourceName はツール結果の見出しに使うファイル名です
+func sourceName(relPath, rev string) string {
+	if rev == "" {
+		return relPath
+	}
+	return relPath + "@" + rev
+}
+
+// splitLines は末尾の改行で空の行を作らずに行へ分割します
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// executeReadFile は start..end 行（1始まり、0 は省略）を行番号付きで返します。
+// 上限を超える分は切り捨て、続きの読み方を添えます
+func (a *L5Agent) executeReadFile(relPath, rev string, start, end int) (string, error) {
+	content, err := a.readSource(relPath, rev)
+	if err != nil {
+		return "", err
+	}
+	lines := splitLines(content)
+	total := len(lines)
+	if total == 0 {
+		return fmt.Sprintf("%s is empty.", sourceName(relPath, rev)), nil
+	}
+
+	start = max(start, 1)
+	if end <= 0 || end > total {
+		end = total
+	}
+	if start > total {
+		return "", fmt.Errorf("start_line %d is past the end of %s (%d lines)", start, relPath, total)
+	}
+	if end < start {
+		return "", fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+
+	limit := cmp.Or(a.limits.MaxReadBytes, maxReadFileBytes)
+	var body strings.Builder
+	width := len(strconv.Itoa(end))
+	last := start - 1
+	cutLine, shown := 0, 0 // 上限に合わせて途中で切った行と、そのうち表示したバイト数
+	for n := start; n <= end; n++ {
+		prefix := fmt.Sprintf("%*d\t", width, n)
+		text := lines[n-1]
+		if body.Len()+len(prefix)+len(text)+1 > limit {
+			if n > start {
+				break
+			}
+			// 先頭の1行だけで上限を超える（minify・生成されたファイルなど）ときは、その行を文字の境界で切る
+			cut := max(limit-len(prefix)-1, 0)
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			text, cutLine, shown = text[:cut], n, cut
+		}
+		body.WriteString(prefix + text + "\n")
+		last = n
+		if cutLine != 0 {
+			break
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (lines %d-%d of %d)\n", sourceName(relPath, rev), start, last, total)
+	b.WriteString(body.String())
+	if cutLine != 0 {
+		fmt.Fprintf(&b, "[truncated: line %d is %d bytes long; only its first %d bytes fit in the output limit of %d bytes.]\n",
+			cutLine, len(lines[cutLine-1]), shown, limit)
+	}
+	if last < end {
+		fmt.Fprintf(&b, "[truncated: output limit of %d bytes reached after line %d. Call read-file with start_line=%d to continue, or use file-outline to pick the lines you need.]\n",
+			limit, last, last+1)
+	}
+	return b.String(), nil
+}
+
+func (a *L5Agent) executeFileOutline(relPath, rev string) (string, error) {
+	if !strings.HasSuffix(relPath, ".go") {
+		return "", fmt.Errorf("file-outline supports only Go files: %s", relPath)
+	}
+	content, err := a.readSource(relPath, rev)
+	if err != nil {
+		return "", err
+	}
+	entries, err := symbol.Outline(relPath, content)
+	if err != nil {
+		return "", fmt.Errorf("agent: file outline %s: %w", relPath, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%d lines)\n", sourceName(relPath, rev), len(splitLines(content)))
+	if len(entries) == 0 {
+		b.WriteString("No top-level declarations.\n")
+	}
+	for _, e := range entries {
+		span := strconv.Itoa(e.StartLine)
+		if e.EndLine > e.StartLine {
+			span += "-" + strconv.Itoa(e.EndLine)
+		}
+		fmt.Fprintf(&b, "%s\t%s", span, e.Kind)
+		if e.Signature != "" {
+			fmt.Fprintf(&b, "\t%s", e.Signature)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,104 @@
+package agent_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/agent/agenttest"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+)
+
+func TestReadFile(t *testing.T) {
+	var twelve strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&twelve, "line %d\n", i)
+	}
+	reader := agenttest.StubReader{
+		"a.go":     "package a\n\nfunc F() {}\n",
+		"long.go":  twelve.String(),
+		"empty.go": "",
+		"wide.txt": "あいうえお\nnext\n",
+	}
+
+	tests := []struct {
+		name         string
+		args         map[string]any
+		maxReadBytes int
+		want         string
+	}{
+		{
+			name: "whole file",
+			args: map[string]any{"file_path": "a.go"},
+			want: "a.go (lines 1-3 of 3)\n1\tpackage a\n2\t\n3\tfunc F() {}\n",
+		},
+		{
+			name: "range",
+			args: map[string]any{"file_path": "long.go", "start_line": 9, "end_line": 10},
+			want: "long.go (lines 9-10 of 12)\n 9\tline 9\n10\tline 10\n",
+		},
+		{
+			name: "start and end are clamped",
+			args: map[string]any{"file_path": "a.go", "start_line": -3, "end_line": 100},
+			want: "a.go (lines 1-3 of 3)\n1\tpackage a\n2\t\n3\tfunc F() {}\n",
+		},
+		{
+			name: "single line",
+			args: map[string]any{"file_path": "long.go", "start_line": 12, "end_line": 12},
+			want: "long.go (lines 12-12 of 12)\n12\tline 12\n",
+		},
+		{
+			name: "end before start",
+			args: map[string]any{"file_path": "long.go", "start_line": 5, "end_line": 2},
+			want: "Error: end_line 2 is before start_line 5",
+		},
+		{
+			name: "start past the end",
+			args: map[string]any{"file_path": "long.go", "start_line": 13},
+			want: "Error: start_line 13 is past the end of long.go (12 lines)",
+		},
+		{
+			name: "empty file",
+			args: map[string]any{"file_path": "empty.go"},
+			want: "empty.go is empty.",
+		},
+		{
+			name: "missing file",
+			args: map[string]any{"file_path": "none.go"},
+			want: "Error: open none.go: no such file",
+		},
+		{
+			// 行番号の幅は 2 なので各行は " N\tline N\n" の 10 バイト。4 行目で上限を超える
+			name:         "output limit",
+			args:         map[string]any{"file_path": "long.go", "start_line": 2},
+			maxReadBytes: 35,
+			want: "long.go (lines 2-4 of 12)\n 2\tline 2\n 3\tline 3\n 4\tline 4\n" +
+				"[truncated: output limit of 35 bytes reached after line 4. Call read-file with start_line=5 to continue, or use file-outline to pick the lines you need.]\n",
+		},
+		{
+			// 先頭の行だけで上限を超えるときは、行を UTF-8 の文字の境界で切る（"1\t" と改行で 3 バイト、残り 7 バイトに2文字）
+			name:         "first line cut at a rune boundary",
+			args:         map[string]any{"file_path": "wide.txt"},
+			maxReadBytes: 10,
+			want: "wide.txt (lines 1-1 of 2)\n1\tあい\n" +
+				"[truncated: line 1 is 15 bytes long; only its first 6 bytes fit in the output limit of 10 bytes.]\n" +
+				"[truncated: output limit of 10 bytes reached after line 1. Call read-file with start_line=2 to continue, or use file-outline to pick the lines you need.]\n",
+		},
+		{
+			name:         "limit smaller than the line number",
+			args:         map[string]any{"file_path": "wide.txt", "end_line": 1},
+			maxReadBytes: 2,
+			want: "wide.txt (lines 1-1 of 2)\n1\t\n" +
+				"[truncated: line 1 is 15 bytes long; only its first 0 bytes fit in the output limit of 2 bytes.]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &persona.Persona{Name: "tester", Tools: persona.Tools{Limits: persona.ToolLimits{MaxReadBytes: tt.maxReadBytes}}}
+			got := runTool(t, deps{persona: p, reader: reader}, "read-file", tt.args)
+			if got != tt.want {
+				t.Errorf("read-file =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const defaultGeminiModel = "gemini-2.5-flash"
+
+var _ ChatModel = (*GeminiModel)(nil)
+
+// GeminiModel は Gemini API を使う ChatModel 実装です
+type GeminiModel struct {
+	client *genai.Client
+	model  string
+	gen    GenerationConfig
+	safety []*genai.SafetySetting
+}
+
+func NewGeminiModel(ctx context.Context, apiKey, model string, gen GenerationConfig) (*GeminiModel, error) {
+	safety, err := toGeminiSafety(gen.SafetySettings)
+	if err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &GeminiModel{client: client, model: model, gen: gen, safety: safety}, nil
+}
+
+// toGeminiSafety は安全性設定を Gemini の形式に変換します。
+// カテゴリとしきい値は HARM_CATEGORY_ を省いた小文字の名前（dangerous_content, block_only_high）でも指定できます
+func toGeminiSafety(settings map[string]string) ([]*genai.SafetySetting, error) {
+	categories := []genai.HarmCategory{
+		genai.HarmCategoryHarassment, genai.HarmCategoryHateSpeech, genai.HarmCategorySexuallyExplicit,
+		genai.HarmCategoryDangerousContent, genai.HarmCategoryCivicIntegrity,
+	}
+	thresholds := []genai.HarmBlockThreshold{
+		genai.HarmBlockThresholdBlockLowAndAbove, genai.HarmBlockThresholdBlockMediumAndAbove,
+		genai.HarmBlockThresholdBlockOnlyHigh, genai.HarmBlockThresholdBlockNone, genai.HarmBlockThresholdOff,
+	}
+
+	var out []*genai.SafetySetting
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		category := genai.HarmCategory(strings.ToUpper(name))
+		if !strings.HasPrefix(string(category), "HARM_CATEGORY_") {
+			category = "HARM_CATEGORY_" + category
+		}
+		if !slices.Contains(categories, category) {
+			return nil, fmt.Errorf("gemini: unknown safety category %q", name)
+		}
+		threshold := genai.HarmBlockThreshold(strings.ToUpper(settings[name]))
+		if !slices.Contains(thresholds, threshold) {
+			return nil, fmt.Errorf("gemini: unknown safety threshold %q for %s", settings[name], name)
+		}
+		out = append(out, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	return out, nil
+}
+
+func (m *GeminiModel) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(req.SystemPrompt)},
+		},
+		MaxOutputTokens: int32(m.gen.MaxOutputTokens),
+		SafetySettings:  m.safety,
+	}
+	if t := m.gen.Temperature; t != nil {
+		config.Temperature = genai.Ptr(float32(*t))
+	}
+	if p := m.gen.TopP; p != nil {
+		config.TopP = genai.Ptr(float32(*p))
+	}
+	if b := m.gen.ThinkingBudget; b != nil {
+		config.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(*b))}
+	}
+	if len(req.Tools) > 0 {
+		decls := make([]*genai.FunctionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			decls = append(decls, &genai.FunctionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  toGeminiSchema(t.Parameters),
+			})
+		}
+		config.Tools = []*genai.Tool{{FunctionDeclarations: decls}}
+	}
+	if req.ResponseSchema != nil {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseSchema = toGeminiSchema(req.ResponseSchema)
+	}
+
+	contents := make([]*genai.Content, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		contents = append(contents, toGeminiContent(msg))
+	}
+
+	resp, err := m.client.Models.GenerateContent(ctx, m.model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, fmt.Errorf("gemini: empty response")
+	}
+
+	msg := Message{
+		Role:   RoleModel,
+		Text:   resp.Text(),
+		native: resp.Candidates[0].Content,
+	}
+	for _, call := range resp.FunctionCalls() {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			ID:   call.ID,
+			Name: call.Name,
+			Args: call.Args,
+		})
+	}
+	return &ChatResponse{Message: msg}, nil
+}
+
+func toGeminiContent(msg Message) *genai.Content {
+	// Gemini 自身が返した応答はそのまま送り返す（thought signature を保持するため）
+	if c, ok := msg.native.(*genai.Content); ok {
+		return c
+	}
+
+	switch msg.Role {
+	case RoleTool:
+		parts := make([]*genai.Part, 0, len(msg.ToolResults))
+		for _, r := range msg.ToolResults {
+			parts = append(parts, &genai.Part{
+				FunctionResponse: &genai.FunctionResponse{
+					ID:       r.CallID,
+					Name:     r.Name,
+					Response: map[string]any{"result": r.Content},
+				},
+			})
+		}
+		return &genai.Content{Role: string(RoleTool), Parts: parts}
+	case RoleModel:
+		var parts []*genai.Part
+		if msg.Text != "" {
+			parts = append(parts, genai.NewPartFromText(msg.Text))
+		}
+		for _, call := range msg.ToolCalls {
+			parts = append(parts, &genai.Part{
+				FunctionCall: &genai.FunctionCall{ID: call.ID, Name: call.Name, Args: call.Args},
+			})
+		}
+		return &genai.Content{Role: string(RoleModel), Parts: parts}
+	default:
+		return genai.NewContentFromText(msg.Text, genai.RoleUser)
+	}
+}
+
+func toGeminiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	gs := &genai.Schema{
+		Type:        genai.Type(strings.ToUpper(s.Type)),
+		Description: s.Description,
+		Required:    s.Required,
+		Enum:        s.Enum,
+		Items:       toGeminiSchema(s.Items),
+	}
+	if s.Properties != nil {
+		gs.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			gs.Properties[name] = toGeminiSchema(prop)
+		}
+	}
+	return gs
+}
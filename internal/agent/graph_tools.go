@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/depgraph"
+)
+
+// WithImportGraph は import-graph ツールを有効にします。rules を渡すと、ツールの結果にレイヤールール違反も含めます
+func WithImportGraph(graph depgraph.Source, rules *depgraph.Rules) Option {
+	return func(a *L5Agent) {
+		a.graph = graph
+		a.layering = rules
+	}
+}
+
+type importGraphArgs struct {
+	Package string `json:"package"`
+}
+
+func (a *L5Agent) importGraphTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "import-graph",
+		Description: "モジュール内パッケージの import グラフ（パッケージ -> import しているパッケージ）と循環依存、レイヤールール違反を返します。依存関係の方向や循環依存を確認するときに使用してください。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"package": {
+					Type:        TypeString,
+					Description: "このパッケージが関係する辺だけに絞り込む（モジュールルートからのディレクトリ。末尾 /... で配下すべて。例: internal/agent/...）",
+				},
+			},
+		},
+	}, func(ctx context.Context, args importGraphArgs) (string, error) {
+		return a.executeImportGraph(ctx, args.Package)
+	})
+}
+
+func (a *L5Agent) executeImportGraph(ctx context.Context, pattern string) (string, error) {
+	g, err := a.graph.Graph(ctx)
+	if err != nil {
+		return "", fmt.Errorf("agent: import graph: %w", err)
+	}
+
+	var b strings.Builder
+	if pattern == "" {
+		b.WriteString(g.String())
+	} else {
+		fmt.Fprintf(&b, "module %s (edges involving %s)\n", g.Module, pattern)
+		for _, from := range g.Packages {
+			for _, to := range g.Edges(from) {
+				if depgraph.Match(pattern, from) || depgraph.Match(pattern, to) {
+					fmt.Fprintf(&b, "%s -> %s\n", from, to)
+				}
+			}
+		}
+	}
+
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		b.WriteString("\nImport cycles:\n")
+		for _, c := range cycles {
+			fmt.Fprintf(&b, "%s\n", strings.Join(c, " <-> "))
+		}
+	}
+
+	if a.layering != nil {
+		violations := a.layering.Check(g)
+		if len(violations) == 0 {
+			fmt.Fprintf(&b, "\nNo layering rule violations (%s).\n", a.layering.Path)
+		} else {
+			fmt.Fprintf(&b, "\nLayering rule violations (%s; reported automatically, do not repeat them as findings):\n", a.layering.Path)
+			for _, v := range violations {
+				fmt.Fprintf(&b, "%s at %s:%d\n", v, v.Site.File, v.Site.Line)
+			}
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+// positionParams は位置指定ツールに共通の引数スキーマです
+func positionParams() map[string]*Schema {
+	return map[string]*Schema{
+		"file_path": {
+			Type:        TypeString,
+			Description: "対象のファイルパス（プロジェクトルートからの相対パス）",
+		},
+		"line": {
+			Type:        TypeInteger,
+			Description: "対象の行番号（1から始まる人間用の行番号）",
+		},
+		"character": {
+			Type:        TypeInteger,
+			Description: "対象の文字位置（1から始まる文字カラム）",
+		},
+	}
+}
+
+// positionArgs は位置指定ツールに共通の引数です（行・文字位置は1始まり）
+type positionArgs struct {
+	FilePath  string `json:"file_path"`
+	Line      int    `json:"line"`
+	Character int    `json:"character"`
+}
+
+type definitionArgs struct {
+	positionArgs
+	TypeDefinition bool `json:"type_definition"`
+}
+
+// positionRequired は位置指定ツールの必須引数です
+var positionRequired = []string{"file_path", "line", "character"}
+
+// lspTools は gopls を使うシンボル解析ツールです
+func (a *L5Agent) lspTools() []Tool {
+	return []Tool{
+		NewTool(ToolSpec{
+			Name:        "find-references",
+			Description: "指定されたファイル内の特定の行・文字位置にあるシンボルの参照元（References）を検索します。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: positionParams(),
+				Required:   positionRequired,
+			},
+		}, func(ctx context.Context, args positionArgs) (string, error) {
+			return a.executeFindReferences(ctx, args.FilePath, args.Line, args.Character)
+		}),
+		NewTool(ToolSpec{
+			Name:        "go-to-definition",
+			Description: "指定位置のシンボルの定義位置にジャンプします。type_definition を true にすると、式の型の定義位置を返します。",
+			Parameters: &Schema{
+				Type: TypeObject,
+				Properties: withProperty(positionParams(), "type_definition", &Schema{
+					Type:        TypeBoolean,
+					Description: "true なら値ではなく型の定義位置を返す",
+				}),
+				Required: positionRequired,
+			},
+		}, func(ctx context.Context, args definitionArgs) (string, error) {
+			return a.executeGoToDefinition(ctx, args.FilePath, args.Line, args.Character, args.TypeDefinition)
+		}),
+		NewTool(ToolSpec{
+			Name:        "hover",
+			Description: "指定位置のシンボルのシグネチャとドキュメントコメントを返します。ファイル全体を読まずに関数や型の概要を知りたいときに使用してください。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: positionParams(),
+				Required:   positionRequired,
+			},
+		}, func(ctx context.Context, args positionArgs) (string, error) {
+			return a.executeHover(ctx, args.FilePath, args.Line, args.Character)
+		}),
+		NewTool(ToolSpec{
+			Name:        "find-implementations",
+			Description: "指定位置の interface を実装している型、または指定位置の型が実装している interface を検索します。依存関係の逆転や interface の分離を確認するときに使用してください。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: positionParams(),
+				Required:   positionRequired,
+			},
+		}, func(ctx context.Context, args positionArgs) (string, error) {
+			return a.executeFindImplementations(ctx, args.FilePath, args.Line, args.Character)
+		}),
+	}
+}
+
+// relPath は LSP の file:// URI をプロジェクトルートからの相対パスに変換します
+func (a *L5Agent) relPath(uri string) string {
+	path := strings.TrimPrefix(uri, "file://")
+	if rel, err := filepath.Rel(a.rootPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// formatLocations は Location を "path:line:col" 形式で列挙します（1始まり）
+func (a *L5Agent) formatLocations(locs []lsp.Location) []string {
+	result := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		result = append(result, fmt.Sprintf("%s:%d:%d", a.relPath(loc.URI), loc.Range.Start.Line+1, loc.Range.Start.Character+1))
+	}
+	return result
+}
+
+func (a *L5Agent) executeFindReferences(ctx context.Context, relPath string, line, char int) (string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+
+	// LSPは 0-based index なので -1 する
+	lspLine := line - 1
+	lspChar := char - 1
+
+	fmt.Fprintf(os.Stderr, "   -> Searching in %s at %d:%d\n", relPath, lspLine, lspChar)
+
+	refs, err := a.analyzer.References(ctx, absPath, lspLine, lspChar)
+	if err != nil {
+		return "", fmt.Errorf("agent: find references %s:%d:%d: %w", relPath, line, char, err)
+	}
+
+	if len(refs) == 0 {
+		return "No references found.", nil
+	}
+
+	var result []string
+	for i, ref := range refs {
+		if a.limits.MaxReferences > 0 && i == a.limits.MaxReferences {
+			result = append(result, fmt.Sprintf("... and %d more", len(refs)-i))
+			break
+		}
+		result = append(result, fmt.Sprintf("%s:%d", a.relPath(ref.URI), ref.Range.Start.Line+1))
+	}
+
+	output := fmt.Sprintf("Found references:\n%s", strings.Join(result, "\n"))
+	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
+	return output, nil
+}
+
+func (a *L5Agent) executeGoToDefinition(ctx context.Context, relPath string, line, char int, typeDefinition bool) (string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+
+	lookup, what := a.analyzer.Definition, "definition"
+	if typeDefinition {
+		lookup, what = a.analyzer.TypeDefinition, "type definition"
+	}
+
+	locs, err := lookup(ctx, absPath, line-1, char-1)
+	if err != nil {
+		return "", fmt.Errorf("agent: go to %s %s:%d:%d: %w", what, relPath, line, char, err)
+	}
+	if len(locs) == 0 {
+		return fmt.Sprintf("No %s found.", what), nil
+	}
+
+	output := fmt.Sprintf("Found %s:\n%s", what, strings.Join(a.formatLocations(locs), "\n"))
+	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
+	return output, nil
+}
+
+func (a *L5Agent) executeHover(ctx context.Context, relPath string, line, char int) (string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+
+	hover, err := a.analyzer.Hover(ctx, absPath, line-1, char-1)
+	if err != nil {
+		return "", fmt.Errorf("agent: hover %s:%d:%d: %w", relPath, line, char, err)
+	}
+	if hover == nil || strings.TrimSpace(hover.Contents.Value) == "" {
+		return "No hover information.", nil
+	}
+	return hover.Contents.Value, nil
+}
+
+func (a *L5Agent) executeFindImplementations(ctx context.Context, relPath string, line, char int) (string, error) {
+	absPath := filepath.Join(a.rootPath, relPath)
+
+	locs, err := a.analyzer.Implementation(ctx, absPath, line-1, char-1)
+	if err != nil {
+		return "", fmt.Errorf("agent: find implementations %s:%d:%d: %w", relPath, line, char, err)
+	}
+	if len(locs) == 0 {
+		return "No implementations found.", nil
+	}
+
+	output := fmt.Sprintf("Found implementations:\n%s", strings.Join(a.formatLocations(locs), "\n"))
+	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
+	return output, nil
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+	// openAITimeout は1回の Chat Completions 呼び出しの上限です。ローカルサーバーの長い生成も収まるよう長めにとる
+	openAITimeout = 5 * time.Minute
+)
+
+var _ ChatModel = (*OpenAIModel)(nil)
+
+// OpenAIModel は OpenAI 互換の Chat Completions API を使う ChatModel 実装です。
+// OpenAI 本家のほか、Ollama や llama.cpp server などのローカルサーバーにも接続できます。
+type OpenAIModel struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+	gen        GenerationConfig // ThinkingBudget と SafetySettings は Chat Completions にないため使わない
+}
+
+func NewOpenAIModel(baseURL, apiKey, model string, gen GenerationConfig) *OpenAIModel {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAIModel{
+		httpClient: &http.Client{Timeout: openAITimeout},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		gen:        gen,
+	}
+}
+
+// --- Chat Completions のワイヤフォーマット ---
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Parameters  *Schema `json:"parameters,omitempty"`
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+type openAIJSONSchema struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (m *OpenAIModel) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	body := openAIRequest{
+		Model:       m.model,
+		Temperature: m.gen.Temperature,
+		TopP:        m.gen.TopP,
+		MaxTokens:   m.gen.MaxOutputTokens,
+	}
+
+	if req.SystemPrompt != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: &req.SystemPrompt})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, toOpenAIMessages(msg)...)
+	}
+	for _, t := range req.Tools {
+		body.Tools = append(body.Tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+
+	if req.ResponseSchema != nil {
+		body.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: openAIJSONSchema{Name: "response", Schema: req.ResponseSchema},
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("openai: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	httpResp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: read response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai: status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var resp openAIResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("openai: parse response: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("openai: empty response")
+	}
+
+	choice := resp.Choices[0].Message
+	msg := Message{Role: RoleModel}
+	if choice.Content != nil {
+		msg.Text = *choice.Content
+	}
+	for _, tc := range choice.ToolCalls {
+		args := map[string]any{}
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("openai: parse arguments of %s: %w", tc.Function.Name, err)
+			}
+		}
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Args: args})
+	}
+	return &ChatResponse{Message: msg}, nil
+}
+
+func toOpenAIMessages(msg Message) []openAIMessage {
+	switch msg.Role {
+	case RoleTool:
+		// OpenAI はツール結果を1呼び出しにつき1メッセージで受け取る
+		out := make([]openAIMessage, 0, len(msg.ToolResults))
+		for _, r := range msg.ToolResults {
+			content := r.Content
+			out = append(out, openAIMessage{Role: "tool", Content: &content, ToolCallID: r.CallID})
+		}
+		return out
+	case RoleModel:
+		out := openAIMessage{Role: "assistant"}
+		if msg.Text != "" || len(msg.ToolCalls) == 0 {
+			text := msg.Text
+			out.Content = &text
+		}
+		for _, call := range msg.ToolCalls {
+			args, _ := json.Marshal(call.Args)
+			out.ToolCalls = append(out.ToolCalls, openAIToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: call.Name, Arguments: string(args)},
+			})
+		}
+		return []openAIMessage{out}
+	default:
+		text := msg.Text
+		return []openAIMessage{{Role: "user", Content: &text}}
+	}
+}
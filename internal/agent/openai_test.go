@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// openAIServer は Chat Completions API の代わりに reply を返し、受け取ったリクエストを記録するサーバーです
+func openAIServer(t *testing.T, status int, reply string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]any
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		requests = append(requests, req)
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestOpenAIModelToolCallRoundTrip(t *testing.T) {
+	srv, requests := openAIServer(t, http.StatusOK, `{"choices": [{"message": {
+		"role": "assistant", "content": null,
+		"tool_calls": [{"id": "call_2", "type": "function", "function": {"name": "read-file", "arguments": "{\"file_path\": \"b.go\"}"}}]
+	}}]}`)
+	m := NewOpenAIModel(srv.URL+"/v1/", "key", "local-model", GenerationConfig{})
+
+	req := &ChatRequest{
+		SystemPrompt: "system",
+		Messages: []Message{
+			{Role: RoleUser, Text: "review"},
+			{Role: RoleModel, ToolCalls: []ToolCall{{ID: "call_1", Name: "find-symbol", Args: map[string]any{"name": "F"}}}},
+			{Role: RoleTool, ToolResults: []ToolResult{{CallID: "call_1", Name: "find-symbol", Content: "a.go:1:6"}}},
+		},
+		Tools: []ToolSpec{{Name: "read-file", Description: "read", Parameters: &Schema{Type: TypeObject}}},
+	}
+	resp, err := m.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	calls := resp.Message.ToolCalls
+	if resp.Message.Role != RoleModel || resp.Message.Text != "" || len(calls) != 1 {
+		t.Fatalf("message = %+v", resp.Message)
+	}
+	if c := calls[0]; c.ID != "call_2" || c.Name != "read-file" || c.Args["file_path"] != "b.go" {
+		t.Errorf("tool call = %+v", c)
+	}
+
+	sent := (*requests)[0]
+	if sent["model"] != "local-model" {
+		t.Errorf("model = %v", sent["model"])
+	}
+	if _, ok := sent["response_format"]; ok {
+		t.Errorf("response_format sent without a response schema")
+	}
+	got, _ := json.Marshal(sent["messages"])
+	want := `[{"content":"system","role":"system"},` +
+		`{"content":"review","role":"user"},` +
+		`{"content":null,"role":"assistant","tool_calls":[{"function":{"arguments":"{\"name\":\"F\"}","name":"find-symbol"},"id":"call_1","type":"function"}]},` +
+		`{"content":"a.go:1:6","role":"tool","tool_call_id":"call_1"}]`
+	if string(got) != want {
+		t.Errorf("messages =\n%s\nwant\n%s", got, want)
+	}
+	tools, _ := json.Marshal(sent["tools"])
+	if want := `[{"function":{"description":"read","name":"read-file","parameters":{"type":"object"}},"type":"function"}]`; string(tools) != want {
+		t.Errorf("tools =\n%s\nwant\n%s", tools, want)
+	}
+}
+
+func TestOpenAIModelResponseSchema(t *testing.T) {
+	srv, requests := openAIServer(t, http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": "{\"summary\": \"ok\"}"}}]}`)
+	temperature := 0.2
+	m := NewOpenAIModel(srv.URL+"/v1", "key", "", GenerationConfig{Temperature: &temperature, MaxOutputTokens: 512})
+
+	resp, err := m.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: RoleUser, Text: "report"}},
+		ResponseSchema: &Schema{Type: TypeObject, Properties: map[string]*Schema{"summary": {Type: TypeString}}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Message.Text != `{"summary": "ok"}` {
+		t.Errorf("Text = %q", resp.Message.Text)
+	}
+
+	sent := (*requests)[0]
+	format, _ := json.Marshal(sent["response_format"])
+	if want := `{"json_schema":{"name":"response","schema":{"properties":{"summary":{"type":"string"}},"type":"object"}},"type":"json_schema"}`; string(format) != want {
+		t.Errorf("response_format =\n%s\nwant\n%s", format, want)
+	}
+	if sent["model"] != defaultOpenAIModel || sent["temperature"] != 0.2 || sent["max_tokens"] != float64(512) {
+		t.Errorf("model, temperature, max_tokens = %v, %v, %v", sent["model"], sent["temperature"], sent["max_tokens"])
+	}
+	if _, ok := sent["top_p"]; ok {
+		t.Error("top_p sent although it is not set")
+	}
+}
+
+func TestOpenAIModelErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reply  string
+		want   string
+	}{
+		{"error status with body", http.StatusBadRequest, `{"error": {"message": "bad model"}}` + "\n", `openai: status 400: {"error": {"message": "bad model"}}`},
+		// chatWithRetry はエラーメッセージの "429" でレート制限を見分ける
+		{"rate limited", http.StatusTooManyRequests, "slow down", "openai: status 429: slow down"},
+		{"no choices", http.StatusOK, `{"choices": []}`, "openai: empty response"},
+		{"broken arguments", http.StatusOK, `{"choices": [{"message": {"tool_calls": [{"id": "c", "function": {"name": "hover", "arguments": "{"}}]}}]}`, "openai: parse arguments of hover"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := openAIServer(t, tt.status, tt.reply)
+			_, err := NewOpenAIModel(srv.URL+"/v1", "key", "", GenerationConfig{}).Chat(context.Background(), &ChatRequest{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Chat error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIModelTimeout(t *testing.T) {
+	m := NewOpenAIModel("", "", "", GenerationConfig{})
+	if m.httpClient.Timeout <= 0 {
+		t.Errorf("HTTP client has no timeout; a hung server would block the review")
+	}
+}
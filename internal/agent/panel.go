@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/review"
+)
+
+// aggregatorPrompt はパネルレビューの結果を統合するモデルへのシステムプロンプトです
+const aggregatorPrompt = `あなたはコードレビューのパネルの議長です。複数のレビュアー（ペルソナ）が同じ変更を独立にレビューした結果を、1つのレビュー結果に統合してください。
+
+- 同じ問題を指している指摘は1つにまとめ、sources にまとめた指摘の番号をすべて列挙する
+- 指摘どうしが矛盾する場合は、根拠がより具体的な方を採り、採らなかった指摘は findings に含めない。その判断と理由は summary に書く
+- まとめた指摘の重要度が異なる場合は、最も根拠のある重要度を選ぶ
+- 入力にない新しい指摘を作ってはならない。すべての指摘は1つ以上の入力の指摘に基づくこと
+- summary には、各レビュアーの総評を踏まえた全体の総評を書く`
+
+// aggregateReport は統合結果としてモデルに出力させる JSON の形です
+type aggregateReport struct {
+	Summary  string             `json:"summary"`
+	Findings []aggregateFinding `json:"findings"`
+}
+
+type aggregateFinding struct {
+	Sources      []int  `json:"sources"`
+	Severity     string `json:"severity"`
+	Category     string `json:"category"`
+	Message      string `json:"message"`
+	SuggestedFix string `json:"suggested_fix"`
+}
+
+// panelSource はモデルに番号付きで示す入力の指摘です
+type panelSource struct {
+	persona string
+	finding review.Finding
+}
+
+// Aggregate はパネルレビューの各ペルソナのレポートを、重複をまとめ矛盾を解消した1つのレポートに統合します。
+// 各レポートの Persona は設定済みである必要があります。
+// モデルの呼び出しに失敗したか、モデルが統合結果を出力できなかった場合は、
+// 重複をまとめずにすべての指摘を並べたレポートを返します
+func Aggregate(ctx context.Context, model ChatModel, reports []*review.Report) (*review.Report, error) {
+	merged := review.Merge(reports...)
+
+	var sources []panelSource
+	for _, r := range reports {
+		for _, f := range r.Findings {
+			sources = append(sources, panelSource{persona: r.Persona, finding: f})
+		}
+	}
+	if len(reports) < 2 || len(sources) == 0 {
+		return merged, nil
+	}
+
+	history := []Message{{Role: RoleUser, Text: aggregationInput(reports, sources)}}
+	schema := aggregateSchema(sources)
+
+	var lastErr error
+	for attempt := 0; attempt < maxReportAttempts; attempt++ {
+		fmt.Fprintf(os.Stderr, "[panel] Aggregating %d findings from %d personas...\n", len(sources), len(reports))
+		resp, err := chatWithRetry(ctx, model, &ChatRequest{
+			SystemPrompt:   aggregatorPrompt,
+			Messages:       history,
+			ResponseSchema: schema,
+		})
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		findings, summary, err := parseAggregate(resp.Message.Text, sources)
+		if err == nil {
+			merged.Summary = summary
+			merged.Findings = findings
+			if err := merged.Validate(); err != nil {
+				return nil, err
+			}
+			return merged, nil
+		}
+		lastErr = err
+
+		history = append(history, resp.Message, Message{
+			Role: RoleUser,
+			Text: fmt.Sprintf("出力が不正です（%v）。スキーマに従ったJSONのみを出力し直してください。", err),
+		})
+	}
+
+	fmt.Fprintf(os.Stderr, "  Aggregation failed: %v\n", lastErr)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// aggregationInput は各ペルソナの総評と、番号付きの指摘の一覧を組み立てます
+func aggregationInput(reports []*review.Report, sources []panelSource) string {
+	var b strings.Builder
+	b.WriteString("## レビュアーごとの総評\n")
+	for _, r := range reports {
+		fmt.Fprintf(&b, "\n### %s\n%s\n", r.Persona, strings.TrimSpace(r.Summary))
+	}
+
+	b.WriteString("\n## 指摘の一覧\n")
+	for i, s := range sources {
+		f := s.finding
+		fmt.Fprintf(&b, "\n%d. [%s] %s/%s `%s`\n%s\n", i+1, s.persona, f.Severity, f.Category, f.Location(), strings.TrimSpace(f.Message))
+		if fix := strings.TrimSpace(f.SuggestedFix); fix != "" {
+			fmt.Fprintf(&b, "修正案: %s\n", fix)
+		}
+	}
+	return b.String()
+}
+
+// aggregateSchema は統合結果を拘束する JSON Schema を返します。カテゴリは入力の指摘のものに限ります
+func aggregateSchema(sources []panelSource) *Schema {
+	severities := make([]string, 0, len(review.Severities))
+	for _, s := range review.Severities {
+		severities = append(severities, string(s))
+	}
+	var categories []string
+	for _, s := range sources {
+		if !slices.Contains(categories, s.finding.Category) {
+			categories = append(categories, s.finding.Category)
+		}
+	}
+
+	return &Schema{
+		Type: TypeObject,
+		Properties: map[string]*Schema{
+			"summary": {
+				Type:        TypeString,
+				Description: "統合したレビュー全体の総評（Markdown可）。矛盾を解消した場合はその判断と理由も書く",
+			},
+			"findings": {
+				Type:        TypeArray,
+				Description: "統合後の指摘事項",
+				Items: &Schema{
+					Type: TypeObject,
+					Properties: map[string]*Schema{
+						"sources": {
+							Type:        TypeArray,
+							Description: "この指摘にまとめた入力の指摘の番号",
+							Items:       &Schema{Type: TypeInteger},
+						},
+						"severity":      {Type: TypeString, Enum: severities, Description: "重要度"},
+						"category":      {Type: TypeString, Enum: categories, Description: "レビュー観点のID"},
+						"message":       {Type: TypeString, Description: "指摘内容"},
+						"suggested_fix": {Type: TypeString, Description: "修正案（任意）"},
+					},
+					Required: []string{"sources", "severity", "category", "message"},
+				},
+			},
+		},
+		Required: []string{"summary", "findings"},
+	}
+}
+
+// parseAggregate はモデルの統合結果を指摘に変換します。
+// 位置は元の指摘から取り（同じファイルなら行範囲を合わせる）、指摘したペルソナとエビデンスは元の指摘を合わせたものにします
+func parseAggregate(text string, sources []panelSource) ([]review.Finding, string, error) {
+	var ar aggregateReport
+	if err := json.Unmarshal([]byte(trimCodeFence(text)), &ar); err != nil {
+		return nil, "", fmt.Errorf("parse aggregate: %w", err)
+	}
+
+	findings := []review.Finding{}
+	for i, af := range ar.Findings {
+		var picked []panelSource
+		for _, n := range af.Sources {
+			// 存在しない番号は捏造なので捨てる
+			if n >= 1 && n <= len(sources) {
+				picked = append(picked, sources[n-1])
+			}
+		}
+		if len(picked) == 0 {
+			return nil, "", fmt.Errorf("findings[%d]: no valid sources", i)
+		}
+
+		first := picked[0].finding
+		f := review.Finding{
+			File:         first.File,
+			StartLine:    first.StartLine,
+			EndLine:      first.EndLine,
+			Severity:     review.Severity(af.Severity),
+			Category:     af.Category,
+			Persona:      picked[0].persona,
+			Message:      af.Message,
+			SuggestedFix: af.SuggestedFix,
+		}
+		for _, s := range picked {
+			if s.finding.File == f.File && f.File != "" && s.finding.StartLine > 0 {
+				if f.StartLine == 0 || s.finding.StartLine < f.StartLine {
+					f.StartLine = s.finding.StartLine
+				}
+				f.EndLine = max(f.EndLine, s.finding.EndLine)
+			}
+			if !slices.Contains(f.Personas, s.persona) {
+				f.Personas = append(f.Personas, s.persona)
+			}
+			for _, e := range s.finding.Evidence {
+				if !slices.Contains(f.Evidence, e) {
+					f.Evidence = append(f.Evidence, e)
+				}
+			}
+		}
+		if err := f.Validate(); err != nil {
+			return nil, "", fmt.Errorf("findings[%d]: %w", i, err)
+		}
+		findings = append(findings, f)
+	}
+	return findings, ar.Summary, nil
+}
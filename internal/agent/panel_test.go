@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/review"
+)
+
+func TestParseAggregate(t *testing.T) {
+	sources := []panelSource{
+		{persona: "Go Expert", finding: review.Finding{
+			File: "a.go", StartLine: 10, EndLine: 12, Severity: review.SeverityMinor, Category: "errors", Message: "m1",
+			Evidence: []review.Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}},
+		}},
+		{persona: "Architect", finding: review.Finding{
+			File: "a.go", StartLine: 8, EndLine: 11, Severity: review.SeverityMajor, Category: "errors", Message: "m2",
+			Evidence: []review.Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}, {Tool: "hover"}},
+		}},
+		{persona: "Architect", finding: review.Finding{
+			File: "b.go", StartLine: 3, EndLine: 3, Severity: review.SeverityInfo, Category: "layout", Message: "m3",
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    []review.Finding
+		wantErr bool
+	}{
+		{
+			name: "merge findings on the same file",
+			text: `{"summary": "s", "findings": [{"sources": [1, 2], "severity": "major", "category": "errors", "message": "merged"}]}`,
+			want: []review.Finding{{
+				File: "a.go", StartLine: 8, EndLine: 12, Severity: review.SeverityMajor, Category: "errors",
+				Persona: "Go Expert", Personas: []string{"Go Expert", "Architect"}, Message: "merged",
+				Evidence: []review.Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}, {Tool: "hover"}},
+			}},
+		},
+		{
+			name: "location comes from the first source only for other files",
+			text: "```json\n" + `{"summary": "s", "findings": [{"sources": [3, 1], "severity": "info", "category": "layout", "message": "m"}]}` + "\n```",
+			want: []review.Finding{{
+				File: "b.go", StartLine: 3, EndLine: 3, Severity: review.SeverityInfo, Category: "layout",
+				Persona: "Architect", Personas: []string{"Architect", "Go Expert"}, Message: "m",
+				Evidence: []review.Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}},
+			}},
+		},
+		{
+			name: "out of range sources are dropped",
+			text: `{"summary": "s", "findings": [{"sources": [0, 3, 9], "severity": "info", "category": "layout", "message": "m"}]}`,
+			want: []review.Finding{{
+				File: "b.go", StartLine: 3, EndLine: 3, Severity: review.SeverityInfo, Category: "layout",
+				Persona: "Architect", Personas: []string{"Architect"}, Message: "m",
+			}},
+		},
+		{
+			name:    "no valid sources",
+			text:    `{"summary": "s", "findings": [{"sources": [4], "severity": "info", "category": "layout", "message": "m"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid severity",
+			text:    `{"summary": "s", "findings": [{"sources": [1], "severity": "blocker", "category": "errors", "message": "m"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			text:    "the panel agrees",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, summary, err := parseAggregate(tt.text, sources)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAggregate: want error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAggregate: %v", err)
+			}
+			if summary != "s" {
+				t.Errorf("summary = %q, want %q", summary, "s")
+			}
+			if !slices.EqualFunc(got, tt.want, equalFinding) {
+				t.Errorf("findings =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalFinding(a, b review.Finding) bool {
+	return a.File == b.File && a.StartLine == b.StartLine && a.EndLine == b.EndLine &&
+		a.Severity == b.Severity && a.Category == b.Category && a.Persona == b.Persona &&
+		slices.Equal(a.Personas, b.Personas) && a.Message == b.Message &&
+		a.SuggestedFix == b.SuggestedFix && slices.Equal(a.Evidence, b.Evidence)
+}
+
+// failingModel はすべての呼び出しに失敗するモデルです
+type failingModel struct{ calls int }
+
+func (m *failingModel) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	m.calls++
+	return nil, errors.New("backend unavailable")
+}
+
+func TestAggregateFallsBackWhenModelFails(t *testing.T) {
+	reports := []*review.Report{
+		{Persona: "Go Expert", Summary: "s1", Findings: []review.Finding{{File: "a.go", StartLine: 1, Severity: review.SeverityMinor, Category: "c", Message: "m1"}}},
+		{Persona: "Architect", Summary: "s2", Findings: []review.Finding{{File: "b.go", StartLine: 2, Severity: review.SeverityMajor, Category: "c", Message: "m2"}}},
+	}
+	model := &failingModel{}
+	got, err := Aggregate(context.Background(), model, reports)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if model.calls != 1 {
+		t.Errorf("model called %d times, want 1", model.calls)
+	}
+	if !slices.Equal(got.Panel, []string{"Go Expert", "Architect"}) {
+		t.Errorf("Panel = %v", got.Panel)
+	}
+	// 統合できなかったので、すべての指摘が重要度順に並ぶ
+	var messages []string
+	for _, f := range got.Findings {
+		messages = append(messages, f.Message)
+	}
+	if !slices.Equal(messages, []string{"m2", "m1"}) {
+		t.Errorf("findings = %v, want [m2 m1]", messages)
+	}
+}
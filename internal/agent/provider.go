@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// Role は会話メッセージの発言者を表します
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleModel Role = "model"
+	RoleTool  Role = "tool"
+)
+
+// JSON Schema の型名です
+const (
+	TypeObject  = "object"
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+)
+
+// Message はプロバイダに依存しない会話履歴の1メッセージです
+type Message struct {
+	Role        Role
+	Text        string
+	ToolCalls   []ToolCall   // RoleModel のときのツール呼び出し
+	ToolResults []ToolResult // RoleTool のときのツール実行結果
+
+	// native はプロバイダ固有の元データです。
+	// Gemini の thought signature のように、往復させないと失われる情報を保持します。
+	native any
+}
+
+// ToolCall はモデルが要求したツール呼び出しです
+type ToolCall struct {
+	ID   string
+	Name string
+	Args map[string]any
+}
+
+// ToolResult はツール呼び出しに対する実行結果です
+type ToolResult struct {
+	CallID  string
+	Name    string
+	Content string
+}
+
+// Schema はツール引数を表す JSON Schema のサブセットです
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+}
+
+// ToolSpec はモデルに公開するツールの宣言です
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  *Schema
+}
+
+// ChatRequest は1ターン分の推論リクエストです
+type ChatRequest struct {
+	SystemPrompt string
+	Messages     []Message
+	Tools        []ToolSpec
+
+	// ResponseSchema が指定されたとき、モデルはこのスキーマに従う JSON を Text として返します
+	ResponseSchema *Schema
+}
+
+// ChatResponse はモデルの応答です。ToolCalls が空なら最終回答として扱います
+type ChatResponse struct {
+	Message Message
+}
+
+// ChatModel はツール呼び出しに対応したチャットモデルの抽象です
+type ChatModel interface {
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// サポートするプロバイダ名です
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+)
+
+// ProviderConfig は ChatModel の生成に必要な設定です
+type ProviderConfig struct {
+	Provider   string // gemini | openai
+	Model      string // 空ならプロバイダごとのデフォルト
+	APIKey     string
+	BaseURL    string // OpenAI 互換エンドポイントのベースURL（例: http://localhost:11434/v1）
+	Generation GenerationConfig
+}
+
+// ProviderName は Provider の省略（gemini）を解決したプロバイダ名を返します
+func (c ProviderConfig) ProviderName() string {
+	if c.Provider == "" {
+		return ProviderGemini
+	}
+	return c.Provider
+}
+
+// Override は model と gen で指定された項目を重ねた設定を返します。model が空なら元のモデルを使います
+func (c ProviderConfig) Override(model string, gen GenerationConfig) ProviderConfig {
+	if model != "" {
+		c.Model = model
+	}
+	c.Generation = c.Generation.Merge(gen)
+	return c
+}
+
+// GenerationConfig はモデルの生成パラメータです。nil やゼロ値の項目はプロバイダの既定値を使います
+type GenerationConfig struct {
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+	ThinkingBudget  *int              // 思考に使うトークン数（0: 思考しない、-1: モデルに任せる）。Gemini のみ
+	SafetySettings  map[string]string // 有害カテゴリ → ブロックのしきい値（例: dangerous_content: block_only_high）。Gemini のみ
+}
+
+// Merge は o で指定された項目で g を上書きした設定を返します。SafetySettings はカテゴリごとに重ねます
+func (g GenerationConfig) Merge(o GenerationConfig) GenerationConfig {
+	if o.Temperature != nil {
+		g.Temperature = o.Temperature
+	}
+	if o.TopP != nil {
+		g.TopP = o.TopP
+	}
+	if o.MaxOutputTokens != 0 {
+		g.MaxOutputTokens = o.MaxOutputTokens
+	}
+	if o.ThinkingBudget != nil {
+		g.ThinkingBudget = o.ThinkingBudget
+	}
+	if len(o.SafetySettings) > 0 {
+		merged := maps.Clone(g.SafetySettings)
+		if merged == nil {
+			merged = make(map[string]string, len(o.SafetySettings))
+		}
+		maps.Copy(merged, o.SafetySettings)
+		g.SafetySettings = merged
+	}
+	return g
+}
+
+// Validate は値の範囲を検証します
+func (g GenerationConfig) Validate() error {
+	if t := g.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *t)
+	}
+	if p := g.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1, got %v", *p)
+	}
+	if g.MaxOutputTokens < 0 {
+		return fmt.Errorf("max_output_tokens must not be negative, got %d", g.MaxOutputTokens)
+	}
+	if b := g.ThinkingBudget; b != nil && *b < -1 {
+		return fmt.Errorf("thinking_budget must be -1 (dynamic) or more, got %d", *b)
+	}
+	return nil
+}
+
+// NewChatModel は設定に応じた ChatModel を生成します
+func NewChatModel(ctx context.Context, cfg ProviderConfig) (ChatModel, error) {
+	if err := cfg.Generation.Validate(); err != nil {
+		return nil, fmt.Errorf("agent: invalid generation config: %w", err)
+	}
+	switch cfg.Provider {
+	case "", ProviderGemini:
+		return NewGeminiModel(ctx, cfg.APIKey, cfg.Model, cfg.Generation)
+	case ProviderOpenAI:
+		return NewOpenAIModel(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Generation), nil
+	default:
+		return nil, fmt.Errorf("agent: unknown provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestGenerationConfigMerge(t *testing.T) {
+	base := GenerationConfig{
+		Temperature:     ptr(0.2),
+		TopP:            ptr(0.9),
+		MaxOutputTokens: 1024,
+		ThinkingBudget:  ptr(-1),
+		SafetySettings:  map[string]string{"harassment": "block_none", "dangerous_content": "block_low_and_above"},
+	}
+
+	// 空の設定を重ねても何も変わらない
+	if got := base.Merge(GenerationConfig{}); !equalGeneration(got, base) {
+		t.Errorf("Merge(empty) = %s, want %s", formatGeneration(got), formatGeneration(base))
+	}
+
+	got := base.Merge(GenerationConfig{
+		Temperature:    ptr(0.0), // 0 も指定として扱う
+		ThinkingBudget: ptr(0),
+		SafetySettings: map[string]string{"dangerous_content": "block_only_high"},
+	})
+	want := GenerationConfig{
+		Temperature:     ptr(0.0),
+		TopP:            ptr(0.9),
+		MaxOutputTokens: 1024,
+		ThinkingBudget:  ptr(0),
+		SafetySettings:  map[string]string{"harassment": "block_none", "dangerous_content": "block_only_high"},
+	}
+	if !equalGeneration(got, want) {
+		t.Errorf("Merge = %s, want %s", formatGeneration(got), formatGeneration(want))
+	}
+	// SafetySettings はカテゴリごとに重ね、元の map は書き換えない
+	if base.SafetySettings["dangerous_content"] != "block_low_and_above" {
+		t.Errorf("Merge modified the receiver's safety settings: %v", base.SafetySettings)
+	}
+
+	got = GenerationConfig{}.Merge(GenerationConfig{MaxOutputTokens: 10, SafetySettings: map[string]string{"hate_speech": "off"}})
+	if got.MaxOutputTokens != 10 || !maps.Equal(got.SafetySettings, map[string]string{"hate_speech": "off"}) {
+		t.Errorf("Merge onto empty = %s", formatGeneration(got))
+	}
+}
+
+func TestGenerationConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  GenerationConfig
+		want string // 空なら成功
+	}{
+		{name: "zero value"},
+		{
+			name: "bounds are inclusive",
+			gen:  GenerationConfig{Temperature: ptr(2.0), TopP: ptr(1.0), MaxOutputTokens: 1, ThinkingBudget: ptr(-1)},
+		},
+		{name: "temperature 0", gen: GenerationConfig{Temperature: ptr(0.0), ThinkingBudget: ptr(0)}},
+		{name: "temperature too high", gen: GenerationConfig{Temperature: ptr(2.5)}, want: "temperature must be between 0 and 2, got 2.5"},
+		{name: "negative temperature", gen: GenerationConfig{Temperature: ptr(-0.1)}, want: "temperature must be between 0 and 2, got -0.1"},
+		{name: "top_p 0", gen: GenerationConfig{TopP: ptr(0.0)}, want: "top_p must be greater than 0 and at most 1, got 0"},
+		{name: "top_p too high", gen: GenerationConfig{TopP: ptr(1.5)}, want: "top_p must be greater than 0 and at most 1, got 1.5"},
+		{name: "negative max_output_tokens", gen: GenerationConfig{MaxOutputTokens: -1}, want: "max_output_tokens must not be negative, got -1"},
+		{name: "thinking_budget below -1", gen: GenerationConfig{ThinkingBudget: ptr(-2)}, want: "thinking_budget must be -1 (dynamic) or more, got -2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.gen.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Validate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func equalGeneration(a, b GenerationConfig) bool {
+	return formatGeneration(a) == formatGeneration(b)
+}
+
+// formatGeneration はポインタの指す値で比べられるよう設定を文字列にします
+func formatGeneration(g GenerationConfig) string {
+	deref := func(p any) any {
+		switch p := p.(type) {
+		case *float64:
+			if p != nil {
+				return *p
+			}
+		case *int:
+			if p != nil {
+				return *p
+			}
+		}
+		return nil
+	}
+	return fmt.Sprintf("{temperature=%v top_p=%v max_output_tokens=%d thinking_budget=%v safety=%v}",
+		deref(g.Temperature), deref(g.TopP), g.MaxOutputTokens, deref(g.ThinkingBudget), g.SafetySettings)
+}
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/review"
+)
+
+const maxReportAttempts = 2
+
+// modelReport はモデルに出力させる JSON の形です。
+// evidence はツール呼び出しログの番号（1始まり）で受け取り、review.Evidence に変換します。
+type modelReport struct {
+	Summary  string         `json:"summary"`
+	Findings []modelFinding `json:"findings"`
+}
+
+type modelFinding struct {
+	File         string `json:"file"`
+	StartLine    int    `json:"start_line"`
+	EndLine      int    `json:"end_line"`
+	Severity     string `json:"severity"`
+	Category     string `json:"category"`
+	Message      string `json:"message"`
+	SuggestedFix string `json:"suggested_fix"`
+	Evidence     []int  `json:"evidence"`
+}
+
+// reportSchema は最終回答を拘束する JSON Schema を返します。
+// categories が空でなければ、指摘のカテゴリをペルソナのレビュー観点IDに限定します。
+func reportSchema(categories []string) *Schema {
+	severities := make([]string, 0, len(review.Severities))
+	for _, s := range review.Severities {
+		severities = append(severities, string(s))
+	}
+
+	return &Schema{
+		Type: TypeObject,
+		Properties: map[string]*Schema{
+			"summary": {
+				Type:        TypeString,
+				Description: "レビュー全体の総評（Markdown可）",
+			},
+			"findings": {
+				Type:        TypeArray,
+				Description: "個々の指摘事項",
+				Items: &Schema{
+					Type: TypeObject,
+					Properties: map[string]*Schema{
+						"file":          {Type: TypeString, Description: "対象のファイルパス（プロジェクトルートからの相対パス）。プロジェクト全体への指摘なら空文字"},
+						"start_line":    {Type: TypeInteger, Description: "指摘範囲の開始行（1始まり）。ファイル全体なら0"},
+						"end_line":      {Type: TypeInteger, Description: "指摘範囲の終了行（1始まり）"},
+						"severity":      {Type: TypeString, Enum: severities, Description: "重要度"},
+						"category":      {Type: TypeString, Enum: categories, Description: "レビュー観点のID"},
+						"message":       {Type: TypeString, Description: "指摘内容"},
+						"suggested_fix": {Type: TypeString, Description: "修正案（任意）"},
+						"evidence": {
+							Type:        TypeArray,
+							Description: "根拠となったツール呼び出しの番号",
+							Items:       &Schema{Type: TypeInteger},
+						},
+					},
+					Required: []string{"file", "start_line", "end_line", "severity", "category", "message"},
+				},
+			},
+		},
+		Required: []string{"summary", "findings"},
+	}
+}
+
+// reportPrompt はツール呼び出しログを添えて構造化出力を依頼するプロンプトを組み立てます
+func reportPrompt(calls []review.Evidence) string {
+	var b strings.Builder
+	b.WriteString("これまでの調査結果を、指定されたJSONスキーマに従って出力してください。")
+	b.WriteString("指摘ごとに、根拠となったツール呼び出しの番号を evidence に列挙してください。\n")
+	if len(calls) == 0 {
+		b.WriteString("\nツール呼び出しはありません。\n")
+		return b.String()
+	}
+	b.WriteString("\nツール呼び出しログ:\n")
+	for i, c := range calls {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, c)
+	}
+	return b.String()
+}
+
+// recordCall はツール呼び出しをエビデンス候補として記録し、ログに出します
+func (a *L5Agent) recordCall(call ToolCall) {
+	args, _ := json.Marshal(call.Args)
+	a.calls = append(a.calls, review.Evidence{Tool: call.Name, Args: string(args)})
+	fmt.Fprintf(os.Stderr, "  Tool: %s %s\n", call.Name, args)
+}
+
+// finalize は ReAct ループの最終回答を構造化されたレポートに変換します
+func (a *L5Agent) finalize(ctx context.Context, draft string) (*review.Report, error) {
+	a.history = append(a.history, Message{Role: RoleUser, Text: reportPrompt(a.calls)})
+
+	var lastErr error
+	for attempt := 0; attempt < maxReportAttempts; attempt++ {
+		resp, err := a.chat(ctx, &ChatRequest{
+			SystemPrompt:   a.systemPrompt(),
+			Messages:       a.history,
+			ResponseSchema: reportSchema(a.persona.AspectIDs()),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		report, err := a.parseReport(resp.Message.Text)
+		if err == nil {
+			return report, nil
+		}
+		lastErr = err
+
+		a.history = append(a.history, resp.Message, Message{
+			Role: RoleUser,
+			Text: fmt.Sprintf("出力が不正です（%v）。スキーマに従ったJSONのみを出力し直してください。", err),
+		})
+	}
+
+	// 構造化に失敗しても調査結果は失わないよう、最終回答を総評として返す
+	fmt.Fprintf(os.Stderr, "  Structured output failed: %v\n", lastErr)
+	return &review.Report{Summary: draft, Findings: []review.Finding{}}, nil
+}
+
+// trimCodeFence はモデルが JSON をコードブロックで囲んだときに外します
+func trimCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return text
+}
+
+func (a *L5Agent) parseReport(text string) (*review.Report, error) {
+	var mr modelReport
+	if err := json.Unmarshal([]byte(trimCodeFence(text)), &mr); err != nil {
+		return nil, fmt.Errorf("parse report: %w", err)
+	}
+
+	report := &review.Report{Summary: mr.Summary}
+	for _, mf := range mr.Findings {
+		f := review.Finding{
+			File:         mf.File,
+			StartLine:    mf.StartLine,
+			EndLine:      mf.EndLine,
+			Severity:     review.Severity(mf.Severity),
+			Category:     mf.Category,
+			Message:      mf.Message,
+			SuggestedFix: mf.SuggestedFix,
+		}
+		for _, n := range mf.Evidence {
+			// 存在しない番号は捏造されたエビデンスなので捨てる
+			if n >= 1 && n <= len(a.calls) {
+				f.Evidence = append(f.Evidence, a.calls[n-1])
+			}
+		}
+		report.Findings = append(report.Findings, f)
+	}
+
+	if err := report.Validate(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
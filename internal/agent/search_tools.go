@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/workspace"
+)
+
+// maxSearchResults はモデルが指定できる検索結果の既定の上限です
+const maxSearchResults = 300
+
+// WithSearcher は search-code ツールを有効にします
+func WithSearcher(s workspace.Searcher) Option {
+	return func(a *L5Agent) {
+		a.searcher = s
+	}
+}
+
+type searchCodeArgs struct {
+	Pattern    string   `json:"pattern"`
+	Regex      bool     `json:"regex"`
+	IgnoreCase bool     `json:"ignore_case"`
+	Paths      []string `json:"paths"`
+	MaxResults int      `json:"max_results"`
+}
+
+func (a *L5Agent) searchCodeTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "search-code",
+		Description: "プロジェクト内のファイルを文字列または正規表現で検索し、一致した行を「ファイル:行: 内容」の形式で返します（.gitignore の対象は除外）。宣言されたシンボル名以外（エラーメッセージ、設定キー、コメント、呼び出しパターンなど）でコードを探すときに使用してください。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"pattern": {
+					Type:        TypeString,
+					Description: "検索する文字列（regex が true なら RE2 の正規表現）",
+				},
+				"regex": {
+					Type:        TypeBoolean,
+					Description: "pattern を正規表現として扱う",
+				},
+				"ignore_case": {
+					Type:        TypeBoolean,
+					Description: "大文字・小文字を区別しない",
+				},
+				"paths": {
+					Type:        TypeArray,
+					Items:       &Schema{Type: TypeString},
+					Description: "対象を絞り込む glob（例: *.go, internal/agent, internal/**/*_test.go）。省略時は全ファイル",
+				},
+				"max_results": {
+					Type:        TypeInteger,
+					Description: fmt.Sprintf("返す一致の上限（既定 %d、最大 %d）", min(workspace.DefaultMaxResults, a.maxSearchResults()), a.maxSearchResults()),
+				},
+			},
+			Required: []string{"pattern"},
+		},
+	}, func(ctx context.Context, args searchCodeArgs) (string, error) {
+		return a.executeSearchCode(ctx, workspace.SearchOptions{
+			Pattern:    args.Pattern,
+			Regex:      args.Regex,
+			IgnoreCase: args.IgnoreCase,
+			Paths:      args.Paths,
+			MaxResults: args.MaxResults,
+		})
+	})
+}
+
+// maxSearchResults はペルソナの設定を反映した、モデルが指定できる検索結果の上限です
+func (a *L5Agent) maxSearchResults() int {
+	return cmp.Or(a.limits.MaxSearchResults, maxSearchResults)
+}
+
+func (a *L5Agent) executeSearchCode(ctx context.Context, opts workspace.SearchOptions) (string, error) {
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = workspace.DefaultMaxResults
+	}
+	opts.MaxResults = min(opts.MaxResults, a.maxSearchResults())
+	result, err := a.searcher.Search(ctx, opts)
+	if err != nil {
+		return "", fmt.Errorf("agent: search code %q: %w", opts.Pattern, err)
+	}
+	if len(result.Matches) == 0 {
+		return fmt.Sprintf("No matches for %q (%d files searched).", opts.Pattern, result.Files), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d matches for %q:\n", len(result.Matches), opts.Pattern)
+	for _, m := range result.Matches {
+		fmt.Fprintf(&b, "%s\n", m)
+	}
+	if result.Truncated {
+		b.WriteString("... more matches omitted; narrow the pattern or paths, or raise max_results.\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+)
+
+type findSymbolArgs struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+func (a *L5Agent) findSymbolTool() Tool {
+	return NewTool(ToolSpec{
+		Name:        "find-symbol",
+		Description: "シンボル名（関数名、型名、変数名など）からソースコード上の定義位置（ファイルパス、行番号、文字位置）と種類を検索します。「Client.Close」「(*Client).Close」「lsp.NewClient」のようにレシーバの型やパッケージで修飾して絞り込めます。完全一致がなければ名前の近いシンボルを「closest candidates」として返します（同名のシンボルではないので、使う前に確認してください）。シンボルの参照元を調べたいがファイルや行番号が不明な場合、まずこのツールで位置を特定してからfind_referencesを使ってください。",
+		Parameters: &Schema{
+			Type: TypeObject,
+			Properties: map[string]*Schema{
+				"name": {
+					Type:        TypeString,
+					Description: "検索するシンボル名（例: SurahService, NewClient, Client.ListSurahs）",
+				},
+				"kind": {
+					Type: TypeString,
+					Enum: []string{
+						symbol.KindFunction, symbol.KindMethod, symbol.KindStruct, symbol.KindInterface,
+						symbol.KindType, symbol.KindField, symbol.KindVariable, symbol.KindConstant,
+					},
+					Description: "シンボルの種類で絞り込む（省略時は全種類）",
+				},
+			},
+			Required: []string{"name"},
+		},
+	}, func(ctx context.Context, args findSymbolArgs) (string, error) {
+		return a.executeFindSymbol(ctx, args.Name, args.Kind)
+	})
+}
+
+func (a *L5Agent) executeFindSymbol(ctx context.Context, name, kind string) (string, error) {
+	locations, err := a.resolver.FindSymbol(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("agent: find symbol %q: %w", name, err)
+	}
+
+	var exact, fuzzy []string
+	for _, loc := range locations {
+		if kind != "" && loc.Kind != kind {
+			continue
+		}
+		line := fmt.Sprintf("%s:%d:%d", loc.FilePath, loc.Line, loc.Character)
+		if loc.Kind != "" {
+			line += " " + loc.Kind
+		}
+		if loc.Name != "" && loc.Name != name {
+			line += " " + loc.Name
+		}
+		if loc.Signature != "" {
+			line += "\n    " + loc.Signature
+		}
+		if loc.Fuzzy {
+			fuzzy = append(fuzzy, line)
+		} else {
+			exact = append(exact, line)
+		}
+	}
+
+	var sections []string
+	if len(exact) > 0 {
+		sections = append(sections, fmt.Sprintf("Found symbol %q at:\n%s", name, strings.Join(exact, "\n")))
+	}
+	// 近い候補は別の見出しにして、完全一致と取り違えないようにする
+	switch {
+	case len(fuzzy) > 0 && len(exact) > 0:
+		sections = append(sections, fmt.Sprintf("Other symbols with similar names:\n%s", strings.Join(fuzzy, "\n")))
+	case len(fuzzy) > 0:
+		sections = append(sections, fmt.Sprintf("No exact match for symbol %q; closest candidates:\n%s", name, strings.Join(fuzzy, "\n")))
+	}
+	if len(sections) == 0 {
+		return fmt.Sprintf("Symbol %q not found.", name), nil
+	}
+
+	output := strings.Join(sections, "\n\n")
+	fmt.Fprintf(os.Stderr, "   -> %s\n", output)
+	return output, nil
+}
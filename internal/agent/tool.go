@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Tool はエージェントがモデルに公開するツールです。
+// Execute は検証前の引数（モデル出力の JSON をデコードした map）を受け取ります
+type Tool interface {
+	Spec() ToolSpec
+	Execute(ctx context.Context, args map[string]any) (string, error)
+}
+
+// typedTool は引数をスキーマで検証してから A にデコードして実行するツールです
+type typedTool[A any] struct {
+	spec ToolSpec
+	run  func(ctx context.Context, args A) (string, error)
+}
+
+// NewTool は spec と実行関数から Tool を作ります。
+// 引数は spec.Parameters で検証したうえで、json タグに従って A にデコードされます
+func NewTool[A any](spec ToolSpec, run func(ctx context.Context, args A) (string, error)) Tool {
+	return &typedTool[A]{spec: spec, run: run}
+}
+
+func (t *typedTool[A]) Spec() ToolSpec {
+	return t.spec
+}
+
+func (t *typedTool[A]) Execute(ctx context.Context, raw map[string]any) (string, error) {
+	var args A
+	if err := decodeArgs(t.spec.Parameters, raw, &args); err != nil {
+		return "", err
+	}
+	return t.run(ctx, args)
+}
+
+// decodeArgs は引数を検証して out にデコードします
+func decodeArgs(schema *Schema, raw map[string]any, out any) error {
+	raw = normalizeArgs(schema, raw)
+	if err := validateArgs(schema, raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("encode arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode arguments: %w", err)
+	}
+	return nil
+}
+
+// Registry はツールを登録順に保持し、名前で引きます
+type Registry struct {
+	tools  []Tool
+	byName map[string]Tool
+}
+
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{byName: make(map[string]Tool)}
+	for _, t := range tools {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register はツールを追加します。同じ名前のツールが既にあれば panic します
+func (r *Registry) Register(tools ...Tool) {
+	for _, t := range tools {
+		name := t.Spec().Name
+		if _, dup := r.byName[name]; dup {
+			panic(fmt.Sprintf("agent: tool %q registered twice", name))
+		}
+		r.tools = append(r.tools, t)
+		r.byName[name] = t
+	}
+}
+
+// Lookup は名前でツールを探します
+func (r *Registry) Lookup(name string) (Tool, bool) {
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// Specs はモデルに渡すツール宣言を登録順に返します
+func (r *Registry) Specs() []ToolSpec {
+	specs := make([]ToolSpec, len(r.tools))
+	for i, t := range r.tools {
+		specs[i] = t.Spec()
+	}
+	return specs
+}
+
+// Execute はモデルのツール呼び出しを該当するツールで実行します
+func (r *Registry) Execute(ctx context.Context, call ToolCall) (string, error) {
+	t, ok := r.byName[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+	return t.Execute(ctx, call.Args)
+}
+
+// withProperty は引数スキーマにプロパティを追加します
+func withProperty(props map[string]*Schema, name string, prop *Schema) map[string]*Schema {
+	props[name] = prop
+	return props
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/symbol"
+)
+
+// WithTypeChecker は型情報を使うツール（find-implementers, type-of, exported-api, list-importers）を有効にします
+func WithTypeChecker(types symbol.TypeChecker) Option {
+	return func(a *L5Agent) {
+		a.types = types
+	}
+}
+
+// packageParam は型情報ツールに共通のパッケージ指定の引数スキーマです
+func packageParam() *Schema {
+	return &Schema{
+		Type:        TypeString,
+		Description: "対象パッケージ（インポートパス、プロジェクトルートからのディレクトリ、またはパッケージ名。例: internal/lsp, lsp）",
+	}
+}
+
+type findImplementersArgs struct {
+	Interface string `json:"interface"`
+}
+
+type packageArgs struct {
+	Package string `json:"package"`
+}
+
+type listImportersArgs struct {
+	Package    string `json:"package"`
+	Transitive bool   `json:"transitive"`
+}
+
+// typeTools は型情報を使うツールの一覧です。WithTypeChecker が指定されたときだけ登録します
+func (a *L5Agent) typeTools() []Tool {
+	return []Tool{
+		NewTool(ToolSpec{
+			Name:        "find-implementers",
+			Description: "interface 名から、それを満たすプロジェクト内の型を型検査に基づいて列挙します（例: CodeAnalyzer, io.Reader, lsp.CodeAnalyzer）。依存性逆転のための interface が実際にどの層で実装されているかを確認するときに使用してください。",
+			Parameters: &Schema{
+				Type: TypeObject,
+				Properties: map[string]*Schema{
+					"interface": {
+						Type:        TypeString,
+						Description: "interface 名（パッケージ名で修飾可）",
+					},
+				},
+				Required: []string{"interface"},
+			},
+		}, func(ctx context.Context, args findImplementersArgs) (string, error) {
+			return a.executeFindImplementers(ctx, args.Interface)
+		}),
+		NewTool(ToolSpec{
+			Name:        "type-of",
+			Description: "指定位置の式・識別子の型と、識別子ならその宣言を返します。変数や戻り値が具象型か interface かを確認するときに使用してください。",
+			Parameters: &Schema{
+				Type:       TypeObject,
+				Properties: positionParams(),
+				Required:   positionRequired,
+			},
+		}, func(ctx context.Context, args positionArgs) (string, error) {
+			return a.executeTypeOf(ctx, args.FilePath, args.Line, args.Character)
+		}),
+		NewTool(ToolSpec{
+			Name:        "exported-api",
+			Description: "パッケージの公開 API（エクスポートされた関数・型・メソッド・フィールド・変数・定数）をシグネチャ付きで返します。パッケージが外部に何を公開しているかを確認するときに使用してください。",
+			Parameters: &Schema{
+				Type: TypeObject,
+				Properties: map[string]*Schema{
+					"package": packageParam(),
+				},
+				Required: []string{"package"},
+			},
+		}, func(ctx context.Context, args packageArgs) (string, error) {
+			return a.executeExportedAPI(ctx, args.Package)
+		}),
+		NewTool(ToolSpec{
+			Name:        "list-importers",
+			Description: "指定パッケージを import しているプロジェクト内のパッケージを返します。下位層のパッケージが上位層から import されていないか（依存関係の方向）を確認するときに使用してください。プロジェクト外のパッケージ（例: net/http）も指定できます。",
+			Parameters: &Schema{
+				Type: TypeObject,
+				Properties: map[string]*Schema{
+					"package": packageParam(),
+					"transitive": {
+						Type:        TypeBoolean,
+						Description: "true なら間接的に import しているパッケージも含める",
+					},
+				},
+				Required: []string{"package"},
+			},
+		}, func(ctx context.Context, args listImportersArgs) (string, error) {
+			return a.executeListImporters(ctx, args.Package, args.Transitive)
+		}),
+	}
+}
+
+func (a *L5Agent) executeFindImplementers(ctx context.Context, iface string) (string, error) {
+	impls, err := a.types.Implementers(ctx, iface)
+	if err != nil {
+		return "", fmt.Errorf("agent: find implementers %q: %w", iface, err)
+	}
+	if len(impls) == 0 {
+		return fmt.Sprintf("No project types implement %q.", iface), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Types implementing %q:\n", iface)
+	for _, impl := range impls {
+		name := impl.Name
+		if impl.Pointer {
+			name = "*" + name
+		}
+		fmt.Fprintf(&b, "%s:%d:%d %s %s\n", impl.FilePath, impl.Line, impl.Character, impl.Kind, name)
+	}
+	return b.String(), nil
+}
+
+func (a *L5Agent) executeTypeOf(ctx context.Context, relPath string, line, char int) (string, error) {
+	t, err := a.types.TypeAt(ctx, relPath, line, char)
+	if err != nil {
+		return "", fmt.Errorf("agent: type of %s:%d:%d: %w", relPath, line, char, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Expression: %s\n", t.Expr)
+	if t.Type != "" {
+		fmt.Fprintf(&b, "Type: %s\n", t.Type)
+	}
+	if t.Object != "" {
+		fmt.Fprintf(&b, "Declaration: %s\n", t.Object)
+	}
+	if t.Decl != "" {
+		fmt.Fprintf(&b, "Declared at: %s\n", t.Decl)
+	}
+	return b.String(), nil
+}
+
+func (a *L5Agent) executeExportedAPI(ctx context.Context, pkg string) (string, error) {
+	api, err := a.types.ExportedAPI(ctx, pkg)
+	if err != nil {
+		return "", fmt.Errorf("agent: exported api %q: %w", pkg, err)
+	}
+	if len(api) == 0 {
+		return fmt.Sprintf("Package %q exports nothing.", pkg), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Exported API of %q:\n", pkg)
+	for _, e := range api {
+		fmt.Fprintf(&b, "%s\n", e.Signature)
+	}
+	return b.String(), nil
+}
+
+func (a *L5Agent) executeListImporters(ctx context.Context, pkg string, transitive bool) (string, error) {
+	importers, err := a.types.Importers(ctx, pkg, transitive)
+	if err != nil {
+		return "", fmt.Errorf("agent: list importers %q: %w", pkg, err)
+	}
+	if len(importers) == 0 {
+		return fmt.Sprintf("No project packages import %q.", pkg), nil
+	}
+
+	what := "directly"
+	if transitive {
+		what = "directly or indirectly"
+	}
+	return fmt.Sprintf("Packages importing %q %s:\n%s\n", pkg, what, strings.Join(importers, "\n")), nil
+}
@@ -0,0 +1,237 @@
+// Package depgraph builds the import graph of a module's own packages and
+// checks it against declarative layering rules.
+package depgraph
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ImportSite is where an import is declared.
+type ImportSite struct {
+	File string // プロジェクトルートからの相対パス
+	Line int
+}
+
+// Graph is the import graph between the packages of one module. Packages are
+// identified by their directory relative to the module root ("." for the root
+// package), which is also how layering rules refer to them.
+type Graph struct {
+	Module   string
+	Packages []string                         // ソート済み
+	Imports  map[string]map[string]ImportSite // from -> to -> 最初の import 宣言
+}
+
+// Edges returns the packages that from imports, sorted.
+func (g *Graph) Edges(from string) []string {
+	to := make([]string, 0, len(g.Imports[from]))
+	for p := range g.Imports[from] {
+		to = append(to, p)
+	}
+	sort.Strings(to)
+	return to
+}
+
+// Source provides the import graph of a project.
+type Source interface {
+	Graph(ctx context.Context) (*Graph, error)
+}
+
+var _ Source = (*Loader)(nil)
+
+// Loader loads the graph with go/packages on first use and caches it.
+type Loader struct {
+	rootPath string
+
+	mu    sync.Mutex
+	graph *Graph
+}
+
+func NewLoader(rootPath string) *Loader {
+	return &Loader{rootPath: rootPath}
+}
+
+func (l *Loader) Graph(ctx context.Context) (*Graph, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.graph != nil {
+		return l.graph, nil
+	}
+	g, err := Load(ctx, l.rootPath)
+	if err != nil {
+		return nil, err
+	}
+	l.graph = g
+	return g, nil
+}
+
+// Load lists the packages under rootPath (./...) and their imports. Only
+// imports of packages in the same module become edges.
+func Load(ctx context.Context, rootPath string) (*Graph, error) {
+	rootPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &packages.Config{
+		Context: ctx,
+		Dir:     rootPath,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedModule,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %w", err)
+	}
+
+	g := &Graph{Imports: make(map[string]map[string]ImportSite)}
+	for _, p := range pkgs {
+		if p.Module != nil && p.Module.Main {
+			g.Module = p.Module.Path
+			break
+		}
+	}
+	if g.Module == "" {
+		return nil, fmt.Errorf("no main module found under %s", rootPath)
+	}
+
+	for _, p := range pkgs {
+		from, ok := g.rel(p.PkgPath)
+		if !ok {
+			continue
+		}
+		g.Packages = append(g.Packages, from)
+		edges := make(map[string]ImportSite)
+		for path := range p.Imports {
+			if to, ok := g.rel(path); ok {
+				edges[to] = ImportSite{}
+			}
+		}
+		if len(edges) > 0 {
+			importSites(rootPath, p.GoFiles, g.Module, edges)
+			g.Imports[from] = edges
+		}
+	}
+	sort.Strings(g.Packages)
+	return g, nil
+}
+
+// rel converts an import path of the module to a directory relative to the
+// module root.
+func (g *Graph) rel(pkgPath string) (string, bool) {
+	if pkgPath == g.Module {
+		return ".", true
+	}
+	rel, ok := strings.CutPrefix(pkgPath, g.Module+"/")
+	return rel, ok
+}
+
+// importSites fills in where each edge is declared, using the first file (in
+// name order) that imports the package.
+func importSites(rootPath string, files []string, module string, edges map[string]ImportSite) {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	fset := token.NewFileSet()
+	for _, file := range sorted {
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		relFile, _ := filepath.Rel(rootPath, file)
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			to := "."
+			if path != module {
+				var ok bool
+				if to, ok = strings.CutPrefix(path, module+"/"); !ok {
+					continue
+				}
+			}
+			if site, ok := edges[to]; ok && site.File == "" {
+				edges[to] = ImportSite{File: relFile, Line: fset.Position(spec.Pos()).Line}
+			}
+		}
+	}
+}
+
+// Cycles returns the import cycles of the graph, one per strongly connected
+// component with more than one package, each as a sorted package list.
+func (g *Graph) Cycles() [][]string {
+	// Tarjan の強連結成分分解
+	var (
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		next    int
+		cycles  [][]string
+	)
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v], lowlink[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.Edges(v) {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				sort.Strings(scc)
+				cycles = append(cycles, scc)
+			}
+		}
+	}
+
+	for _, p := range g.Packages {
+		if _, seen := index[p]; !seen {
+			visit(p)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// String renders the graph as an adjacency list.
+func (g *Graph) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "module %s\n", g.Module)
+	for _, p := range g.Packages {
+		edges := g.Edges(p)
+		if len(edges) == 0 {
+			fmt.Fprintf(&b, "%s\n", p)
+			continue
+		}
+		fmt.Fprintf(&b, "%s -> %s\n", p, strings.Join(edges, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package depgraph
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newGraph builds a graph from "from -> to" edges; every import is declared
+// at line 3 of from/x.go.
+func newGraph(edges ...string) *Graph {
+	g := &Graph{Module: "example.com/m", Imports: make(map[string]map[string]ImportSite)}
+	add := func(p string) {
+		if !slices.Contains(g.Packages, p) {
+			g.Packages = append(g.Packages, p)
+		}
+	}
+	for _, e := range edges {
+		from, to, _ := strings.Cut(e, " -> ")
+		add(from)
+		add(to)
+		if g.Imports[from] == nil {
+			g.Imports[from] = make(map[string]ImportSite)
+		}
+		g.Imports[from][to] = ImportSite{File: from + "/x.go", Line: 3}
+	}
+	slices.Sort(g.Packages)
+	return g
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []string
+		want  [][]string
+	}{
+		{
+			name:  "acyclic",
+			edges: []string{"a -> b", "b -> c", "a -> c"},
+		},
+		{
+			name:  "two packages",
+			edges: []string{"a -> b", "b -> a", "b -> c"},
+			want:  [][]string{{"a", "b"}},
+		},
+		{
+			name:  "separate cycles are sorted",
+			edges: []string{"z -> y", "y -> x", "x -> z", "b -> c", "c -> b", "c -> x"},
+			want:  [][]string{{"b", "c"}, {"x", "y", "z"}},
+		},
+		{
+			name:  "nested loops form one component",
+			edges: []string{"a -> b", "b -> c", "c -> a", "c -> b"},
+			want:  [][]string{{"a", "b", "c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newGraph(tt.edges...).Cycles()
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Cycles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
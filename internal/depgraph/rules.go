@@ -0,0 +1,194 @@
+package depgraph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/0muji4/llm-reviewer/internal/review"
+)
+
+// DefaultRulesFile is where layering rules are looked up, relative to the
+// project root, when no path is given.
+const DefaultRulesFile = ".llm-reviewer/layers.yaml"
+
+// defaultCategory is the finding category of rule violations. It matches the
+// Architect persona's aspect ID.
+const defaultCategory = "dependency-direction"
+
+// Rules is a layering-rules file:
+//
+//	category: dependency-direction   # 違反の指摘カテゴリ（省略時）
+//	severity: major                  # 違反の重大度（省略時）
+//	rules:
+//	  - package: internal/server
+//	    may_import: [internal/agent, internal/lsp, internal/workspace]
+//	  - package: internal/...
+//	    must_not_import: [cmd/..., internal/server]
+//	    reason: 内部パッケージはアダプタ層に依存しない
+//
+// Package patterns are directories relative to the module root; a trailing
+// "/..." also matches every package below it.
+type Rules struct {
+	Path     string          `yaml:"-"` // 読み込んだファイル（根拠の表示用）
+	Category string          `yaml:"category"`
+	Severity review.Severity `yaml:"severity"`
+	Rules    []Rule          `yaml:"rules"`
+}
+
+// Rule constrains the module-internal imports of the packages it matches.
+type Rule struct {
+	Package       string          `yaml:"package"`
+	MayImport     []string        `yaml:"may_import"`      // 指定時はここにないモジュール内パッケージの import を禁止する
+	MustNotImport []string        `yaml:"must_not_import"` // 禁止する import
+	Severity      review.Severity `yaml:"severity"`
+	Reason        string          `yaml:"reason"`
+}
+
+// LoadRules reads and validates a rules file.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layering rules %s: %w", path, err)
+	}
+
+	var r Rules
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse layering rules %s: %w", path, err)
+	}
+	r.Path = path
+	if r.Category == "" {
+		r.Category = defaultCategory
+	}
+	if r.Severity == "" {
+		r.Severity = review.SeverityMajor
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("layering rules %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+func (r *Rules) validate() error {
+	var errs []error
+	if !r.Severity.Valid() {
+		errs = append(errs, fmt.Errorf("invalid severity %q", r.Severity))
+	}
+	for i, rule := range r.Rules {
+		switch {
+		case rule.Package == "":
+			errs = append(errs, fmt.Errorf("rules[%d]: package is empty", i))
+		case rule.MayImport == nil && len(rule.MustNotImport) == 0:
+			errs = append(errs, fmt.Errorf("rules[%d] (%s): neither may_import nor must_not_import is set", i, rule.Package))
+		case rule.Severity != "" && !rule.Severity.Valid():
+			errs = append(errs, fmt.Errorf("rules[%d] (%s): invalid severity %q", i, rule.Package, rule.Severity))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Match reports whether pkg matches pattern ("a/b", "a/..." or "...").
+func Match(pattern, pkg string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if pattern == "..." {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == pattern
+}
+
+func matchAny(patterns []string, pkg string) bool {
+	for _, p := range patterns {
+		if Match(p, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation is an import that breaks a rule.
+type Violation struct {
+	From, To string
+	Site     ImportSite
+	Rule     *Rule
+}
+
+// Check returns the imports of g that break the rules, ordered by importing
+// package and target. An import breaking several rules is reported per rule.
+func (r *Rules) Check(g *Graph) []Violation {
+	var violations []Violation
+	for _, from := range g.Packages {
+		for i := range r.Rules {
+			rule := &r.Rules[i]
+			if !Match(rule.Package, from) {
+				continue
+			}
+			for _, to := range g.Edges(from) {
+				denied := matchAny(rule.MustNotImport, to)
+				notAllowed := rule.MayImport != nil && !matchAny(rule.MayImport, to)
+				if denied || notAllowed {
+					violations = append(violations, Violation{From: from, To: to, Site: g.Imports[from][to], Rule: rule})
+				}
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].From != violations[j].From {
+			return violations[i].From < violations[j].From
+		}
+		return violations[i].To < violations[j].To
+	})
+	return violations
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s -> %s (rule: %s)", v.From, v.To, v.Rule.Package)
+}
+
+// evidence attributes rule findings to the rules file instead of a model tool call.
+func (r *Rules) evidence() review.Evidence {
+	args, _ := json.Marshal(map[string]string{"rules": r.Path})
+	return review.Evidence{Tool: "layering-rules", Args: string(args)}
+}
+
+// Findings converts violations into review findings anchored at the import
+// declarations.
+func (r *Rules) Findings(violations []Violation) []review.Finding {
+	findings := make([]review.Finding, 0, len(violations))
+	for _, v := range violations {
+		severity := v.Rule.Severity
+		if severity == "" {
+			severity = r.Severity
+		}
+
+		var msg strings.Builder
+		fmt.Fprintf(&msg, "レイヤールール違反: `%s` が `%s` を import しています。", v.From, v.To)
+		if matchAny(v.Rule.MustNotImport, v.To) {
+			fmt.Fprintf(&msg, "`%s` は %s の import を禁止されています。", v.Rule.Package, strings.Join(v.Rule.MustNotImport, ", "))
+		} else if len(v.Rule.MayImport) == 0 {
+			fmt.Fprintf(&msg, "`%s` はモジュール内のパッケージを import できません。", v.Rule.Package)
+		} else {
+			fmt.Fprintf(&msg, "`%s` が import してよいのは %s のみです。", v.Rule.Package, strings.Join(v.Rule.MayImport, ", "))
+		}
+		if v.Rule.Reason != "" {
+			fmt.Fprintf(&msg, "（理由: %s）", v.Rule.Reason)
+		}
+
+		findings = append(findings, review.Finding{
+			File:      v.Site.File,
+			StartLine: v.Site.Line,
+			Severity:  severity,
+			Category:  r.Category,
+			Message:   msg.String(),
+			Evidence:  []review.Evidence{r.evidence()},
+		})
+	}
+	return findings
+}
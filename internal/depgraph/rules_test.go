@@ -0,0 +1,143 @@
+package depgraph
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/review"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, pkg string
+		want         bool
+	}{
+		{"internal/agent", "internal/agent", true},
+		{"./internal/agent", "internal/agent", true},
+		{"internal/agent", "internal/agent/agenttest", false},
+		{"internal/...", "internal", true},
+		{"internal/...", "internal/agent/agenttest", true},
+		{"internal/...", "internalx", false},
+		{"...", ".", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.pkg); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	g := newGraph(
+		"cmd/server -> internal/server",
+		"internal/server -> internal/agent",
+		"internal/server -> internal/review",
+		"internal/agent -> internal/review",
+		"internal/agent -> internal/server",
+		"internal/review -> cmd/server",
+	)
+	rules := &Rules{
+		Path:     ".llm-reviewer/layers.yaml",
+		Category: defaultCategory,
+		Severity: review.SeverityMajor,
+		Rules: []Rule{
+			{Package: "internal/server", MayImport: []string{"internal/agent"}},
+			{Package: "internal/...", MustNotImport: []string{"cmd/...", "internal/server"}, Severity: review.SeverityCritical, Reason: "内部パッケージはアダプタ層に依存しない"},
+			{Package: "internal/review", MayImport: []string{}},
+		},
+	}
+
+	violations := rules.Check(g)
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"internal/agent -> internal/server (rule: internal/...)",
+		"internal/review -> cmd/server (rule: internal/...)",
+		"internal/review -> cmd/server (rule: internal/review)",
+		"internal/server -> internal/review (rule: internal/server)",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Check =\n%q\nwant\n%q", got, want)
+	}
+
+	findings := rules.Findings(violations)
+	tests := []struct {
+		severity review.Severity
+		message  string
+	}{
+		{review.SeverityCritical, "`internal/...` は cmd/..., internal/server の import を禁止されています。（理由: 内部パッケージはアダプタ層に依存しない）"},
+		{review.SeverityCritical, "`internal/...` は cmd/..., internal/server の import を禁止されています。"},
+		{review.SeverityMajor, "`internal/review` はモジュール内のパッケージを import できません。"},
+		{review.SeverityMajor, "`internal/server` が import してよいのは internal/agent のみです。"},
+	}
+	for i, tt := range tests {
+		f := findings[i]
+		if f.Severity != tt.severity || !strings.Contains(f.Message, tt.message) {
+			t.Errorf("findings[%d] = %s %q, want %s containing %q", i, f.Severity, f.Message, tt.severity, tt.message)
+		}
+		if f.File != violations[i].From+"/x.go" || f.StartLine != 3 || f.Category != defaultCategory {
+			t.Errorf("findings[%d] at %s (%s), want %s/x.go:3 (%s)", i, f.Location(), f.Category, violations[i].From, defaultCategory)
+		}
+		if err := f.Validate(); err != nil {
+			t.Errorf("findings[%d]: %v", i, err)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			yaml: "rules:\n  - package: internal/...\n    must_not_import: [cmd/...]\n",
+		},
+		{
+			name: "empty may_import forbids every internal import",
+			yaml: "rules:\n  - package: internal/review\n    may_import: []\n",
+		},
+		{
+			name:    "no constraint",
+			yaml:    "rules:\n  - package: internal/review\n",
+			wantErr: "rules[0] (internal/review): neither may_import nor must_not_import is set",
+		},
+		{
+			name:    "no package",
+			yaml:    "rules:\n  - must_not_import: [cmd/...]\n",
+			wantErr: "rules[0]: package is empty",
+		},
+		{
+			name:    "bad severity",
+			yaml:    "severity: blocker\nrules: []\n",
+			wantErr: `invalid severity "blocker"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "layers.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := LoadRules(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadRules error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRules: %v", err)
+			}
+			if r.Category != defaultCategory || r.Severity != review.SeverityMajor || r.Path != path {
+				t.Errorf("LoadRules = category %q, severity %q, path %q", r.Category, r.Severity, r.Path)
+			}
+		})
+	}
+}
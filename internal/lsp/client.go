@@ -1,31 +1,40 @@
 package lsp
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
-var _ CodeAnalyzer = (*Client)(nil)
+var (
+	_ CodeAnalyzer   = (*Client)(nil)
+	_ SymbolProvider = (*Client)(nil)
+)
+
+// shutdownTimeout は Close 時に gopls の正常終了を待つ時間です
+const shutdownTimeout = 3 * time.Second
 
-// Client は gopls プロセスを管理する構造体です
+// Client は gopls プロセスを管理する構造体です。
+// 複数のゴルーチンから同時にリクエストを送ることができます。
 type Client struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout *bufio.Reader
-	mutex  sync.Mutex
-	idSeq  int
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	conn  *conn
+
+	diagnostics *diagnosticStore
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewClient は gopls を起動し、Initialize まで完了させてクライアントを返します
-func NewClient(rootPath string) (*Client, error) {
+func NewClient(ctx context.Context, rootPath string) (*Client, error) {
 	absRoot, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
@@ -54,52 +63,76 @@ func NewClient(rootPath string) (*Client, error) {
 	}
 
 	client := &Client{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: bufio.NewReader(stdoutPipe),
+		cmd:         cmd,
+		stdin:       stdin,
+		conn:        newConn(stdin, stdoutPipe),
+		diagnostics: newDiagnosticStore(),
 	}
+	client.conn.subscribe("textDocument/publishDiagnostics", client.diagnostics.publish)
 
 	// Initialize Handshake
 	// RootURI を正しく設定することが重要です
 	initParams := InitializeParams{
 		ProcessID: os.Getpid(),
 		RootURI:   "file://" + absRoot,
+		Capabilities: ClientCapabilities{
+			TextDocument: TextDocumentClientCapabilities{
+				DocumentSymbol: DocumentSymbolClientCapabilities{HierarchicalDocumentSymbolSupport: true},
+			},
+		},
 	}
 
-	if _, err := client.sendRequest("initialize", initParams); err != nil {
+	if _, err := client.conn.call(ctx, "initialize", initParams); err != nil {
 		_ = client.Close()
 		return nil, fmt.Errorf("failed to initialize: %w", err)
 	}
 
 	// Initialized Notification
-	client.sendNotification("initialized", struct{}{})
+	if err := client.conn.notify("initialized", struct{}{}); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to initialize: %w", err)
+	}
 
 	return client, nil
 }
 
+// Close は shutdown/exit で gopls を終了させます。応答がなければプロセスを強制終了します
 func (c *Client) Close() error {
-	if c.cmd.Process != nil {
-		return c.cmd.Process.Kill()
-	}
-	return nil
+	c.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if _, err := c.conn.call(ctx, "shutdown", nil); err == nil {
+			_ = c.conn.notify("exit", nil)
+		}
+		_ = c.stdin.Close()
+
+		exited := make(chan error, 1)
+		go func() { exited <- c.cmd.Wait() }()
+
+		select {
+		case <-exited:
+		case <-ctx.Done():
+			c.closeErr = c.cmd.Process.Kill()
+			<-exited
+		}
+	})
+	return c.closeErr
 }
 
 // References は指定されたファイル・位置の参照元を検索します
-func (c *Client) References(filePath string, line, char int) ([]Location, error) {
-	absPath, err := filepath.Abs(filePath)
+func (c *Client) References(ctx context.Context, filePath string, line, char int) ([]Location, error) {
+	pos, err := positionParams(filePath, line, char)
 	if err != nil {
 		return nil, err
 	}
 
 	params := ReferenceParams{
-		TextDocumentPositionParams: TextDocumentPositionParams{
-			TextDocument: TextDocumentIdentifier{URI: "file://" + absPath},
-			Position:     Position{Line: line, Character: char},
-		},
-		Context: ReferenceContext{IncludeDeclaration: true},
+		TextDocumentPositionParams: pos,
+		Context:                    ReferenceContext{IncludeDeclaration: true},
 	}
 
-	resp, err := c.sendRequest("textDocument/references", params)
+	resp, err := c.conn.call(ctx, "textDocument/references", params)
 	if err != nil {
 		return nil, err
 	}
@@ -112,78 +145,191 @@ func (c *Client) References(filePath string, line, char int) ([]Location, error)
 	return locations, nil
 }
 
-// --- Internal Helpers ---
+// Definition は指定位置のシンボルの定義位置を返します
+func (c *Client) Definition(ctx context.Context, filePath string, line, char int) ([]Location, error) {
+	return c.locationRequest(ctx, "textDocument/definition", filePath, line, char)
+}
 
-func (c *Client) sendRequest(method string, params interface{}) (json.RawMessage, error) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// TypeDefinition は指定位置の式の型の定義位置を返します
+func (c *Client) TypeDefinition(ctx context.Context, filePath string, line, char int) ([]Location, error) {
+	return c.locationRequest(ctx, "textDocument/typeDefinition", filePath, line, char)
+}
+
+// Implementation は指定位置の interface の実装（または型が実装する interface）を返します
+func (c *Client) Implementation(ctx context.Context, filePath string, line, char int) ([]Location, error) {
+	return c.locationRequest(ctx, "textDocument/implementation", filePath, line, char)
+}
 
-	c.idSeq++
-	id := c.idSeq
+// Hover は指定位置のシンボルのシグネチャとドキュメントを返します。情報がなければ nil を返します
+func (c *Client) Hover(ctx context.Context, filePath string, line, char int) (*Hover, error) {
+	params, err := positionParams(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
 
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		ID:      id,
-		Method:  method,
-		Params:  params,
+	resp, err := c.conn.call(ctx, "textDocument/hover", params)
+	if err != nil {
+		return nil, err
 	}
-	body, _ := json.Marshal(req)
-	fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n%s", len(body), body)
 
-	return c.readResponse()
+	var hover *Hover
+	if err := json.Unmarshal(resp, &hover); err != nil {
+		return nil, fmt.Errorf("failed to parse hover: %w", err)
+	}
+	return hover, nil
 }
 
-func (c *Client) sendNotification(method string, params interface{}) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// PrepareCallHierarchy は指定位置の関数・メソッドを呼び出し階層の起点として解決します
+func (c *Client) PrepareCallHierarchy(ctx context.Context, filePath string, line, char int) ([]CallHierarchyItem, error) {
+	params, err := positionParams(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.conn.call(ctx, "textDocument/prepareCallHierarchy", params)
+	if err != nil {
+		return nil, err
+	}
 
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  method,
-		Params:  params,
+	var items []CallHierarchyItem
+	if err := json.Unmarshal(resp, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse call hierarchy items: %w", err)
 	}
-	body, _ := json.Marshal(req)
-	fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return items, nil
 }
 
-func (c *Client) readResponse() (json.RawMessage, error) {
-	var length int
-	for {
-		line, err := c.stdout.ReadString('\n')
-		if err != nil {
-			return nil, err
-		}
-		line = strings.TrimSpace(line)
-		if line == "" {
-			break
-		}
-		if strings.HasPrefix(line, "Content-Length: ") {
-			length, _ = strconv.Atoi(strings.TrimPrefix(line, "Content-Length: "))
-		}
+// IncomingCalls は item を呼び出している関数の一覧を返します
+func (c *Client) IncomingCalls(ctx context.Context, item CallHierarchyItem) ([]CallHierarchyIncomingCall, error) {
+	resp, err := c.conn.call(ctx, "callHierarchy/incomingCalls", callHierarchyCallsParams{Item: item})
+	if err != nil {
+		return nil, err
 	}
 
-	body := make([]byte, length)
-	_, err := io.ReadFull(c.stdout, body)
+	var calls []CallHierarchyIncomingCall
+	if err := json.Unmarshal(resp, &calls); err != nil {
+		return nil, fmt.Errorf("failed to parse incoming calls: %w", err)
+	}
+	return calls, nil
+}
+
+// OutgoingCalls は item が呼び出している関数の一覧を返します
+func (c *Client) OutgoingCalls(ctx context.Context, item CallHierarchyItem) ([]CallHierarchyOutgoingCall, error) {
+	resp, err := c.conn.call(ctx, "callHierarchy/outgoingCalls", callHierarchyCallsParams{Item: item})
 	if err != nil {
 		return nil, err
 	}
 
-	var base struct {
-		Result json.RawMessage `json:"result"`
-		Error  interface{}     `json:"error"`
+	var calls []CallHierarchyOutgoingCall
+	if err := json.Unmarshal(resp, &calls); err != nil {
+		return nil, fmt.Errorf("failed to parse outgoing calls: %w", err)
 	}
-	if err := json.Unmarshal(body, &base); err != nil {
+	return calls, nil
+}
+
+// DocumentSymbols はファイル内のシンボル（型・関数・メソッド・フィールドなど）を階層構造で返します
+func (c *Client) DocumentSymbols(ctx context.Context, filePath string) ([]DocumentSymbol, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	params := DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: "file://" + absPath}}
+	resp, err := c.conn.call(ctx, "textDocument/documentSymbol", params)
+	if err != nil {
 		return nil, err
 	}
 
-	if base.Error != nil {
-		return nil, fmt.Errorf("lsp error: %v", base.Error)
+	var symbols []DocumentSymbol
+	if err := json.Unmarshal(resp, &symbols); err != nil {
+		return nil, fmt.Errorf("failed to parse document symbols: %w", err)
+	}
+	return symbols, nil
+}
+
+// WorkspaceSymbols はワークスペース全体からクエリに（あいまいに）一致するシンボルを検索します
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	resp, err := c.conn.call(ctx, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+	if err != nil {
+		return nil, err
 	}
 
-	// Notification(Resultがnil)ならスキップして次を読む
-	if base.Result == nil {
-		return c.readResponse()
+	var symbols []SymbolInformation
+	if err := json.Unmarshal(resp, &symbols); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace symbols: %w", err)
 	}
+	return symbols, nil
+}
 
-	return base.Result, nil
+// --- Internal Helpers ---
+
+func positionParams(filePath string, line, char int) (TextDocumentPositionParams, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return TextDocumentPositionParams{}, err
+	}
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file://" + absPath},
+		Position:     Position{Line: line, Character: char},
+	}, nil
+}
+
+// locationRequest は Location を返す位置指定リクエスト（definition 系）を送ります
+func (c *Client) locationRequest(ctx context.Context, method, filePath string, line, char int) ([]Location, error) {
+	params, err := positionParams(filePath, line, char)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.conn.call(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := parseLocations(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", method, err)
+	}
+	return locations, nil
+}
+
+// parseLocations は Location | Location[] | LocationLink[] | null の結果を Location の配列に正規化します
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	var items []json.RawMessage
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return nil, nil
+	case raw[0] == '[':
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+	default:
+		items = []json.RawMessage{raw}
+	}
+
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		var probe struct {
+			URI       string `json:"uri"`
+			TargetURI string `json:"targetUri"`
+		}
+		if err := json.Unmarshal(item, &probe); err != nil {
+			return nil, err
+		}
+
+		if probe.TargetURI != "" {
+			var link LocationLink
+			if err := json.Unmarshal(item, &link); err != nil {
+				return nil, err
+			}
+			locations = append(locations, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+
+		var loc Location
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
 }
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestParseLocations(t *testing.T) {
+	loc := func(uri string, line, start, end int) Location {
+		return Location{URI: uri, Range: Range{Start: Position{line, start}, End: Position{line, end}}}
+	}
+	tests := []struct {
+		name string
+		json string
+		want []Location
+	}{
+		{
+			name: "Location",
+			json: `{"uri": "file:///a.go", "range": {"start": {"line": 3, "character": 5}, "end": {"line": 3, "character": 8}}}`,
+			want: []Location{loc("file:///a.go", 3, 5, 8)},
+		},
+		{
+			name: "Location array",
+			json: `[{"uri": "file:///a.go", "range": {"start": {"line": 3, "character": 5}, "end": {"line": 3, "character": 8}}},
+				{"uri": "file:///b.go", "range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 1}}}]`,
+			want: []Location{loc("file:///a.go", 3, 5, 8), loc("file:///b.go", 0, 0, 1)},
+		},
+		{
+			// 宣言全体の targetRange ではなく名前を指す targetSelectionRange を使う
+			name: "LocationLink array",
+			json: `[{"targetUri": "file:///c.go",
+				"targetRange": {"start": {"line": 9, "character": 0}, "end": {"line": 12, "character": 1}},
+				"targetSelectionRange": {"start": {"line": 9, "character": 5}, "end": {"line": 9, "character": 9}}}]`,
+			want: []Location{loc("file:///c.go", 9, 5, 9)},
+		},
+		{name: "null", json: `null`},
+		{name: "empty array", json: `[]`, want: []Location{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLocations(json.RawMessage(tt.json))
+			if err != nil {
+				t.Fatalf("parseLocations: %v", err)
+			}
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("parseLocations = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseLocations(json.RawMessage(`[1]`)); err == nil {
+		t.Error("parseLocations accepted a number")
+	}
+}
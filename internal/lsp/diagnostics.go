@@ -0,0 +1,158 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// diagnosticsQuiet は最後の publishDiagnostics からこの時間通知が途絶えたら「落ち着いた」とみなす時間です
+	diagnosticsQuiet = 750 * time.Millisecond
+	// diagnosticsMaxWait は診断結果を待つ最大時間です
+	diagnosticsMaxWait = 15 * time.Second
+)
+
+// diagnosticStore は gopls から push される診断結果を URI ごとに保持します
+type diagnosticStore struct {
+	mu       sync.Mutex
+	byURI    map[string][]Diagnostic
+	received map[string]bool // 一度でも publishDiagnostics を受け取った URI
+	opened   map[string]bool // didOpen 済みの URI
+	lastPush time.Time
+	updated  chan struct{} // 通知のたびに close して差し替える
+}
+
+func newDiagnosticStore() *diagnosticStore {
+	return &diagnosticStore{
+		byURI:    make(map[string][]Diagnostic),
+		received: make(map[string]bool),
+		opened:   make(map[string]bool),
+		updated:  make(chan struct{}),
+	}
+}
+
+// publish は textDocument/publishDiagnostics 通知を記録します
+func (s *diagnosticStore) publish(raw json.RawMessage) {
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: malformed publishDiagnostics: %v\n", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byURI[params.URI] = params.Diagnostics
+	s.received[params.URI] = true
+	s.lastPush = time.Now()
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+// Diagnostics は指定ファイルを開き、gopls の診断（コンパイルエラー、vet、アナライザ）が
+// 落ち着くのを待ってから、ファイルの絶対パスごとの診断結果を返します
+func (c *Client) Diagnostics(ctx context.Context, filePaths []string) (map[string][]Diagnostic, error) {
+	uris := make(map[string]string, len(filePaths)) // uri -> 絶対パス
+	for _, p := range filePaths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		uri := "file://" + absPath
+		uris[uri] = absPath
+
+		if err := c.open(uri, absPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.diagnostics.wait(ctx, uris); err != nil {
+		return nil, err
+	}
+
+	c.diagnostics.mu.Lock()
+	defer c.diagnostics.mu.Unlock()
+	result := make(map[string][]Diagnostic, len(uris))
+	for uri, absPath := range uris {
+		result[absPath] = c.diagnostics.byURI[uri]
+	}
+	return result, nil
+}
+
+// open はファイルを didOpen で gopls に渡します。開済みのファイルは何もしません
+func (c *Client) open(uri, absPath string) error {
+	c.diagnostics.mu.Lock()
+	opened := c.diagnostics.opened[uri]
+	c.diagnostics.opened[uri] = true
+	if !opened {
+		// ワークスペース全体の診断で受信済みでも、didOpen 後の（アナライザを含む）結果を待ち直す
+		delete(c.diagnostics.received, uri)
+	}
+	c.diagnostics.mu.Unlock()
+	if opened {
+		return nil
+	}
+
+	text, err := os.ReadFile(absPath)
+	if err != nil {
+		c.diagnostics.mu.Lock()
+		delete(c.diagnostics.opened, uri)
+		c.diagnostics.mu.Unlock()
+		return err
+	}
+
+	return c.conn.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        uri,
+			LanguageID: "go",
+			Version:    1,
+			Text:       string(text),
+		},
+	})
+}
+
+// wait は全ての URI の診断を一度は受け取り、かつ通知が diagnosticsQuiet の間途絶えるまで待ちます。
+// diagnosticsMaxWait を超えたら、その時点までの結果で打ち切ります
+func (s *diagnosticStore) wait(ctx context.Context, uris map[string]string) error {
+	deadline := time.NewTimer(diagnosticsMaxWait)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		complete := true
+		for uri := range uris {
+			if !s.received[uri] {
+				complete = false
+				break
+			}
+		}
+		quietFor := time.Since(s.lastPush)
+		updated := s.updated
+		s.mu.Unlock()
+
+		if complete && quietFor >= diagnosticsQuiet {
+			return nil
+		}
+
+		wait := diagnosticsQuiet
+		if complete {
+			wait = diagnosticsQuiet - quietFor
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-deadline.C:
+			timer.Stop()
+			return nil
+		case <-updated:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
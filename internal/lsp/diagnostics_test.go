@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func publish(s *diagnosticStore, uri string, messages ...string) {
+	params := PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}}
+	for _, m := range messages {
+		params.Diagnostics = append(params.Diagnostics, Diagnostic{Severity: SeverityError, Message: m})
+	}
+	raw, _ := json.Marshal(params)
+	s.publish(raw)
+}
+
+// TestDiagnosticStoreWait は偽の時計（synctest）で、待ち時間が diagnosticsQuiet と diagnosticsMaxWait に従うことを確かめます
+func TestDiagnosticStoreWait(t *testing.T) {
+	uris := map[string]string{"file:///a.go": "/a.go", "file:///b.go": "/b.go"}
+
+	tests := []struct {
+		name   string
+		pushes func(s *diagnosticStore) // wait と並行に通知を送る
+		want   time.Duration            // wait が戻るまでの時間
+	}{
+		{
+			name: "quiet after the last push",
+			pushes: func(s *diagnosticStore) {
+				time.Sleep(100 * time.Millisecond)
+				publish(s, "file:///a.go")
+				time.Sleep(200 * time.Millisecond)
+				publish(s, "file:///b.go", "undefined: x")
+			},
+			want: 300*time.Millisecond + diagnosticsQuiet,
+		},
+		{
+			name: "pushes restart the quiet period",
+			pushes: func(s *diagnosticStore) {
+				publish(s, "file:///a.go")
+				publish(s, "file:///b.go")
+				for range 3 {
+					time.Sleep(diagnosticsQuiet / 2)
+					publish(s, "file:///b.go", "vet")
+				}
+			},
+			want: 3*diagnosticsQuiet/2 + diagnosticsQuiet,
+		},
+		{
+			name: "a file without diagnostics hits the cap",
+			pushes: func(s *diagnosticStore) {
+				publish(s, "file:///a.go")
+			},
+			want: diagnosticsMaxWait,
+		},
+		{
+			name: "never quiet hits the cap",
+			pushes: func(s *diagnosticStore) {
+				publish(s, "file:///b.go")
+				for range 2 * diagnosticsMaxWait / diagnosticsQuiet {
+					publish(s, "file:///a.go")
+					time.Sleep(diagnosticsQuiet / 2)
+				}
+			},
+			want: diagnosticsMaxWait,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				s := newDiagnosticStore()
+				go tt.pushes(s)
+
+				start := time.Now()
+				if err := s.wait(context.Background(), uris); err != nil {
+					t.Fatalf("wait: %v", err)
+				}
+				if got := time.Since(start); got != tt.want {
+					t.Errorf("wait returned after %v, want %v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestDiagnosticStoreWaitCanceled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := newDiagnosticStore()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := s.wait(ctx, map[string]string{"file:///a.go": "/a.go"}); err != context.DeadlineExceeded {
+			t.Errorf("wait error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestDiagnosticStorePublish(t *testing.T) {
+	s := newDiagnosticStore()
+	publish(s, "file:///a.go", "first")
+	publish(s, "file:///a.go", "second", "third")
+	publish(s, "file:///b.go")
+	s.publish(json.RawMessage(`{"uri": 1}`))
+
+	// 最新の通知が前の結果を置き換える
+	if got := fmt.Sprint(s.byURI["file:///a.go"]); got != fmt.Sprint([]Diagnostic{{Severity: SeverityError, Message: "second"}, {Severity: SeverityError, Message: "third"}}) {
+		t.Errorf("a.go diagnostics = %s", got)
+	}
+	if !s.received["file:///b.go"] || len(s.byURI["file:///b.go"]) != 0 {
+		t.Errorf("b.go: received %v, diagnostics %v; want received with none", s.received["file:///b.go"], s.byURI["file:///b.go"])
+	}
+}
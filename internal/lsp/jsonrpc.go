@@ -0,0 +1,318 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC 2.0 の MethodNotFound エラーコードです
+const codeMethodNotFound = -32601
+
+// ErrClosed は gopls との接続が切れた後に返されます
+var ErrClosed = errors.New("lsp: connection closed")
+
+// ResponseError は LSP サーバーが返したエラーです
+type ResponseError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// message は受信した JSON-RPC メッセージです。
+// id と method の有無で、レスポンス・通知・サーバーからのリクエストを判別します。
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ResponseError  `json:"error,omitempty"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// conn は JSON-RPC の送受信を多重化する接続です。
+// バックグラウンドの読み取りゴルーチンが、レスポンスを ID ごとの待ち手に振り分け、
+// 通知を購読者に配送し、サーバーからのリクエストに応答します。
+type conn struct {
+	w io.Writer
+	r *bufio.Reader
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	idSeq       int
+	pending     map[int]chan response
+	subscribers map[string]map[int]func(json.RawMessage)
+	subSeq      int
+	err         error // 読み取りループの終了理由
+
+	done chan struct{}
+}
+
+func newConn(w io.Writer, r io.Reader) *conn {
+	c := &conn{
+		w:           w,
+		r:           bufio.NewReader(r),
+		pending:     make(map[int]chan response),
+		subscribers: make(map[string]map[int]func(json.RawMessage)),
+		done:        make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// call はリクエストを送信し、対応するレスポンスを待ちます。
+// ctx がキャンセルされたら $/cancelRequest を送って ctx.Err() を返します。
+func (c *conn) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	ch := make(chan response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.idSeq++
+	id := c.idSeq
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	req := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	}
+	if err := c.write(req); err != nil {
+		c.forget(id)
+		return nil, fmt.Errorf("lsp: send %s: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp.result, resp.err
+	case <-ctx.Done():
+		c.forget(id)
+		_ = c.notify("$/cancelRequest", cancelParams{ID: id})
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, c.err
+	}
+}
+
+// notify は通知（レスポンスを待たないメッセージ）を送信します
+func (c *conn) notify(method string, params any) error {
+	return c.write(JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// subscribe は method の通知を受け取る関数を登録し、登録解除の関数を返します。
+// fn は読み取りゴルーチンから呼ばれるので、ブロックしてはいけません。
+func (c *conn) subscribe(method string, fn func(params json.RawMessage)) (unsubscribe func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subSeq++
+	id := c.subSeq
+	if c.subscribers[method] == nil {
+		c.subscribers[method] = make(map[int]func(json.RawMessage))
+	}
+	c.subscribers[method][id] = fn
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers[method], id)
+	}
+}
+
+func (c *conn) forget(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+func (c *conn) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (c *conn) readLoop() {
+	var err error
+	for {
+		var body []byte
+		body, err = c.readFrame()
+		if err != nil {
+			break
+		}
+		// 1通の本文が壊れていても次のメッセージの境界はわかるので、読み取りは続ける
+		var msg message
+		if jsonErr := json.Unmarshal(body, &msg); jsonErr != nil {
+			fmt.Fprintf(os.Stderr, "lsp: skipping malformed message: %v: %.200s\n", jsonErr, body)
+			continue
+		}
+		c.dispatch(&msg)
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+
+	c.mu.Lock()
+	c.err = err
+	pending := c.pending
+	c.pending = make(map[int]chan response)
+	c.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- response{err: err}
+	}
+	close(c.done)
+}
+
+// readFrame は Content-Length ヘッダーで区切られたメッセージを1通読み、本文を返します。
+// エラーはヘッダーの不正か I/O エラーで、以降のメッセージの境界がわからなくなったことを表します。
+func (c *conn) readFrame() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+			if length, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("lsp: invalid Content-Length %q", v)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("lsp: missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (c *conn) dispatch(msg *message) {
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		// サーバーからクライアントへのリクエスト。読み取りを止めないよう別ゴルーチンで応答する
+		go c.reply(msg)
+
+	case msg.Method != "":
+		c.mu.Lock()
+		subs := make([]func(json.RawMessage), 0, len(c.subscribers[msg.Method]))
+		for _, fn := range c.subscribers[msg.Method] {
+			subs = append(subs, fn)
+		}
+		c.mu.Unlock()
+		for _, fn := range subs {
+			fn(msg.Params)
+		}
+
+	case msg.ID != nil:
+		id, err := strconv.Atoi(string(msg.ID))
+		if err != nil {
+			// こちらは数値 ID しか使わないので、それ以外は無視する
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if !ok {
+			// キャンセル済みのリクエストへの遅れたレスポンス
+			return
+		}
+
+		if msg.Error != nil {
+			ch <- response{err: msg.Error}
+			return
+		}
+		result := msg.Result
+		if result == nil {
+			// "result": null は RawMessage に null のまま入る。nil になるのは result が欠けた応答なので、null として扱う
+			result = json.RawMessage("null")
+		}
+		ch <- response{result: result}
+	}
+}
+
+// reply はサーバーからのリクエストに応答します。
+// 必要最小限のメソッドだけを受け付け、それ以外は MethodNotFound を返します。
+func (c *conn) reply(msg *message) {
+	resp := jsonrpcResponse{JSONRPC: "2.0", ID: msg.ID}
+
+	switch msg.Method {
+	case "workspace/configuration":
+		// 設定は持たないので、要求された項目数だけ null を返す
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		result := make([]any, len(params.Items))
+		resp.Result, _ = json.Marshal(result)
+
+	case "window/workDoneProgress/create",
+		"client/registerCapability",
+		"client/unregisterCapability",
+		"window/showMessageRequest",
+		"workspace/diagnostic/refresh",
+		"workspace/codeLens/refresh",
+		"workspace/semanticTokens/refresh",
+		"workspace/inlayHint/refresh":
+		resp.Result = json.RawMessage("null")
+
+	case "workspace/applyEdit":
+		resp.Result = json.RawMessage(`{"applied":false,"failureReason":"read-only client"}`)
+
+	default:
+		resp.Error = &ResponseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+	}
+
+	if err := c.write(resp); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: reply to %s: %v\n", msg.Method, err)
+	}
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+type cancelParams struct {
+	ID int `json:"id"`
+}
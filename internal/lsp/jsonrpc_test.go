@@ -0,0 +1,281 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeServer は io.Pipe の向こう側で LSP サーバーとして振る舞います
+type fakeServer struct {
+	t    *testing.T
+	in   *conn     // クライアントが書いたメッセージの読み取りにフレームの解析だけ流用する
+	out  io.Writer // クライアントへ送るメッセージ
+	done func()
+}
+
+// newTestConn は fakeServer とつながった conn を返します
+func newTestConn(t *testing.T) (*conn, *fakeServer) {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	c := newConn(clientW, clientR)
+	s := &fakeServer{
+		t:   t,
+		in:  &conn{r: bufio.NewReader(serverR)},
+		out: serverW,
+		done: func() {
+			serverW.Close()
+			serverR.Close()
+		},
+	}
+	t.Cleanup(s.done)
+	return c, s
+}
+
+// request はクライアントから届いたメッセージです
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func (s *fakeServer) read() request {
+	s.t.Helper()
+	body, err := s.in.readFrame()
+	if err != nil {
+		s.t.Fatalf("server read: %v", err)
+	}
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.t.Fatalf("server read %s: %v", body, err)
+	}
+	return req
+}
+
+func (s *fakeServer) sendRaw(body string) {
+	s.t.Helper()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		s.t.Fatalf("server send: %v", err)
+	}
+}
+
+func (s *fakeServer) send(format string, args ...any) {
+	s.t.Helper()
+	s.sendRaw(fmt.Sprintf(format, args...))
+}
+
+type callResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// goCall は別ゴルーチンで call を呼び、結果を受け取るチャネルを返します
+func goCall(ctx context.Context, c *conn, method string) <-chan callResult {
+	ch := make(chan callResult, 1)
+	go func() {
+		result, err := c.call(ctx, method, nil)
+		ch <- callResult{result, err}
+	}()
+	return ch
+}
+
+func wait[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		panic("unreachable")
+	}
+}
+
+func TestConnOutOfOrderReplies(t *testing.T) {
+	c, s := newTestConn(t)
+	ctx := context.Background()
+
+	first := goCall(ctx, c, "first")
+	a := s.read()
+	second := goCall(ctx, c, "second")
+	b := s.read()
+
+	// 後のリクエストから先に応答する
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": {"from": %q}}`, b.ID, b.Method)
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": {"from": %q}}`, a.ID, a.Method)
+
+	for _, tt := range []struct {
+		ch   <-chan callResult
+		want string
+	}{{first, `{"from": "first"}`}, {second, `{"from": "second"}`}} {
+		got := wait(t, tt.ch)
+		if got.err != nil || string(got.result) != tt.want {
+			t.Errorf("call = %s, %v; want %s", got.result, got.err, tt.want)
+		}
+	}
+}
+
+func TestConnResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  string // %s に ID が入る
+		result string
+		err    string
+	}{
+		{name: "null result", reply: `{"jsonrpc": "2.0", "id": %s, "result": null}`, result: "null"},
+		{name: "missing result", reply: `{"jsonrpc": "2.0", "id": %s}`, result: "null"},
+		{name: "error", reply: `{"jsonrpc": "2.0", "id": %s, "error": {"code": -32602, "message": "bad params"}}`, err: "lsp error -32602: bad params"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := newTestConn(t)
+			ch := goCall(context.Background(), c, "m")
+			s.send(tt.reply, s.read().ID)
+
+			got := wait(t, ch)
+			if tt.err != "" {
+				var respErr *ResponseError
+				if !errors.As(got.err, &respErr) || got.err.Error() != tt.err {
+					t.Errorf("call error = %v, want %q", got.err, tt.err)
+				}
+				return
+			}
+			if got.err != nil || string(got.result) != tt.result {
+				t.Errorf("call = %s, %v; want %s", got.result, got.err, tt.result)
+			}
+		})
+	}
+}
+
+func TestConnAnswersServerRequests(t *testing.T) {
+	tests := []struct {
+		method string
+		params string
+		want   string // result、または error.message
+	}{
+		{"workspace/configuration", `{"items": [{"section": "gopls"}, {}]}`, "[null,null]"},
+		{"window/workDoneProgress/create", `{"token": "t"}`, "null"},
+		{"workspace/applyEdit", `{}`, `{"applied":false,"failureReason":"read-only client"}`},
+		{"workspace/unknown", `{}`, "method not supported: workspace/unknown"},
+	}
+	_, s := newTestConn(t)
+	for i, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			id := fmt.Sprintf(`"srv-%d"`, i)
+			s.send(`{"jsonrpc": "2.0", "id": %s, "method": %q, "params": %s}`, id, tt.method, tt.params)
+			resp := s.read()
+			if string(resp.ID) != id {
+				t.Errorf("reply id = %s, want %s", resp.ID, id)
+			}
+			got := string(resp.Result)
+			if resp.Error != nil {
+				got = resp.Error.Message
+				if resp.Error.Code != codeMethodNotFound {
+					t.Errorf("error code = %d, want %d", resp.Error.Code, codeMethodNotFound)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("reply = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnNotifiesSubscribers(t *testing.T) {
+	c, s := newTestConn(t)
+	got := make(chan string, 2)
+	unsubscribe := c.subscribe("textDocument/publishDiagnostics", func(params json.RawMessage) {
+		got <- string(params)
+	})
+
+	s.send(`{"jsonrpc": "2.0", "method": "window/logMessage", "params": {"message": "ignored"}}`)
+	s.send(`{"jsonrpc": "2.0", "method": "textDocument/publishDiagnostics", "params": {"uri": "file:///a.go"}}`)
+	if p := wait(t, got); p != `{"uri": "file:///a.go"}` {
+		t.Errorf("params = %s", p)
+	}
+
+	unsubscribe()
+	s.send(`{"jsonrpc": "2.0", "method": "textDocument/publishDiagnostics", "params": {"uri": "file:///b.go"}}`)
+	// 読み取りループは順に処理するので、続くレスポンスが届いた時点で通知は処理済み
+	ch := goCall(context.Background(), c, "sync")
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": null}`, s.read().ID)
+	wait(t, ch)
+	select {
+	case p := <-got:
+		t.Errorf("unsubscribed handler received %s", p)
+	default:
+	}
+}
+
+func TestConnCancel(t *testing.T) {
+	c, s := newTestConn(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := goCall(ctx, c, "slow")
+	req := s.read()
+
+	cancel()
+	notice := s.read()
+	if notice.Method != "$/cancelRequest" || notice.ID != nil || string(notice.Params) != fmt.Sprintf(`{"id":%s}`, req.ID) {
+		t.Errorf("cancel notification = %+v", notice)
+	}
+	if got := wait(t, ch); !errors.Is(got.err, context.Canceled) {
+		t.Fatalf("call error = %v, want context.Canceled", got.err)
+	}
+
+	// 遅れて届いたレスポンスは捨てられ、次の呼び出しに影響しない
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": "late"}`, req.ID)
+	next := goCall(context.Background(), c, "next")
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": "next"}`, s.read().ID)
+	if got := wait(t, next); got.err != nil || string(got.result) != `"next"` {
+		t.Errorf("call after cancel = %s, %v; want \"next\"", got.result, got.err)
+	}
+}
+
+func TestConnSkipsMalformedMessages(t *testing.T) {
+	c, s := newTestConn(t)
+	ch := goCall(context.Background(), c, "m")
+	req := s.read()
+
+	s.sendRaw(`{"jsonrpc": "2.0", "id": ` + string(req.ID) + `, "result": `)
+	s.sendRaw(`["not", "an", "object"]`)
+	s.send(`{"jsonrpc": "2.0", "id": %s, "result": true}`, req.ID)
+
+	if got := wait(t, ch); got.err != nil || string(got.result) != "true" {
+		t.Errorf("call = %s, %v; want true", got.result, got.err)
+	}
+}
+
+func TestConnClosed(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(s *fakeServer)
+		err   string
+	}{
+		{name: "EOF", close: func(s *fakeServer) { s.done() }, err: ErrClosed.Error()},
+		{name: "missing Content-Length", close: func(s *fakeServer) { io.WriteString(s.out, "Content-Type: x\r\n\r\n{}") }, err: "lsp: missing Content-Length header"},
+		{name: "invalid Content-Length", close: func(s *fakeServer) { io.WriteString(s.out, "Content-Length: ten\r\n\r\n") }, err: `lsp: invalid Content-Length "ten"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := newTestConn(t)
+			ch := goCall(context.Background(), c, "pending")
+			s.read()
+
+			tt.close(s)
+			if got := wait(t, ch); got.err == nil || got.err.Error() != tt.err {
+				t.Errorf("pending call error = %v, want %q", got.err, tt.err)
+			}
+			wait(t, c.done)
+			if _, err := c.call(context.Background(), "after", nil); err == nil || err.Error() != tt.err {
+				t.Errorf("call after close error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,7 +1,26 @@
 package lsp
 
+import "context"
+
 // CodeAnalyzer defines operations for code structural analysis.
+// Positions are 0-based, as in the LSP specification.
 type CodeAnalyzer interface {
-	References(filePath string, line, char int) ([]Location, error)
+	References(ctx context.Context, filePath string, line, char int) ([]Location, error)
+	Definition(ctx context.Context, filePath string, line, char int) ([]Location, error)
+	TypeDefinition(ctx context.Context, filePath string, line, char int) ([]Location, error)
+	Implementation(ctx context.Context, filePath string, line, char int) ([]Location, error)
+	Hover(ctx context.Context, filePath string, line, char int) (*Hover, error)
+	PrepareCallHierarchy(ctx context.Context, filePath string, line, char int) ([]CallHierarchyItem, error)
+	IncomingCalls(ctx context.Context, item CallHierarchyItem) ([]CallHierarchyIncomingCall, error)
+	OutgoingCalls(ctx context.Context, item CallHierarchyItem) ([]CallHierarchyOutgoingCall, error)
+	// Diagnostics opens the given files and returns the diagnostics gopls
+	// publishes for them, keyed by absolute path.
+	Diagnostics(ctx context.Context, filePaths []string) (map[string][]Diagnostic, error)
 	Close() error
 }
+
+// SymbolProvider defines symbol queries over a document or the whole workspace.
+type SymbolProvider interface {
+	DocumentSymbols(ctx context.Context, filePath string) ([]DocumentSymbol, error)
+	WorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error)
+}
@@ -1,5 +1,10 @@
 package lsp
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type JSONRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
 	ID      int         `json:"id,omitempty"`
@@ -8,8 +13,24 @@ type JSONRPCRequest struct {
 }
 
 type InitializeParams struct {
-	ProcessID int    `json:"processId"`
-	RootURI   string `json:"rootUri"`
+	ProcessID    int                `json:"processId"`
+	RootURI      string             `json:"rootUri"`
+	Capabilities ClientCapabilities `json:"capabilities"`
+}
+
+// ClientCapabilities declares the optional protocol features the client
+// understands. Only the ones that change the shape of results are listed.
+type ClientCapabilities struct {
+	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+}
+
+type TextDocumentClientCapabilities struct {
+	DocumentSymbol DocumentSymbolClientCapabilities `json:"documentSymbol"`
+}
+
+type DocumentSymbolClientCapabilities struct {
+	// 宣言しないと documentSymbol は階層のない SymbolInformation[] で返る
+	HierarchicalDocumentSymbolSupport bool `json:"hierarchicalDocumentSymbolSupport"`
 }
 
 type TextDocumentPositionParams struct {
@@ -44,3 +65,257 @@ type Range struct {
 	Start Position `json:"start"`
 	End   Position `json:"end"`
 }
+
+// LocationLink is the alternative result form of definition-like requests.
+type LocationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the result of textDocument/hover. Contents is always normalized
+// to MarkupContent; see UnmarshalJSON.
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// UnmarshalJSON accepts every form the specification allows for the hover
+// contents: MarkupContent, MarkedString and MarkedString[]. MarkedStrings
+// are converted to Markdown, with language-tagged ones as code blocks.
+func (h *Hover) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Contents json.RawMessage `json:"contents"`
+		Range    *Range          `json:"range"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var items []json.RawMessage
+	switch {
+	case len(raw.Contents) == 0 || string(raw.Contents) == "null":
+	case raw.Contents[0] == '[':
+		if err := json.Unmarshal(raw.Contents, &items); err != nil {
+			return err
+		}
+	default:
+		var markup struct {
+			Kind  *string `json:"kind"`
+			Value string  `json:"value"`
+		}
+		// 文字列の MarkedString は構造体に入らないので、エラーは MarkedString として扱う
+		if err := json.Unmarshal(raw.Contents, &markup); err == nil && markup.Kind != nil {
+			*h = Hover{Contents: MarkupContent{Kind: *markup.Kind, Value: markup.Value}, Range: raw.Range}
+			return nil
+		}
+		items = []json.RawMessage{raw.Contents}
+	}
+
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		s, err := markedString(item)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(s) != "" {
+			parts = append(parts, s)
+		}
+	}
+	*h = Hover{Contents: MarkupContent{Kind: "markdown", Value: strings.Join(parts, "\n\n")}, Range: raw.Range}
+	return nil
+}
+
+// markedString renders a MarkedString (string | { language, value }) as Markdown.
+func markedString(raw json.RawMessage) (string, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	var code struct {
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &code); err != nil {
+		return "", err
+	}
+	if code.Language == "" {
+		return code.Value, nil
+	}
+	return "```" + code.Language + "\n" + code.Value + "\n```", nil
+}
+
+// SymbolKind is the LSP symbol kind enumeration.
+type SymbolKind int
+
+const (
+	SymbolFile          SymbolKind = 1
+	SymbolModule        SymbolKind = 2
+	SymbolNamespace     SymbolKind = 3
+	SymbolPackage       SymbolKind = 4
+	SymbolClass         SymbolKind = 5
+	SymbolMethod        SymbolKind = 6
+	SymbolProperty      SymbolKind = 7
+	SymbolField         SymbolKind = 8
+	SymbolConstructor   SymbolKind = 9
+	SymbolEnum          SymbolKind = 10
+	SymbolInterface     SymbolKind = 11
+	SymbolFunction      SymbolKind = 12
+	SymbolVariable      SymbolKind = 13
+	SymbolConstant      SymbolKind = 14
+	SymbolString        SymbolKind = 15
+	SymbolNumber        SymbolKind = 16
+	SymbolBoolean       SymbolKind = 17
+	SymbolArray         SymbolKind = 18
+	SymbolObject        SymbolKind = 19
+	SymbolKey           SymbolKind = 20
+	SymbolNull          SymbolKind = 21
+	SymbolEnumMember    SymbolKind = 22
+	SymbolStruct        SymbolKind = 23
+	SymbolEvent         SymbolKind = 24
+	SymbolOperator      SymbolKind = 25
+	SymbolTypeParameter SymbolKind = 26
+)
+
+var symbolKindNames = [...]string{
+	SymbolFile:          "file",
+	SymbolModule:        "module",
+	SymbolNamespace:     "namespace",
+	SymbolPackage:       "package",
+	SymbolClass:         "class",
+	SymbolMethod:        "method",
+	SymbolProperty:      "property",
+	SymbolField:         "field",
+	SymbolConstructor:   "constructor",
+	SymbolEnum:          "enum",
+	SymbolInterface:     "interface",
+	SymbolFunction:      "function",
+	SymbolVariable:      "variable",
+	SymbolConstant:      "constant",
+	SymbolString:        "string",
+	SymbolNumber:        "number",
+	SymbolBoolean:       "boolean",
+	SymbolArray:         "array",
+	SymbolObject:        "object",
+	SymbolKey:           "key",
+	SymbolNull:          "null",
+	SymbolEnumMember:    "enum member",
+	SymbolStruct:        "struct",
+	SymbolEvent:         "event",
+	SymbolOperator:      "operator",
+	SymbolTypeParameter: "type parameter",
+}
+
+func (k SymbolKind) String() string {
+	if k > 0 && int(k) < len(symbolKindNames) {
+		return symbolKindNames[k]
+	}
+	return "unknown"
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbol is one node of the hierarchical textDocument/documentSymbol result.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SymbolInformation is a workspace/symbol result. For gopls, ContainerName is
+// the package path.
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+type CallHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           SymbolKind      `json:"kind"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            string          `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type callHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DiagnosticSeverity is the LSP diagnostic severity (1 = error ... 4 = hint).
+type DiagnosticSeverity int
+
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "unknown"
+	}
+}
+
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     json.RawMessage    `json:"code,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
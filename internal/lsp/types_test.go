@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHoverUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want MarkupContent
+	}{
+		{
+			name: "MarkupContent",
+			json: `{"contents": {"kind": "markdown", "value": "func F()"}}`,
+			want: MarkupContent{Kind: "markdown", Value: "func F()"},
+		},
+		{
+			name: "plaintext MarkupContent",
+			json: `{"contents": {"kind": "plaintext", "value": ""}}`,
+			want: MarkupContent{Kind: "plaintext"},
+		},
+		{
+			name: "string",
+			json: `{"contents": "doc comment"}`,
+			want: MarkupContent{Kind: "markdown", Value: "doc comment"},
+		},
+		{
+			name: "language and value",
+			json: `{"contents": {"language": "go", "value": "func F()"}}`,
+			want: MarkupContent{Kind: "markdown", Value: "```go\nfunc F()\n```"},
+		},
+		{
+			name: "MarkedString array",
+			json: `{"contents": [{"language": "go", "value": "type T int"}, "", "T is a number."]}`,
+			want: MarkupContent{Kind: "markdown", Value: "```go\ntype T int\n```\n\nT is a number."},
+		},
+		{
+			name: "null",
+			json: `{"contents": null}`,
+			want: MarkupContent{Kind: "markdown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Hover
+			if err := json.Unmarshal([]byte(tt.json), &h); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if h.Contents != tt.want {
+				t.Errorf("Contents = %+v, want %+v", h.Contents, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoverUnmarshalJSONRange(t *testing.T) {
+	var h Hover
+	data := `{"contents": "x", "range": {"start": {"line": 1, "character": 2}, "end": {"line": 1, "character": 5}}}`
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if want := (Range{Start: Position{1, 2}, End: Position{1, 5}}); h.Range == nil || *h.Range != want {
+		t.Errorf("Range = %v, want %v", h.Range, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"contents": [42]}`), &h); err == nil {
+		t.Error("Unmarshal accepted a number as MarkedString")
+	}
+}
@@ -0,0 +1,189 @@
+package persona
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fragment is the text of an included file.
+type fragment struct {
+	path string
+	text string
+}
+
+// Load reads a persona definition from a YAML file.
+//
+// A persona file may extend another persona file and include text
+// fragments. Paths in extends and include are relative to the file that
+// declares them. Fields the file sets override those of the extended
+// persona, and the included fragments are appended to the system prompt
+// after the fragments of the extended persona. After Load, SystemPrompt
+// holds the composed prompt and Include lists every included file.
+func Load(path string) (*Persona, error) {
+	p, err := load(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Tools.validate(); err != nil {
+		return nil, fmt.Errorf("persona file %s: %w", path, err)
+	}
+
+	if len(p.fragments) == 0 {
+		return p, nil
+	}
+	parts := []string{strings.TrimSpace(p.SystemPrompt)}
+	p.Include = nil
+	for _, f := range p.fragments {
+		parts = append(parts, strings.TrimSpace(f.text))
+		p.Include = append(p.Include, f.path)
+	}
+	p.SystemPrompt = strings.Join(slices.DeleteFunc(parts, func(s string) bool { return s == "" }), "\n\n") + "\n"
+	p.fragments = nil
+	return p, nil
+}
+
+// load reads path and the personas it extends. chain holds the files that
+// extend path, in order, to detect cycles.
+func load(path string, chain []string) (*Persona, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("persona file %s: %w", path, err)
+	}
+	for i, prev := range chain {
+		if a, _ := filepath.Abs(prev); a == abs {
+			cycle := append(slices.Clone(chain[i:]), path)
+			return nil, fmt.Errorf("persona file %s: extends cycle: %s", chain[len(chain)-1], strings.Join(cycle, " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("persona file %s: failed to read extended persona: %w", chain[len(chain)-1], err)
+		}
+		return nil, fmt.Errorf("failed to read persona file %s: %w", path, err)
+	}
+
+	var p Persona
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse persona file %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(p.Aspects))
+	for _, a := range p.Aspects {
+		if a.ID == "" {
+			return nil, fmt.Errorf("persona file %s: aspect %q has no id", path, a.Name)
+		}
+		if seen[a.ID] {
+			return nil, fmt.Errorf("persona file %s: duplicate aspect id %q", path, a.ID)
+		}
+		seen[a.ID] = true
+	}
+
+	for i, h := range p.ToolGuide {
+		if strings.TrimSpace(h.Text) == "" {
+			return nil, fmt.Errorf("persona file %s: tool_guide[%d] has no text", path, i)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	for _, inc := range p.Include {
+		if inc == "" {
+			return nil, fmt.Errorf("persona file %s: empty include path", path)
+		}
+		file := resolve(dir, inc)
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("persona file %s: failed to read include %s: %w", path, inc, err)
+		}
+		p.fragments = append(p.fragments, fragment{path: file, text: string(text)})
+	}
+
+	if p.Extends == "" {
+		return &p, nil
+	}
+	base, err := load(resolve(dir, p.Extends), append(chain, path))
+	if err != nil {
+		return nil, err
+	}
+	p.inherit(base)
+	return &p, nil
+}
+
+// resolve interprets name relative to dir unless it is absolute.
+func resolve(dir, name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(dir, name)
+}
+
+// inherit fills in p from the persona it extends. Fields p sets win;
+// aspects with the same ID replace the base's in place and new ones follow
+// them. An allow list replaces the base's (an explicit empty list allows
+// every tool), while tool guides, deny lists and fragments accumulate.
+func (p *Persona) inherit(base *Persona) {
+	p.Name = cmp.Or(p.Name, base.Name)
+	p.Description = cmp.Or(p.Description, base.Description)
+	p.SystemPrompt = cmp.Or(p.SystemPrompt, base.SystemPrompt)
+
+	aspects := slices.Clone(base.Aspects)
+	for _, a := range p.Aspects {
+		i := slices.IndexFunc(aspects, func(b Aspect) bool { return b.ID == a.ID })
+		if i < 0 {
+			aspects = append(aspects, a)
+			continue
+		}
+		aspects[i] = a
+	}
+	p.Aspects = aspects
+
+	guide := slices.Clone(base.ToolGuide)
+	for _, h := range p.ToolGuide {
+		if !slices.ContainsFunc(guide, func(g ToolHint) bool { return g.Text == h.Text }) {
+			guide = append(guide, h)
+		}
+	}
+	p.ToolGuide = guide
+
+	if p.Tools.Allow == nil {
+		p.Tools.Allow = base.Tools.Allow
+	}
+	p.Tools.Deny = append(slices.Clone(base.Tools.Deny), p.Tools.Deny...)
+	l, bl := &p.Tools.Limits, base.Tools.Limits
+	l.MaxReadBytes = cmp.Or(l.MaxReadBytes, bl.MaxReadBytes)
+	l.MaxReferences = cmp.Or(l.MaxReferences, bl.MaxReferences)
+	l.MaxSearchResults = cmp.Or(l.MaxSearchResults, bl.MaxSearchResults)
+	l.MaxCallDepth = cmp.Or(l.MaxCallDepth, bl.MaxCallDepth)
+	l.MaxDiagnostics = cmp.Or(l.MaxDiagnostics, bl.MaxDiagnostics)
+
+	m, bm := &p.Model, base.Model
+	// モデル名はプロバイダと組で意味を持つので、どちらかを指定したら両方とも上書きする
+	if m.Provider == "" && m.Name == "" {
+		m.Provider, m.Name = bm.Provider, bm.Name
+	}
+	m.Temperature = cmp.Or(m.Temperature, bm.Temperature)
+	m.TopP = cmp.Or(m.TopP, bm.TopP)
+	m.MaxOutputTokens = cmp.Or(m.MaxOutputTokens, bm.MaxOutputTokens)
+	m.ThinkingBudget = cmp.Or(m.ThinkingBudget, bm.ThinkingBudget)
+	if len(bm.Safety) > 0 {
+		safety := maps.Clone(bm.Safety)
+		maps.Copy(safety, m.Safety)
+		m.Safety = safety
+	}
+
+	fragments := slices.Clone(base.fragments)
+	for _, f := range p.fragments {
+		if !slices.ContainsFunc(fragments, func(g fragment) bool { return g.path == f.path }) {
+			fragments = append(fragments, f)
+		}
+	}
+	p.fragments = fragments
+}
@@ -0,0 +1,244 @@
+package persona
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files (relative path -> content) under a temporary
+// directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadExtends(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/base.yaml": `
+include: [rules.md, common.md]
+name: Base
+description: base reviewer
+system_prompt: base prompt
+aspects:
+  - {id: errors, name: Errors, description: base errors}
+  - {id: naming, name: Naming, description: base naming}
+tool_guide:
+  - {tools: [read-file], text: read files}
+tools:
+  allow: [find-*, read-file]
+  deny: [hover]
+  limits: {max_read_bytes: 1000, max_references: 10}
+model:
+  provider: gemini
+  name: gemini-2.5-flash
+  max_output_tokens: 2048
+  safety: {harassment: BLOCK_NONE, hate: BLOCK_NONE}
+`,
+		"shared/rules.md":  "## 行動規範\nrules\n",
+		"shared/common.md": "common\n",
+		"child.yaml": `
+extends: shared/base.yaml
+include: [shared/common.md, extra.md]
+name: Child
+aspects:
+  - {id: naming, name: Naming, description: child naming}
+  - {id: layout, name: Layout, description: child layout}
+tool_guide:
+  - {tools: [read-file], text: read files}
+  - {tools: [import-graph], text: use the graph}
+tools:
+  deny: [search-code]
+  limits: {max_references: 20}
+model:
+  name: gpt-4o
+  provider: openai
+  safety: {hate: BLOCK_LOW_AND_ABOVE}
+`,
+		"extra.md": "extra\n",
+	})
+
+	p, err := Load(filepath.Join(dir, "child.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if p.Name != "Child" || p.Description != "base reviewer" {
+		t.Errorf("Name, Description = %q, %q; want Child, base reviewer", p.Name, p.Description)
+	}
+	// 断片は継承元のものが先に並び、同じファイルは1度だけ含まれる
+	if want := "base prompt\n\n## 行動規範\nrules\n\ncommon\n\nextra\n"; p.SystemPrompt != want {
+		t.Errorf("SystemPrompt = %q, want %q", p.SystemPrompt, want)
+	}
+	wantInclude := []string{
+		filepath.Join(dir, "shared/rules.md"),
+		filepath.Join(dir, "shared/common.md"),
+		filepath.Join(dir, "extra.md"),
+	}
+	if !slices.Equal(p.Include, wantInclude) {
+		t.Errorf("Include = %v, want %v", p.Include, wantInclude)
+	}
+
+	var aspects []string
+	for _, a := range p.Aspects {
+		aspects = append(aspects, a.ID+": "+a.Description)
+	}
+	if want := []string{"errors: base errors", "naming: child naming", "layout: child layout"}; !slices.Equal(aspects, want) {
+		t.Errorf("Aspects = %v, want %v", aspects, want)
+	}
+
+	var hints []string
+	for _, h := range p.ToolGuide {
+		hints = append(hints, h.Text)
+	}
+	if want := []string{"read files", "use the graph"}; !slices.Equal(hints, want) {
+		t.Errorf("ToolGuide = %v, want %v", hints, want)
+	}
+
+	if want := []string{"find-*", "read-file"}; !slices.Equal(p.Tools.Allow, want) {
+		t.Errorf("Allow = %v, want %v", p.Tools.Allow, want)
+	}
+	if want := []string{"hover", "search-code"}; !slices.Equal(p.Tools.Deny, want) {
+		t.Errorf("Deny = %v, want %v", p.Tools.Deny, want)
+	}
+	if l := p.Tools.Limits; l.MaxReadBytes != 1000 || l.MaxReferences != 20 {
+		t.Errorf("Limits = %+v, want max_read_bytes 1000, max_references 20", l)
+	}
+
+	m := p.Model
+	if m.Provider != "openai" || m.Name != "gpt-4o" || m.MaxOutputTokens != 2048 {
+		t.Errorf("Model = %+v", m)
+	}
+	if m.Safety["harassment"] != "BLOCK_NONE" || m.Safety["hate"] != "BLOCK_LOW_AND_ABOVE" {
+		t.Errorf("Safety = %v", m.Safety)
+	}
+}
+
+func TestLoadExtendsModelPair(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":  "system_prompt: p\nmodel: {provider: gemini, name: gemini-2.5-pro}\n",
+		"child.yaml": "extends: base.yaml\nmodel: {temperature: 0.2}\n",
+	})
+	p, err := Load(filepath.Join(dir, "child.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p.Model.Provider != "gemini" || p.Model.Name != "gemini-2.5-pro" || p.Model.Temperature == nil || *p.Model.Temperature != 0.2 {
+		t.Errorf("Model = %+v", p.Model)
+	}
+}
+
+func TestLoadExplicitEmptyAllow(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":  "tools: {allow: [read-file]}\n",
+		"child.yaml": "extends: base.yaml\ntools: {allow: []}\n",
+	})
+	p, err := Load(filepath.Join(dir, "child.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !p.Tools.Allows("hover") {
+		t.Errorf("Allow = %v: an explicit empty allow list should allow every tool", p.Tools.Allow)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "extends itself",
+			files: map[string]string{"a.yaml": "extends: a.yaml\n"},
+			want:  "extends cycle: ",
+		},
+		{
+			name: "extends cycle",
+			files: map[string]string{
+				"a.yaml":     "extends: sub/b.yaml\n",
+				"sub/b.yaml": "extends: ../a.yaml\n",
+			},
+			want: "extends cycle: ",
+		},
+		{
+			name:  "missing extended persona",
+			files: map[string]string{"a.yaml": "extends: none.yaml\n"},
+			want:  "failed to read extended persona",
+		},
+		{
+			name:  "missing include",
+			files: map[string]string{"a.yaml": "include: [none.md]\n"},
+			want:  "failed to read include none.md",
+		},
+		{
+			name:  "empty include",
+			files: map[string]string{"a.yaml": "include: ['']\n"},
+			want:  "empty include path",
+		},
+		{
+			name:  "aspect without id",
+			files: map[string]string{"a.yaml": "aspects: [{name: Errors}]\n"},
+			want:  `aspect "Errors" has no id`,
+		},
+		{
+			name:  "duplicate aspect",
+			files: map[string]string{"a.yaml": "aspects: [{id: e}, {id: e}]\n"},
+			want:  `duplicate aspect id "e"`,
+		},
+		{
+			name:  "tool hint without text",
+			files: map[string]string{"a.yaml": "tool_guide: [{tools: [hover]}]\n"},
+			want:  "tool_guide[0] has no text",
+		},
+		{
+			name:  "bad allow pattern",
+			files: map[string]string{"a.yaml": "tools: {allow: ['[']}\n"},
+			want:  `invalid tool pattern "["`,
+		},
+		{
+			name:  "negative limit",
+			files: map[string]string{"a.yaml": "tools: {limits: {max_references: -1}}\n"},
+			want:  "max_references must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			_, err := Load(filepath.Join(dir, "a.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// 同梱のペルソナは継承と断片の解決まで含めて読み込める
+func TestLoadBundledPersonas(t *testing.T) {
+	files, err := filepath.Glob("../../configs/personas/*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no bundled personas: %v", err)
+	}
+	for _, file := range files {
+		p, err := Load(file)
+		if err != nil {
+			t.Errorf("Load(%s): %v", file, err)
+			continue
+		}
+		if p.Name == "" || len(p.Aspects) == 0 || !strings.Contains(p.SystemPrompt, "## 行動規範") {
+			t.Errorf("%s: name %q, %d aspects, prompt %q", file, p.Name, len(p.Aspects), p.SystemPrompt)
+		}
+	}
+}
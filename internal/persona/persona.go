@@ -2,29 +2,160 @@ package persona
 
 import (
 	"fmt"
-	"os"
-
-	"gopkg.in/yaml.v3"
+	"path"
+	"slices"
+	"strings"
 )
 
 // Persona defines a bot's identity and review perspective.
 type Persona struct {
-	Name         string `yaml:"name"`
-	Description  string `yaml:"description"`
-	SystemPrompt string `yaml:"system_prompt"`
+	Extends      string     `yaml:"extends"` // 継承元のペルソナファイル（このファイルからの相対パス）
+	Include      []string   `yaml:"include"` // system_prompt の後に続けるテキストの断片ファイル
+	Name         string     `yaml:"name"`
+	Description  string     `yaml:"description"`
+	SystemPrompt string     `yaml:"system_prompt"`
+	Aspects      []Aspect   `yaml:"aspects"`
+	ToolGuide    []ToolHint `yaml:"tool_guide"`
+	Tools        Tools      `yaml:"tools"`
+	Model        Model      `yaml:"model"`
+
+	fragments []fragment // 読み込んだ include。Load が SystemPrompt に連結する
+}
+
+// Model selects the LLM and its generation parameters for the persona.
+// Unset fields fall back to the server's defaults, and the arguments of a
+// review request override them.
+type Model struct {
+	Provider        string            `yaml:"provider"` // Name が前提とするプロバイダ。サーバーのプロバイダと異なれば Name は使わない
+	Name            string            `yaml:"name"`
+	Temperature     *float64          `yaml:"temperature"`
+	TopP            *float64          `yaml:"top_p"`
+	MaxOutputTokens int               `yaml:"max_output_tokens"`
+	ThinkingBudget  *int              `yaml:"thinking_budget"`
+	Safety          map[string]string `yaml:"safety"` // 有害カテゴリ → ブロックのしきい値
+}
+
+// ModelFor returns the model name to use with the given provider, or "" to
+// use the server's model.
+func (m Model) ModelFor(provider string) string {
+	if m.Provider != "" && m.Provider != provider {
+		return ""
+	}
+	return m.Name
+}
+
+// Tools selects and tunes the tools the persona's agent may use. Allow and
+// Deny hold tool names or path.Match patterns (e.g. "find-*").
+type Tools struct {
+	Allow  []string   `yaml:"allow"` // 空なら全ツールを許可
+	Deny   []string   `yaml:"deny"`  // Allow より優先する
+	Limits ToolLimits `yaml:"limits"`
+}
+
+// ToolLimits caps the output of individual tools. Zero means the tool's
+// default.
+type ToolLimits struct {
+	MaxReadBytes     int `yaml:"max_read_bytes"`     // read-file の1回の本文の上限
+	MaxReferences    int `yaml:"max_references"`     // find-references の結果件数
+	MaxSearchResults int `yaml:"max_search_results"` // search-code で指定できる件数の上限
+	MaxCallDepth     int `yaml:"max_call_depth"`     // call-hierarchy で辿れる深さ
+	MaxDiagnostics   int `yaml:"max_diagnostics"`    // get-diagnostics の結果件数
+}
+
+// Allows reports whether the persona may use the named tool.
+func (t Tools) Allows(name string) bool {
+	if matchAny(t.Deny, name) {
+		return false
+	}
+	return len(t.Allow) == 0 || matchAny(t.Allow, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
 }
 
-// Load reads a persona definition from a YAML file.
-func Load(path string) (*Persona, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read persona file %s: %w", path, err)
+// validate checks the patterns and limits.
+func (t Tools) validate() error {
+	for _, p := range append(slices.Clone(t.Allow), t.Deny...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", p, err)
+		}
+	}
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"max_read_bytes", t.Limits.MaxReadBytes},
+		{"max_references", t.Limits.MaxReferences},
+		{"max_search_results", t.Limits.MaxSearchResults},
+		{"max_call_depth", t.Limits.MaxCallDepth},
+		{"max_diagnostics", t.Limits.MaxDiagnostics},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return fmt.Errorf("tool limit %s must not be negative: %d", l.name, l.value)
+		}
 	}
+	return nil
+}
+
+// ToolHint is a piece of tool guidance for the system prompt. It is shown
+// only when every tool it names is available to the agent, so a persona that
+// denies a tool, or a review that does not offer it, is never told to use it.
+type ToolHint struct {
+	Tools []string `yaml:"tools"` // ヒントが前提とするツール。空なら常に表示する
+	Text  string   `yaml:"text"`
+}
 
-	var p Persona
-	if err := yaml.Unmarshal(data, &p); err != nil {
-		return nil, fmt.Errorf("failed to parse persona file %s: %w", path, err)
+// Aspect is one review perspective of a persona. Its ID is used as the
+// finding category and as the basis of SARIF rule IDs.
+type Aspect struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// AspectIDs returns the IDs of the persona's review aspects in declaration order.
+func (p *Persona) AspectIDs() []string {
+	ids := make([]string, 0, len(p.Aspects))
+	for _, a := range p.Aspects {
+		ids = append(ids, a.ID)
 	}
+	return ids
+}
 
-	return &p, nil
+// Prompt returns the full system prompt: the system prompt, the tool guide
+// entries whose tools are all available as a "ツールの使い方" section, and
+// the review aspects as a "レビュー観点" section. A nil available treats
+// every tool as available.
+func (p *Persona) Prompt(available func(tool string) bool) string {
+	var hints []string
+	for _, h := range p.ToolGuide {
+		if available == nil || !slices.ContainsFunc(h.Tools, func(t string) bool { return !available(t) }) {
+			hints = append(hints, strings.TrimSpace(h.Text)+"\n")
+		}
+	}
+	if len(hints) == 0 && len(p.Aspects) == 0 {
+		return p.SystemPrompt
+	}
+
+	// 各セクションは改行で終わるので、空行1つで区切られる
+	sections := []string{strings.TrimRight(p.SystemPrompt, "\n") + "\n"}
+	if len(hints) > 0 {
+		sections = append(sections, "## ツールの使い方\n"+strings.Join(hints, ""))
+	}
+	if len(p.Aspects) > 0 {
+		var b strings.Builder
+		b.WriteString("## レビュー観点\n")
+		for _, a := range p.Aspects {
+			fmt.Fprintf(&b, "- %s（%s）: %s\n", a.Name, a.ID, a.Description)
+		}
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n")
 }
@@ -0,0 +1,108 @@
+package persona
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestToolsAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		tools Tools
+		tool  string
+		want  bool
+	}{
+		{name: "empty allows everything", tools: Tools{}, tool: "hover", want: true},
+		{name: "allowed by name", tools: Tools{Allow: []string{"read-file"}}, tool: "read-file", want: true},
+		{name: "not in the allow list", tools: Tools{Allow: []string{"read-file"}}, tool: "hover", want: false},
+		{name: "allowed by pattern", tools: Tools{Allow: []string{"find-*"}}, tool: "find-references", want: true},
+		{name: "denied by name", tools: Tools{Deny: []string{"search-code"}}, tool: "search-code", want: false},
+		{name: "deny wins over allow", tools: Tools{Allow: []string{"find-*"}, Deny: []string{"find-references"}}, tool: "find-references", want: false},
+		{name: "deny pattern", tools: Tools{Deny: []string{"*-graph"}}, tool: "import-graph", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tools.Allows(tt.tool); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	p := &Persona{
+		SystemPrompt: "You review Go code.\n",
+		ToolGuide: []ToolHint{
+			{Text: "Always cite evidence."},
+			{Tools: []string{"find-symbol", "find-references"}, Text: "Use find-symbol, then find-references.\n"},
+			{Tools: []string{"import-graph"}, Text: "Use import-graph."},
+		},
+		Aspects: []Aspect{{ID: "errors", Name: "Errors", Description: "Are errors wrapped?"}},
+	}
+	available := func(tools ...string) func(string) bool {
+		return func(tool string) bool { return slices.Contains(tools, tool) }
+	}
+
+	tests := []struct {
+		name      string
+		persona   *Persona
+		available func(string) bool
+		want      string
+	}{
+		{
+			name:      "only hints whose tools are all available",
+			persona:   p,
+			available: available("find-symbol", "find-references", "read-file"),
+			want: `You review Go code.
+
+## ツールの使い方
+Always cite evidence.
+Use find-symbol, then find-references.
+
+## レビュー観点
+- Errors（errors）: Are errors wrapped?
+`,
+		},
+		{
+			name:      "a missing tool drops its hint",
+			persona:   p,
+			available: available("find-symbol", "import-graph"),
+			want: `You review Go code.
+
+## ツールの使い方
+Always cite evidence.
+Use import-graph.
+
+## レビュー観点
+- Errors（errors）: Are errors wrapped?
+`,
+		},
+		{
+			name:    "nil treats every tool as available",
+			persona: p,
+			want: `You review Go code.
+
+## ツールの使い方
+Always cite evidence.
+Use find-symbol, then find-references.
+Use import-graph.
+
+## レビュー観点
+- Errors（errors）: Are errors wrapped?
+`,
+		},
+		{
+			name:      "no sections",
+			persona:   &Persona{SystemPrompt: "plain"},
+			available: available(),
+			want:      "plain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.persona.Prompt(tt.available); got != tt.want {
+				t.Errorf("Prompt =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+)
+
+// APIChange is an exported identifier that was added, removed or changed
+// between the two revisions of an APIReport.
+type APIChange struct {
+	Package  string `json:"package"`
+	Name     string `json:"name,omitempty"` // パッケージ全体の追加・削除では空
+	Kind     string `json:"kind"`
+	Change   string `json:"change"` // added / removed / changed
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// Ident returns the qualified name of the changed identifier.
+func (c *APIChange) Ident() string {
+	if c.Name == "" {
+		return c.Package
+	}
+	return c.Package + "." + c.Name
+}
+
+// APIReport compares the exported API of the reviewed change's base and head.
+// Unlike findings, it is computed mechanically and not by the reviewer.
+type APIReport struct {
+	Base       string      `json:"base"`
+	Head       string      `json:"head"`
+	Changes    []APIChange `json:"changes"`
+	Incomplete []string    `json:"incomplete,omitempty"` // 型検査に失敗し、比較結果が不完全かもしれないパッケージ
+}
+
+// markdown renders the API comparison, incompatible changes first.
+func (a *APIReport) markdown(b *strings.Builder) {
+	fmt.Fprintf(b, "\n## 公開 API の変更（%s → %s）\n\n", a.Base, a.Head)
+	if len(a.Changes) == 0 {
+		b.WriteString("公開 API に変更はありません。\n")
+	}
+
+	var breaking, compatible []APIChange
+	for _, c := range a.Changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		} else {
+			compatible = append(compatible, c)
+		}
+	}
+	if len(breaking) > 0 {
+		fmt.Fprintf(b, "### 互換性のない変更（%d件）\n\n", len(breaking))
+		for _, c := range breaking {
+			writeAPIChange(b, &c)
+		}
+		b.WriteString("\n")
+	}
+	if len(compatible) > 0 {
+		fmt.Fprintf(b, "### 互換性のある変更（%d件）\n\n", len(compatible))
+		for _, c := range compatible {
+			writeAPIChange(b, &c)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(a.Incomplete) > 0 {
+		fmt.Fprintf(b, "型検査に失敗したため結果が不完全な可能性があるパッケージ: %s\n", strings.Join(a.Incomplete, ", "))
+	}
+}
+
+func writeAPIChange(b *strings.Builder, c *APIChange) {
+	switch {
+	case c.Old != "" && c.New != "":
+		fmt.Fprintf(b, "- %s `%s`: `%s` → `%s`\n", c.Change, c.Ident(), c.Old, c.New)
+	case c.Old != "":
+		fmt.Fprintf(b, "- %s `%s`: `%s`\n", c.Change, c.Ident(), c.Old)
+	default:
+		fmt.Fprintf(b, "- %s `%s`: `%s`\n", c.Change, c.Ident(), c.New)
+	}
+}
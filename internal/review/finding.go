@@ -0,0 +1,181 @@
+// Package review defines the structured result of a code review.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks how urgently a finding should be addressed.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityMajor    Severity = "major"
+	SeverityMinor    Severity = "minor"
+	SeverityInfo     Severity = "info"
+)
+
+// Severities lists all valid severities from most to least severe.
+var Severities = []Severity{SeverityCritical, SeverityMajor, SeverityMinor, SeverityInfo}
+
+func (s Severity) rank() int {
+	for i, v := range Severities {
+		if v == s {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// Valid reports whether s is one of Severities.
+func (s Severity) Valid() bool {
+	return s.rank() < len(Severities)
+}
+
+// Evidence is a tool call the reviewer made to back up a finding.
+type Evidence struct {
+	Tool string `json:"tool"`
+	Args string `json:"args,omitempty"` // JSON エンコードされた引数
+}
+
+func (e Evidence) String() string {
+	return fmt.Sprintf("%s(%s)", e.Tool, e.Args)
+}
+
+// Finding is a single review comment anchored to a source location.
+type Finding struct {
+	File         string     `json:"file,omitempty"` // プロジェクトルートからの相対パス。プロジェクト全体への指摘なら空
+	StartLine    int        `json:"start_line,omitempty"`
+	EndLine      int        `json:"end_line,omitempty"`
+	Severity     Severity   `json:"severity"`
+	Category     string     `json:"category"`
+	Persona      string     `json:"persona,omitempty"`
+	Personas     []string   `json:"personas,omitempty"` // パネルレビューでこの指摘をしたペルソナ（統合された指摘では複数）
+	Message      string     `json:"message"`
+	SuggestedFix string     `json:"suggested_fix,omitempty"`
+	Evidence     []Evidence `json:"evidence,omitempty"`
+}
+
+// Location formats the finding's position as file:start-end.
+func (f *Finding) Location() string {
+	switch {
+	case f.File == "":
+		return "(project)"
+	case f.StartLine == 0:
+		return f.File
+	case f.EndLine <= f.StartLine:
+		return fmt.Sprintf("%s:%d", f.File, f.StartLine)
+	default:
+		return fmt.Sprintf("%s:%d-%d", f.File, f.StartLine, f.EndLine)
+	}
+}
+
+// Validate checks required fields and normalizes the line range.
+func (f *Finding) Validate() error {
+	if strings.TrimSpace(f.Message) == "" {
+		return errors.New("message is empty")
+	}
+	if !f.Severity.Valid() {
+		return fmt.Errorf("invalid severity %q", f.Severity)
+	}
+	if f.StartLine < 0 || f.EndLine < 0 {
+		return fmt.Errorf("negative line range %d-%d", f.StartLine, f.EndLine)
+	}
+	if f.File == "" && f.StartLine > 0 {
+		return fmt.Errorf("line %d given without file", f.StartLine)
+	}
+	if f.EndLine == 0 {
+		f.EndLine = f.StartLine
+	}
+	if f.EndLine < f.StartLine {
+		return fmt.Errorf("end_line %d is before start_line %d", f.EndLine, f.StartLine)
+	}
+	return nil
+}
+
+// Report is the structured outcome of one review run.
+type Report struct {
+	Persona  string    `json:"persona,omitempty"`
+	Panel    []string  `json:"panel,omitempty"` // パネルレビューに参加したペルソナ
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+
+	// ContextNotes holds findings outside the changed lines of a diff-scoped
+	// review. They are kept for background but are not review comments.
+	ContextNotes []Finding `json:"context_notes,omitempty"`
+
+	// API is the exported API comparison of the change, when requested.
+	API *APIReport `json:"api,omitempty"`
+}
+
+// Validate validates every finding and sorts them by severity and location.
+func (r *Report) Validate() error {
+	var errs []error
+	for i := range r.Findings {
+		if err := r.Findings[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("findings[%d]: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity.rank() < b.Severity.rank()
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.StartLine < b.StartLine
+	})
+	return nil
+}
+
+// SetPersona attributes the report and all its findings to persona.
+func (r *Report) SetPersona(persona string) {
+	r.Persona = persona
+	for i := range r.Findings {
+		r.Findings[i].Persona = persona
+	}
+	for i := range r.ContextNotes {
+		r.ContextNotes[i].Persona = persona
+	}
+}
+
+// ApplyScope keeps only findings inside a changed hunk. Out-of-scope
+// findings are moved to ContextNotes, or discarded when drop is true.
+func (r *Report) ApplyScope(scope LineScope, drop bool) {
+	kept := r.Findings[:0]
+	for _, f := range r.Findings {
+		switch {
+		case f.InScope(scope):
+			kept = append(kept, f)
+		case !drop:
+			r.ContextNotes = append(r.ContextNotes, f)
+		}
+	}
+	r.Findings = kept
+}
+
+// LineScope tells which source lines are part of the change under review,
+// i.e. fall inside a changed hunk.
+// workspace.Diff satisfies it.
+type LineScope interface {
+	Touches(path string, start, end int) bool
+}
+
+// InScope reports whether the finding lies inside a changed hunk.
+// Project-level findings without a file are never in scope.
+func (f *Finding) InScope(scope LineScope) bool {
+	if f.File == "" {
+		return false
+	}
+	return scope.Touches(f.File, f.StartLine, f.EndLine)
+}
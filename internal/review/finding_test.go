@@ -0,0 +1,94 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding Finding
+		want    string // 空ならエラーにならない
+		endLine int    // 正規化後の EndLine
+	}{
+		{name: "single line", finding: Finding{File: "a.go", StartLine: 3, Severity: SeverityMajor, Message: "m"}, endLine: 3},
+		{name: "range", finding: Finding{File: "a.go", StartLine: 3, EndLine: 5, Severity: SeverityInfo, Message: "m"}, endLine: 5},
+		{name: "project level", finding: Finding{Severity: SeverityMinor, Message: "m"}},
+		{name: "empty message", finding: Finding{Severity: SeverityMajor, Message: " \n"}, want: "message is empty"},
+		{name: "invalid severity", finding: Finding{Severity: "blocker", Message: "m"}, want: `invalid severity "blocker"`},
+		{name: "missing severity", finding: Finding{Message: "m"}, want: `invalid severity ""`},
+		{name: "end before start", finding: Finding{File: "a.go", StartLine: 5, EndLine: 3, Severity: SeverityMajor, Message: "m"}, want: "end_line 3 is before start_line 5"},
+		{name: "negative line", finding: Finding{File: "a.go", StartLine: -1, Severity: SeverityMajor, Message: "m"}, want: "negative line range -1-0"},
+		{name: "line without file", finding: Finding{StartLine: 2, Severity: SeverityMajor, Message: "m"}, want: "line 2 given without file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.finding
+			err := f.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("Validate: %v", err)
+			case tt.want != "" && (err == nil || err.Error() != tt.want):
+				t.Fatalf("Validate error = %v, want %q", err, tt.want)
+			case tt.want == "" && f.EndLine != tt.endLine:
+				t.Errorf("EndLine = %d, want %d", f.EndLine, tt.endLine)
+			}
+		})
+	}
+}
+
+func TestReportValidate(t *testing.T) {
+	r := &Report{Findings: []Finding{
+		{File: "b.go", StartLine: 9, Severity: SeverityMinor, Message: "b9"},
+		{File: "b.go", StartLine: 2, Severity: SeverityMinor, Message: "b2"},
+		{Severity: SeverityCritical, Message: "project"},
+		{File: "a.go", StartLine: 7, Severity: SeverityMinor, Message: "a7"},
+		{File: "c.go", StartLine: 1, Severity: SeverityInfo, Message: "c1"},
+	}}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	var got []string
+	for _, f := range r.Findings {
+		got = append(got, f.Message)
+	}
+	if want := "project a7 b2 b9 c1"; strings.Join(got, " ") != want {
+		t.Errorf("order = %v, want %s", got, want)
+	}
+
+	empty := &Report{}
+	if err := empty.Validate(); err != nil || empty.Findings == nil {
+		t.Errorf("empty report: err %v, findings %#v; want no error and an empty slice", err, empty.Findings)
+	}
+
+	bad := &Report{Findings: []Finding{
+		{Severity: SeverityMajor, Message: "ok"},
+		{Severity: "high", Message: "m"},
+		{Severity: SeverityMajor},
+	}}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded for invalid findings")
+	}
+	if want := "findings[1]: invalid severity \"high\"\nfindings[2]: message is empty"; err.Error() != want {
+		t.Errorf("Validate error =\n%v\nwant\n%s", err, want)
+	}
+}
+
+func TestFindingLocation(t *testing.T) {
+	tests := []struct {
+		finding Finding
+		want    string
+	}{
+		{Finding{}, "(project)"},
+		{Finding{File: "a.go"}, "a.go"},
+		{Finding{File: "a.go", StartLine: 3, EndLine: 3}, "a.go:3"},
+		{Finding{File: "a.go", StartLine: 3, EndLine: 6}, "a.go:3-6"},
+	}
+	for _, tt := range tests {
+		if got := tt.finding.Location(); got != tt.want {
+			t.Errorf("Location(%+v) = %q, want %q", tt.finding, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the report for human readers.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	switch {
+	case len(r.Panel) > 0:
+		fmt.Fprintf(&b, "# レビュー結果（パネル: %s）\n\n", strings.Join(r.Panel, ", "))
+	case r.Persona != "":
+		fmt.Fprintf(&b, "# レビュー結果（%s）\n\n", r.Persona)
+	default:
+		b.WriteString("# レビュー結果\n\n")
+	}
+
+	if s := strings.TrimSpace(r.Summary); s != "" {
+		b.WriteString(s)
+		b.WriteString("\n\n")
+	}
+
+	if len(r.Findings) == 0 {
+		b.WriteString("指摘事項はありません。\n")
+	} else {
+		fmt.Fprintf(&b, "## 指摘事項（%d件）\n", len(r.Findings))
+		for i, f := range r.Findings {
+			writeFinding(&b, i+1, &f)
+		}
+	}
+
+	if len(r.ContextNotes) > 0 {
+		fmt.Fprintf(&b, "\n## 変更範囲外の参考情報（%d件）\n", len(r.ContextNotes))
+		for i, f := range r.ContextNotes {
+			writeFinding(&b, i+1, &f)
+		}
+	}
+
+	if r.API != nil {
+		r.API.markdown(&b)
+	}
+	return b.String()
+}
+
+func writeFinding(b *strings.Builder, n int, f *Finding) {
+	fmt.Fprintf(b, "\n### %d. [%s] %s — `%s`\n\n", n, f.Severity, f.Category, f.Location())
+	if len(f.Personas) > 0 {
+		fmt.Fprintf(b, "**指摘したペルソナ:** %s\n\n", strings.Join(f.Personas, ", "))
+	}
+	b.WriteString(strings.TrimSpace(f.Message))
+	b.WriteString("\n")
+
+	if fix := strings.TrimSpace(f.SuggestedFix); fix != "" {
+		b.WriteString("\n**修正案:**\n\n")
+		b.WriteString(fix)
+		b.WriteString("\n")
+	}
+
+	if len(f.Evidence) > 0 {
+		b.WriteString("\n<details><summary>根拠となったツール呼び出し</summary>\n\n")
+		for _, e := range f.Evidence {
+			fmt.Fprintf(b, "- `%s`\n", e)
+		}
+		b.WriteString("\n</details>\n")
+	}
+}
@@ -0,0 +1,119 @@
+package review
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		report *Report
+		want   string
+	}{
+		{
+			name:   "no findings",
+			report: &Report{Summary: "  looks good \n"},
+			want:   "# レビュー結果\n\nlooks good\n\n指摘事項はありません。\n",
+		},
+		{
+			name: "findings, context notes and API",
+			report: &Report{
+				Persona: "Go Expert",
+				Summary: "Two problems.",
+				Findings: []Finding{
+					{
+						File: "a.go", StartLine: 3, EndLine: 5, Severity: SeverityMajor, Category: "errors",
+						Message:      "The error is dropped.\n",
+						SuggestedFix: "return err",
+						Evidence:     []Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}},
+					},
+					{Severity: SeverityMinor, Category: "layout", Message: "Split the package."},
+				},
+				ContextNotes: []Finding{
+					{File: "b.go", StartLine: 10, EndLine: 10, Severity: SeverityInfo, Category: "naming", Message: "Old name."},
+				},
+				API: &APIReport{
+					Base: "main",
+					Head: "HEAD",
+					Changes: []APIChange{
+						{Package: "example.com/p", Name: "F", Change: "changed", Old: "func F(int)", New: "func F(int64)", Breaking: true},
+						{Package: "example.com/p", Name: "G", Change: "added", New: "func G()"},
+						{Package: "example.com/q", Change: "removed", Old: "package (2 exported identifiers)", Breaking: true},
+					},
+					Incomplete: []string{"example.com/r"},
+				},
+			},
+			want: "# レビュー結果（Go Expert）\n" +
+				"\n" +
+				"Two problems.\n" +
+				"\n" +
+				"## 指摘事項（2件）\n" +
+				"\n" +
+				"### 1. [major] errors — `a.go:3-5`\n" +
+				"\n" +
+				"The error is dropped.\n" +
+				"\n" +
+				"**修正案:**\n" +
+				"\n" +
+				"return err\n" +
+				"\n" +
+				"<details><summary>根拠となったツール呼び出し</summary>\n" +
+				"\n" +
+				"- `read-file({\"file_path\":\"a.go\"})`\n" +
+				"\n" +
+				"</details>\n" +
+				"\n" +
+				"### 2. [minor] layout — `(project)`\n" +
+				"\n" +
+				"Split the package.\n" +
+				"\n" +
+				"## 変更範囲外の参考情報（1件）\n" +
+				"\n" +
+				"### 1. [info] naming — `b.go:10`\n" +
+				"\n" +
+				"Old name.\n" +
+				"\n" +
+				"## 公開 API の変更（main → HEAD）\n" +
+				"\n" +
+				"### 互換性のない変更（2件）\n" +
+				"\n" +
+				"- changed `example.com/p.F`: `func F(int)` → `func F(int64)`\n" +
+				"- removed `example.com/q`: `package (2 exported identifiers)`\n" +
+				"\n" +
+				"### 互換性のある変更（1件）\n" +
+				"\n" +
+				"- added `example.com/p.G`: `func G()`\n" +
+				"\n" +
+				"型検査に失敗したため結果が不完全な可能性があるパッケージ: example.com/r\n",
+		},
+		{
+			name: "panel",
+			report: &Report{
+				Persona: PanelPersona,
+				Panel:   []string{"security", "go-expert"},
+				Findings: []Finding{
+					{File: "a.go", StartLine: 1, EndLine: 1, Severity: SeverityCritical, Category: "injection", Personas: []string{"security", "go-expert"}, Message: "SQL is built by hand."},
+				},
+				API: &APIReport{Base: "main", Head: "HEAD"},
+			},
+			want: "# レビュー結果（パネル: security, go-expert）\n" +
+				"\n" +
+				"## 指摘事項（1件）\n" +
+				"\n" +
+				"### 1. [critical] injection — `a.go:1`\n" +
+				"\n" +
+				"**指摘したペルソナ:** security, go-expert\n" +
+				"\n" +
+				"SQL is built by hand.\n" +
+				"\n" +
+				"## 公開 API の変更（main → HEAD）\n" +
+				"\n" +
+				"公開 API に変更はありません。\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.Markdown(); got != tt.want {
+				t.Errorf("Markdown =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PanelPersona is the persona of a panel review report. Findings that no
+// panel member raised (e.g. layering rule violations) are attributed to it.
+const PanelPersona = "panel"
+
+// Merge combines the reports of a panel review without deduplicating them.
+// Every finding keeps the persona that raised it, and the summaries are
+// listed per persona. Each report's Persona must be set.
+func Merge(reports ...*Report) *Report {
+	merged := &Report{Persona: PanelPersona, Findings: []Finding{}}
+	var summary strings.Builder
+	for _, r := range reports {
+		merged.Panel = append(merged.Panel, r.Persona)
+		fmt.Fprintf(&summary, "### %s\n\n%s\n\n", r.Persona, strings.TrimSpace(r.Summary))
+		for _, f := range r.Findings {
+			merged.Findings = append(merged.Findings, f.attributed(r.Persona))
+		}
+		for _, f := range r.ContextNotes {
+			merged.ContextNotes = append(merged.ContextNotes, f.attributed(r.Persona))
+		}
+	}
+	merged.Summary = strings.TrimSpace(summary.String())
+	return merged
+}
+
+// attributed returns a copy of f credited to persona, unless it already
+// names the personas that raised it.
+func (f Finding) attributed(persona string) Finding {
+	if f.Persona == "" {
+		f.Persona = persona
+	}
+	if len(f.Personas) == 0 {
+		f.Personas = []string{f.Persona}
+	}
+	return f
+}
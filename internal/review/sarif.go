@@ -0,0 +1,190 @@
+package review
+
+import "strings"
+
+// SARIF 2.1.0 の最小限のオブジェクトモデルです。
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SrcRootBaseID is the uriBaseId findings are relative to.
+	SrcRootBaseID = "%SRCROOT%"
+)
+
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name,omitempty"`
+	ShortDescription *SARIFMessage `json:"shortDescription,omitempty"`
+	FullDescription  *SARIFMessage `json:"fullDescription,omitempty"`
+}
+
+type SARIFMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type SARIFResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    SARIFMessage    `json:"message"`
+	Locations  []SARIFLocation `json:"locations,omitempty"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+type SARIFArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// Rule describes a review aspect that findings are categorized by.
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// RuleID derives a stable SARIF rule ID from a persona name and a finding
+// category, e.g. ("Go Expert", "error-handling") -> "go-expert/error-handling".
+func RuleID(persona, category string) string {
+	slug := strings.Join(strings.Fields(strings.ToLower(persona)), "-")
+	if category == "" {
+		category = "general"
+	}
+	if slug == "" {
+		return category
+	}
+	return slug + "/" + category
+}
+
+// SARIF converts reports into a single-run SARIF log. Rules for categories
+// not listed in rules are synthesized from the findings themselves.
+// Context notes are left out: they point at unchanged code and would
+// otherwise surface as annotations on lines the change did not touch.
+func SARIF(toolVersion string, rules []Rule, reports ...*Report) *SARIFLog {
+	driver := SARIFDriver{Name: "llm-reviewer", Version: toolVersion, Rules: []SARIFRule{}}
+	index := make(map[string]int)
+
+	addRule := func(r Rule) int {
+		if i, ok := index[r.ID]; ok {
+			return i
+		}
+		rule := SARIFRule{ID: r.ID, Name: r.Name}
+		if r.Name != "" {
+			rule.ShortDescription = &SARIFMessage{Text: r.Name}
+		}
+		if r.Description != "" {
+			rule.FullDescription = &SARIFMessage{Text: r.Description}
+		}
+		index[r.ID] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, rule)
+		return index[r.ID]
+	}
+	for _, r := range rules {
+		addRule(r)
+	}
+
+	results := []SARIFResult{}
+	for _, report := range reports {
+		for _, f := range report.Findings {
+			persona := f.Persona
+			if persona == "" {
+				persona = report.Persona
+			}
+			id := RuleID(persona, f.Category)
+			results = append(results, sarifResult(&f, id, addRule(Rule{ID: id})))
+		}
+	}
+
+	return &SARIFLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SARIFRun{{Tool: SARIFTool{Driver: driver}, Results: results}},
+	}
+}
+
+func sarifResult(f *Finding, ruleID string, ruleIndex int) SARIFResult {
+	text := f.Message
+	if f.SuggestedFix != "" {
+		text += "\n\n修正案: " + f.SuggestedFix
+	}
+
+	res := SARIFResult{
+		RuleID:    ruleID,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(f.Severity),
+		Message:   SARIFMessage{Text: text},
+		Properties: map[string]any{
+			"severity": f.Severity,
+		},
+	}
+	if f.Persona != "" {
+		res.Properties["persona"] = f.Persona
+	}
+	if len(f.Evidence) > 0 {
+		evidence := make([]string, 0, len(f.Evidence))
+		for _, e := range f.Evidence {
+			evidence = append(evidence, e.String())
+		}
+		res.Properties["evidence"] = evidence
+	}
+
+	if f.File != "" {
+		loc := SARIFLocation{PhysicalLocation: SARIFPhysicalLocation{
+			ArtifactLocation: SARIFArtifactLocation{URI: f.File, URIBaseID: SrcRootBaseID},
+		}}
+		if f.StartLine > 0 {
+			loc.PhysicalLocation.Region = &SARIFRegion{StartLine: f.StartLine, EndLine: max(f.EndLine, f.StartLine)}
+		}
+		res.Locations = []SARIFLocation{loc}
+	}
+	return res
+}
+
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityCritical, SeverityMajor:
+		return "error"
+	case SeverityMinor:
+		return "warning"
+	default:
+		return "note"
+	}
+}
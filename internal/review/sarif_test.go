@@ -0,0 +1,183 @@
+package review
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRuleID(t *testing.T) {
+	tests := []struct {
+		persona, category, want string
+	}{
+		{"Go Expert", "error-handling", "go-expert/error-handling"},
+		{"  Security   Reviewer ", "injection", "security-reviewer/injection"},
+		{"go-expert", "", "go-expert/general"},
+		{"", "layering", "layering"},
+	}
+	for _, tt := range tests {
+		if got := RuleID(tt.persona, tt.category); got != tt.want {
+			t.Errorf("RuleID(%q, %q) = %q, want %q", tt.persona, tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	report := &Report{
+		Persona: "Go Expert",
+		Findings: []Finding{
+			{File: "a.go", StartLine: 3, EndLine: 5, Severity: SeverityCritical, Category: "errors", Message: "dropped error", SuggestedFix: "return err",
+				Evidence: []Evidence{{Tool: "read-file", Args: `{"file_path":"a.go"}`}}},
+			{File: "b.go", StartLine: 7, EndLine: 7, Severity: SeverityMajor, Category: "errors", Persona: "Security", Message: "unchecked input"},
+			{File: "c.go", Severity: SeverityMinor, Category: "naming", Message: "file level"},
+			{Severity: SeverityInfo, Category: "layout", Message: "project level"},
+		},
+		ContextNotes: []Finding{
+			{File: "d.go", StartLine: 1, EndLine: 1, Severity: SeverityMajor, Category: "errors", Message: "unchanged code"},
+		},
+	}
+	rules := []Rule{{ID: "go-expert/errors", Name: "Errors", Description: "Are errors handled?"}}
+
+	log := SARIF("1.2.3", rules, report)
+	got, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "version": "2.1.0",
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "llm-reviewer",
+          "version": "1.2.3",
+          "rules": [
+            {
+              "id": "go-expert/errors",
+              "name": "Errors",
+              "shortDescription": {
+                "text": "Errors"
+              },
+              "fullDescription": {
+                "text": "Are errors handled?"
+              }
+            },
+            {
+              "id": "security/errors"
+            },
+            {
+              "id": "go-expert/naming"
+            },
+            {
+              "id": "go-expert/layout"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "go-expert/errors",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "dropped error\n\n修正案: return err"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "a.go",
+                  "uriBaseId": "%SRCROOT%"
+                },
+                "region": {
+                  "startLine": 3,
+                  "endLine": 5
+                }
+              }
+            }
+          ],
+          "properties": {
+            "evidence": [
+              "read-file({\"file_path\":\"a.go\"})"
+            ],
+            "severity": "critical"
+          }
+        },
+        {
+          "ruleId": "security/errors",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "unchecked input"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "b.go",
+                  "uriBaseId": "%SRCROOT%"
+                },
+                "region": {
+                  "startLine": 7,
+                  "endLine": 7
+                }
+              }
+            }
+          ],
+          "properties": {
+            "persona": "Security",
+            "severity": "major"
+          }
+        },
+        {
+          "ruleId": "go-expert/naming",
+          "ruleIndex": 2,
+          "level": "warning",
+          "message": {
+            "text": "file level"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "c.go",
+                  "uriBaseId": "%SRCROOT%"
+                }
+              }
+            }
+          ],
+          "properties": {
+            "severity": "minor"
+          }
+        },
+        {
+          "ruleId": "go-expert/layout",
+          "ruleIndex": 3,
+          "level": "note",
+          "message": {
+            "text": "project level"
+          },
+          "properties": {
+            "severity": "info"
+          }
+        }
+      ]
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Errorf("SARIF =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSARIFWithoutReports(t *testing.T) {
+	got, err := json.Marshal(SARIF("", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GitHub code scanning は results と rules の null を受け付けない
+	want := `{"version":"2.1.0","$schema":"https://json.schemastore.org/sarif-2.1.0.json","runs":[{"tool":{"driver":{"name":"llm-reviewer","rules":[]}},"results":[]}]}`
+	if string(got) != want {
+		t.Errorf("SARIF =\n%s\nwant\n%s", got, want)
+	}
+}
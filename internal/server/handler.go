@@ -2,29 +2,71 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/0muji4/llm-reviewer/internal/agent"
+	"github.com/0muji4/llm-reviewer/internal/depgraph"
 	"github.com/0muji4/llm-reviewer/internal/lsp"
 	"github.com/0muji4/llm-reviewer/internal/persona"
+	"github.com/0muji4/llm-reviewer/internal/review"
 	"github.com/0muji4/llm-reviewer/internal/symbol"
 	"github.com/0muji4/llm-reviewer/internal/workspace"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// review ツールの出力形式です。
+const (
+	formatMarkdown = "markdown"
+	formatSARIF    = "sarif"
+)
+
+// review ツールのレビュー範囲と、範囲外の指摘の扱いです。
+const (
+	scopeFull = "full"
+	scopeDiff = "diff"
+
+	outOfScopeDemote = "demote"
+	outOfScopeDrop   = "drop"
+)
+
+// find-symbol ツールが使うシンボル解決の実装です。
+const (
+	resolverAST = "ast"
+	resolverLSP = "lsp"
+)
+
+// review ツールで公開 API の比較をするかどうかと、その対象です。
+const (
+	apiDiffOff    = "off"
+	apiDiffPublic = "public"
+	apiDiffAll    = "all"
+)
+
 // ReviewHandler は MCP リクエストを Agent のユースケースに変換する Adapter です。
 type ReviewHandler struct {
-	apiKey     string
+	provider   agent.ProviderConfig
 	personaDir string
+	indexDir   string // シンボル索引の保存先。空ならメモリ上にのみ保持する
+
+	mu      sync.Mutex
+	indexes map[string]*symbol.Index // プロジェクトの絶対パスで引く。レビュー間で共有する
 }
 
 // NewReviewHandler は ReviewHandler を生成します。
-func NewReviewHandler(apiKey, personaDir string) *ReviewHandler {
+func NewReviewHandler(provider agent.ProviderConfig, personaDir, indexDir string) *ReviewHandler {
 	return &ReviewHandler{
-		apiKey:     apiKey,
+		provider:   provider,
 		personaDir: personaDir,
+		indexDir:   indexDir,
+		indexes:    make(map[string]*symbol.Index),
 	}
 }
 
@@ -43,17 +85,62 @@ func (h *ReviewHandler) Handle(ctx context.Context, req mcp.CallToolRequest) (*m
 	if err != nil {
 		return mcp.NewToolResultError("query is required"), nil
 	}
-	personaName := req.GetString("persona", "architect")
+	format := req.GetString("format", formatMarkdown)
+	if format != formatMarkdown && format != formatSARIF {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q", format)), nil
+	}
+	scope := req.GetString("scope", scopeFull)
+	if scope != scopeFull && scope != scopeDiff {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown scope %q", scope)), nil
+	}
+	outOfScope := req.GetString("out_of_scope", outOfScopeDemote)
+	if outOfScope != outOfScopeDemote && outOfScope != outOfScopeDrop {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown out_of_scope %q", outOfScope)), nil
+	}
+	resolverName := req.GetString("symbol_resolver", resolverAST)
+	if resolverName != resolverLSP && resolverName != resolverAST {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown symbol_resolver %q", resolverName)), nil
+	}
+	apiDiffMode := req.GetString("api_diff", apiDiffOff)
+	if apiDiffMode != apiDiffOff && apiDiffMode != apiDiffPublic && apiDiffMode != apiDiffAll {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown api_diff %q", apiDiffMode)), nil
+	}
+	diffOpts, err := diffOptionsFrom(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid diff options: %v", err)), nil
+	}
+	if diffOpts.HasRevision() || len(diffOpts.Paths) > 0 {
+		query += fmt.Sprintf("\n\nレビュー対象の差分は `%s` です。「get-diff」を引数なしで呼ぶとこの差分を取得できます。", diffOpts)
+	}
 
-	// 1. Persona の読み込み
-	personaPath := fmt.Sprintf("%s/%s.yaml", h.personaDir, personaName)
-	p, err := persona.Load(personaPath)
+	// 1. Persona の読み込み（panel が指定されたら複数）
+	personas, err := h.loadPersonas(req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to load persona %q: %v", personaName, err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// レイヤールール（指定がなければプロジェクトの既定ファイルがあるときだけ使う）
+	rulesPath := req.GetString("layering_rules", "")
+	explicitRules := rulesPath != ""
+	if !explicitRules {
+		rulesPath = depgraph.DefaultRulesFile
+	}
+	if !filepath.IsAbs(rulesPath) {
+		rulesPath = filepath.Join(projectPath, rulesPath)
+	}
+	var rules *depgraph.Rules
+	if _, statErr := os.Stat(rulesPath); explicitRules || statErr == nil {
+		rules, err = depgraph.LoadRules(rulesPath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if rel, err := filepath.Rel(projectPath, rulesPath); err == nil && !strings.HasPrefix(rel, "..") {
+			rules.Path = rel
+		}
 	}
 
 	// 2. Infrastructure 層の生成
-	lspClient, err := lsp.NewClient(projectPath)
+	lspClient, err := lsp.NewClient(ctx, projectPath)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start LSP: %v", err)), nil
 	}
@@ -61,18 +148,301 @@ func (h *ReviewHandler) Handle(ctx context.Context, req mcp.CallToolRequest) (*m
 
 	fsReader := workspace.NewFSReader(projectPath)
 	gitDiff := workspace.NewGitDiff(projectPath)
-	astResolver := symbol.NewASTResolver(projectPath)
+	var resolver symbol.Resolver = symbol.NewASTResolver(projectPath, h.symbolIndex(projectPath))
+	if resolverName == resolverLSP {
+		resolver = symbol.NewLSPResolver(projectPath, lspClient)
+	}
+
+	graph := depgraph.NewLoader(projectPath)
+	apiDiffer := symbol.NewAPIDiffer(projectPath, gitDiff)
+
+	opts := []agent.Option{
+		agent.WithDiffOptions(diffOpts),
+		agent.WithRevisionReader(workspace.NewGitReader(projectPath, "HEAD")),
+		agent.WithSearcher(workspace.NewFSSearcher(projectPath)),
+		agent.WithTypeChecker(symbol.NewPackageResolver(projectPath)),
+		agent.WithImportGraph(graph, rules),
+	}
+
+	// 公開 API の比較対象はレビュー対象の差分の両端。求められていないときは解決できなくてもツールを外すだけにする
+	apiBase, apiHead, err := gitDiff.Revisions(diffOpts)
+	switch {
+	case err == nil:
+		opts = append(opts, agent.WithAPIDiff(apiDiffer, apiBase, apiHead))
+	case apiDiffMode != apiDiffOff:
+		return mcp.NewToolResultError(fmt.Sprintf("failed to resolve revisions for api_diff: %v", err)), nil
+	}
+
+	// 差分スコープでは変更箇所を事前に確定させ、Agent への指示と結果の絞り込みに使う
+	var changes workspace.Diff
+	if scope == scopeDiff {
+		patch, err := gitDiff.Diff(diffOpts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get diff: %v", err)), nil
+		}
+		changes, err = workspace.ParseDiff(patch)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to parse diff: %v", err)), nil
+		}
+		if len(changes) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no changes to review (%s)", diffOpts)), nil
+		}
+		opts = append(opts, agent.WithChangeScope(changes))
+	}
 
 	// 3. UseCase 層（Agent）の生成と実行
-	bot, err := agent.NewL5Agent(ctx, h.apiKey, projectPath, p.SystemPrompt, lspClient, fsReader, gitDiff, astResolver)
+	report, err := h.runReview(ctx, req, personas, query, func(p *persona.Persona, model agent.ChatModel, extra ...agent.Option) *agent.L5Agent {
+		// パネルでは並行に呼ばれるので opts を共有したまま append しない
+		return agent.NewL5Agent(model, projectPath, p, lspClient, fsReader, gitDiff, resolver, slices.Concat(opts, extra)...)
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// レイヤールール違反は LLM の判断によらず確定的な指摘として加える。
+	// 依存グラフを読み込めなくても（モジュールでない、パッケージの読み込みに失敗したなど）レビュー結果は返し、検査できなかったことを総評に残す
+	if rules != nil {
+		g, err := graph.Graph(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "layering rules skipped: failed to load import graph: %v\n", err)
+			report.Summary = strings.TrimSpace(report.Summary + fmt.Sprintf("\n\n> レイヤールール（%s）の検査は、依存グラフを読み込めなかったため実行していません: %v", rules.Path, err))
+		} else {
+			report.Findings = append(report.Findings, rules.Findings(rules.Check(g))...)
+			if err := report.Validate(); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid report: %v", err)), nil
+			}
+		}
+	}
+	if apiDiffMode != apiDiffOff {
+		diff, err := apiDiffer.Diff(ctx, symbol.APIDiffOptions{Base: apiBase, Head: apiHead, IncludeInternal: apiDiffMode == apiDiffAll})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to compare exported API: %v", err)), nil
+		}
+		report.API = apiReport(diff)
+	}
+	if changes != nil {
+		report.ApplyScope(changes, outOfScope == outOfScopeDrop)
+	}
+	if len(personas) == 1 {
+		report.SetPersona(personas[0].Name)
+	}
+
+	if format == formatSARIF {
+		var rules []review.Rule
+		for _, p := range personas {
+			rules = append(rules, rulesFor(p)...)
+		}
+		log := review.SARIF(Version, rules, report)
+		data, err := json.MarshalIndent(log, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to encode SARIF: %v", err)), nil
+		}
+		return mcp.NewToolResultStructured(log, string(data)), nil
+	}
+
+	return mcp.NewToolResultStructured(report, report.Markdown()), nil
+}
+
+// loadPersonas は review ツールの persona、または panel に指定されたペルソナを読み込みます。
+func (h *ReviewHandler) loadPersonas(req mcp.CallToolRequest) ([]*persona.Persona, error) {
+	names := req.GetStringSlice("panel", nil)
+	if len(names) == 0 {
+		names = []string{req.GetString("persona", "architect")}
+	}
+
+	personas := make([]*persona.Persona, 0, len(names))
+	for i, name := range names {
+		if slices.Contains(names[:i], name) {
+			return nil, fmt.Errorf("persona %q is listed twice in panel", name)
+		}
+		p, err := persona.Load(fmt.Sprintf("%s/%s.yaml", h.personaDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load persona %q: %v", name, err)
+		}
+		personas = append(personas, p)
+	}
+	return personas, nil
+}
+
+// runReview は各ペルソナのエージェントを実行してレビュー結果を返します。
+// 複数のペルソナ（パネル）は同じツール結果のキャッシュを共有して並行に実行し、最後に結果を1つに統合します。
+// パネルの一部のペルソナが失敗した場合は、残りのペルソナの結果を返します。
+func (h *ReviewHandler) runReview(
+	ctx context.Context,
+	req mcp.CallToolRequest,
+	personas []*persona.Persona,
+	query string,
+	newAgent func(p *persona.Persona, model agent.ChatModel, extra ...agent.Option) *agent.L5Agent,
+) (*review.Report, error) {
+	run := func(ctx context.Context, p *persona.Persona, extra ...agent.Option) (*review.Report, error) {
+		model, err := agent.NewChatModel(ctx, h.providerFor(p, req))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create model: %v", err)
+		}
+		report, err := newAgent(p, model, extra...).Run(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("agent error: %v", err)
+		}
+		report.SetPersona(p.Name)
+		return report, nil
+	}
+	if len(personas) == 1 {
+		return run(ctx, personas[0])
+	}
+
+	// 1つのペルソナが失敗しても他のペルソナは止めず、成功したレポートだけを統合する。
+	// 失敗したペルソナは統合結果の総評に記す。全員が失敗したときだけエラーにする
+	cache := agent.NewToolCache()
+	reports := make([]*review.Report, len(personas))
+	errs := make([]error, len(personas))
+	var wg sync.WaitGroup
+	for i, p := range personas {
+		wg.Go(func() {
+			reports[i], errs[i] = run(ctx, p, agent.WithToolCache(cache))
+		})
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[panel] persona %q failed: %v\n", personas[i].Name, err)
+			failed = append(failed, fmt.Sprintf("%s（%v）", personas[i].Name, err))
+			errs[i] = fmt.Errorf("persona %q: %w", personas[i].Name, err)
+		}
+	}
+	reports = slices.DeleteFunc(reports, func(r *review.Report) bool { return r == nil })
+	if len(reports) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	hits, runs := cache.Stats()
+	fmt.Fprintf(os.Stderr, "[panel] shared tool results: %d cache hits, %d tool runs\n", hits, runs)
+
+	// 統合はペルソナによらないので、サーバーの既定に review ツールの引数だけを重ねたモデルで行う
+	model, err := agent.NewChatModel(ctx, requestProvider(h.provider, req))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to create agent: %v", err)), nil
+		return nil, fmt.Errorf("failed to create model: %v", err)
+	}
+	report, err := agent.Aggregate(ctx, model, reports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate panel review: %v", err)
+	}
+	if len(failed) > 0 {
+		report.Summary += fmt.Sprintf("\n\n> 次のペルソナのレビューは失敗したため、この結果に含まれていません: %s", strings.Join(failed, "、"))
 	}
+	return report, nil
+}
 
-	result, err := bot.Run(ctx, query)
+// providerFor はサーバーの既定のモデル設定に、ペルソナの指定、review ツールの引数の順で重ねた設定を返します。
+func (h *ReviewHandler) providerFor(p *persona.Persona, req mcp.CallToolRequest) agent.ProviderConfig {
+	cfg := h.provider
+	if p.Model.Name != "" && p.Model.ModelFor(cfg.ProviderName()) == "" {
+		fmt.Fprintf(os.Stderr, "persona %q: model %q is for provider %s; using the server's model\n", p.Name, p.Model.Name, p.Model.Provider)
+	}
+	cfg = cfg.Override(p.Model.ModelFor(cfg.ProviderName()), agent.GenerationConfig{
+		Temperature:     p.Model.Temperature,
+		TopP:            p.Model.TopP,
+		MaxOutputTokens: p.Model.MaxOutputTokens,
+		ThinkingBudget:  p.Model.ThinkingBudget,
+		SafetySettings:  p.Model.Safety,
+	})
+	return requestProvider(cfg, req)
+}
+
+// requestProvider は review ツールの引数で指定されたモデルと生成パラメータを cfg に重ねます。
+func requestProvider(cfg agent.ProviderConfig, req mcp.CallToolRequest) agent.ProviderConfig {
+	return cfg.Override(req.GetString("model", ""), generationFrom(req))
+}
+
+// generationFrom は review ツールの引数で指定された生成パラメータを取り出します。
+func generationFrom(req mcp.CallToolRequest) agent.GenerationConfig {
+	args := req.GetArguments()
+	var gen agent.GenerationConfig
+	if v, ok := args["temperature"].(float64); ok {
+		gen.Temperature = &v
+	}
+	if v, ok := args["top_p"].(float64); ok {
+		gen.TopP = &v
+	}
+	if v, ok := args["max_output_tokens"].(float64); ok {
+		gen.MaxOutputTokens = int(v)
+	}
+	if v, ok := args["thinking_budget"].(float64); ok {
+		budget := int(v)
+		gen.ThinkingBudget = &budget
+	}
+	if v, ok := args["safety"].(map[string]any); ok && len(v) > 0 {
+		// 文字列以外のしきい値もそのまま渡し、モデルの生成時にエラーにする
+		gen.SafetySettings = make(map[string]string, len(v))
+		for category, threshold := range v {
+			gen.SafetySettings[category] = fmt.Sprint(threshold)
+		}
+	}
+	return gen
+}
+
+// symbolIndex はプロジェクトのシンボル索引を返します。同じプロジェクトのレビュー間で共有します。
+// 索引を開けない場合は nil を返し、ASTResolver は毎回ツリー全体を解析します。
+func (h *ReviewHandler) symbolIndex(projectPath string) *symbol.Index {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if idx, ok := h.indexes[projectPath]; ok {
+		return idx
+	}
+	idx, err := symbol.OpenIndex(projectPath, h.indexDir)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("agent error: %v", err)), nil
+		fmt.Fprintf(os.Stderr, "symbol index disabled for %s: %v\n", projectPath, err)
+		return nil
 	}
+	h.indexes[projectPath] = idx
+	return idx
+}
+
+// diffOptionsFrom は review ツールの引数からレビュー対象の差分設定を組み立てます。
+func diffOptionsFrom(req mcp.CallToolRequest) (workspace.DiffOptions, error) {
+	opts := workspace.DiffOptions{
+		Base:      req.GetString("base", ""),
+		MergeBase: req.GetString("merge_base", ""),
+		Range:     req.GetString("range", ""),
+		Paths:     req.GetStringSlice("paths", nil),
+	}
+	if mode := req.GetString("diff_mode", "all"); mode != "all" {
+		opts.Mode = workspace.DiffMode(mode)
+	}
+	return opts, opts.Validate()
+}
 
-	return mcp.NewToolResultText(result), nil
+// apiReport は公開 API の比較結果をレビュー結果のセクションに変換します。
+func apiReport(diff *symbol.APIDiff) *review.APIReport {
+	r := &review.APIReport{
+		Base:       diff.Base,
+		Head:       diff.HeadName(),
+		Changes:    make([]review.APIChange, 0, len(diff.Changes)),
+		Incomplete: diff.Incomplete,
+	}
+	for _, c := range diff.Changes {
+		r.Changes = append(r.Changes, review.APIChange{
+			Package:  c.Package,
+			Name:     c.Name,
+			Kind:     c.Kind,
+			Change:   string(c.Change),
+			Old:      c.Old,
+			New:      c.New,
+			Breaking: c.Breaking,
+		})
+	}
+	return r
+}
+
+// rulesFor はペルソナのレビュー観点から SARIF のルール定義を組み立てます。
+func rulesFor(p *persona.Persona) []review.Rule {
+	rules := make([]review.Rule, 0, len(p.Aspects))
+	for _, a := range p.Aspects {
+		rules = append(rules, review.Rule{
+			ID:          review.RuleID(p.Name, a.ID),
+			Name:        a.Name,
+			Description: a.Description,
+		})
+	}
+	return rules
 }
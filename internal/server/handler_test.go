@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/0muji4/llm-reviewer/internal/agent"
+	"github.com/0muji4/llm-reviewer/internal/persona"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func callTool(args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Arguments = args
+	return req
+}
+
+// describe はポインタの指す値で比べられるようモデル設定を文字列にします
+func describe(cfg agent.ProviderConfig) string {
+	g := cfg.Generation
+	deref := func(p any) any {
+		switch p := p.(type) {
+		case *float64:
+			if p != nil {
+				return *p
+			}
+		case *int:
+			if p != nil {
+				return *p
+			}
+		}
+		return nil
+	}
+	return fmt.Sprintf("%s/%s temperature=%v top_p=%v max_output_tokens=%d thinking_budget=%v safety=%v",
+		cfg.ProviderName(), cfg.Model, deref(g.Temperature), deref(g.TopP), g.MaxOutputTokens, deref(g.ThinkingBudget), g.SafetySettings)
+}
+
+func TestProviderFor(t *testing.T) {
+	server := agent.ProviderConfig{
+		Provider: agent.ProviderGemini,
+		Model:    "gemini-2.5-flash",
+		APIKey:   "key",
+		Generation: agent.GenerationConfig{
+			Temperature:     ptr(0.2),
+			TopP:            ptr(0.9),
+			MaxOutputTokens: 1000,
+			SafetySettings:  map[string]string{"harassment": "block_none", "dangerous_content": "block_low_and_above"},
+		},
+	}
+	security := persona.Model{
+		Name:           "gemini-2.5-pro",
+		Temperature:    ptr(0.0),
+		ThinkingBudget: ptr(2048),
+		Safety:         map[string]string{"dangerous_content": "block_only_high"},
+	}
+
+	tests := []struct {
+		name  string
+		model persona.Model
+		args  map[string]any
+		want  string
+	}{
+		{
+			name: "server defaults",
+			want: "gemini/gemini-2.5-flash temperature=0.2 top_p=0.9 max_output_tokens=1000 thinking_budget=<nil> safety=map[dangerous_content:block_low_and_above harassment:block_none]",
+		},
+		{
+			name:  "persona over server",
+			model: security,
+			want:  "gemini/gemini-2.5-pro temperature=0 top_p=0.9 max_output_tokens=1000 thinking_budget=2048 safety=map[dangerous_content:block_only_high harassment:block_none]",
+		},
+		{
+			name:  "request over persona",
+			model: security,
+			args: map[string]any{
+				"model": "gemini-2.5-flash-lite", "temperature": 0.7, "max_output_tokens": float64(500), "thinking_budget": float64(0),
+				"safety": map[string]any{"harassment": "block_only_high", "hate_speech": "off"},
+			},
+			want: "gemini/gemini-2.5-flash-lite temperature=0.7 top_p=0.9 max_output_tokens=500 thinking_budget=0 safety=map[dangerous_content:block_only_high harassment:block_only_high hate_speech:off]",
+		},
+		{
+			name:  "persona model for another provider",
+			model: persona.Model{Provider: agent.ProviderOpenAI, Name: "gpt-4o", TopP: ptr(0.5)},
+			want:  "gemini/gemini-2.5-flash temperature=0.2 top_p=0.5 max_output_tokens=1000 thinking_budget=<nil> safety=map[dangerous_content:block_low_and_above harassment:block_none]",
+		},
+		{
+			// しきい値が文字列でなくても捨てずに渡し、モデルの生成時にエラーにする
+			name: "non-string safety threshold",
+			args: map[string]any{"safety": map[string]any{"harassment": float64(1)}},
+			want: "gemini/gemini-2.5-flash temperature=0.2 top_p=0.9 max_output_tokens=1000 thinking_budget=<nil> safety=map[dangerous_content:block_low_and_above harassment:1]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewReviewHandler(server, "", "")
+			p := &persona.Persona{Name: "security", Model: tt.model}
+			got := h.providerFor(p, callTool(tt.args))
+			if describe(got) != tt.want {
+				t.Errorf("providerFor =\n%s\nwant\n%s", describe(got), tt.want)
+			}
+			if got.APIKey != server.APIKey {
+				t.Errorf("APIKey = %q, want the server's", got.APIKey)
+			}
+		})
+	}
+
+	// 重ねてもサーバーの設定は書き換えない
+	if server.Generation.SafetySettings["harassment"] != "block_none" || *server.Generation.Temperature != 0.2 {
+		t.Errorf("providerFor modified the server config: %s", describe(server))
+	}
+}
@@ -3,19 +3,24 @@ package server
 import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/0muji4/llm-reviewer/internal/workspace"
 )
 
+// Version は MCP サーバーおよび SARIF 出力に記録するバージョンです。
+const Version = "0.1.0"
+
 // New は MCP サーバーを生成し、ツールを登録して返します。
 // ビジネスロジックは handler に委譲し、ここではプロトコル変換のみ行います。
 func New(handler *ReviewHandler) *server.MCPServer {
 	s := server.NewMCPServer(
 		"llm-reviewer",
-		"0.1.0",
+		Version,
 		server.WithToolCapabilities(false),
 	)
 
 	reviewTool := mcp.NewTool("review",
-		mcp.WithDescription("指定されたGoプロジェクトに対してLLMベースのコードレビューを実行します。Gemini ReActループにより、LSP・AST・Git差分を活用した深いコード分析を行います。"),
+		mcp.WithDescription("指定されたGoプロジェクトに対してLLMベースのコードレビューを実行します。LLM（Gemini / OpenAI 互換）の ReAct ループにより、LSP・AST・Git差分を活用した深いコード分析を行います。"),
 		mcp.WithString("project_path",
 			mcp.Required(),
 			mcp.Description("レビュー対象のGoプロジェクトの絶対パス"),
@@ -27,6 +32,73 @@ func New(handler *ReviewHandler) *server.MCPServer {
 		mcp.WithString("persona",
 			mcp.Description("使用するペルソナ名（architect, go-expert）。デフォルト: architect"),
 		),
+		mcp.WithArray("panel",
+			mcp.WithStringItems(),
+			mcp.Description("パネルレビューに参加させるペルソナ名（例: [architect, go-expert]）。指定すると persona の代わりに、各ペルソナが並行にレビューしてツールの結果を共有し、重複をまとめて矛盾を解消した1つのレビュー結果に統合する"),
+		),
+		mcp.WithString("base",
+			mcp.Description("差分の比較元 ref（例: main, HEAD~3）。デフォルト: HEAD"),
+		),
+		mcp.WithString("merge_base",
+			mcp.Description("指定したブランチと HEAD の merge-base を比較元にする（例: main）。フィーチャーブランチのレビューに使用"),
+		),
+		mcp.WithString("range",
+			mcp.Description("コミット範囲（例: main..feature, v1.0...HEAD）。base・merge_base・diff_mode とは併用不可"),
+		),
+		mcp.WithString("diff_mode",
+			mcp.Enum("all", string(workspace.DiffModeStaged), string(workspace.DiffModeUnstaged)),
+			mcp.Description("all: ステージ済み＋未ステージ、staged: ステージ済みのみ、unstaged: 未ステージのみ。デフォルト: all"),
+		),
+		mcp.WithArray("paths",
+			mcp.WithStringItems(),
+			mcp.Description("差分を絞り込むパス（プロジェクトルートからの相対パス）"),
+		),
+		mcp.WithString("scope",
+			mcp.Enum(scopeFull, scopeDiff),
+			mcp.Description("full: プロジェクト全体をレビュー、diff: 差分の変更箇所（ハンク）のみを指摘対象にする（PRレビュー向け）。デフォルト: full"),
+		),
+		mcp.WithString("out_of_scope",
+			mcp.Enum(outOfScopeDemote, outOfScopeDrop),
+			mcp.Description("scope=diff のとき、変更箇所以外への指摘の扱い（demote: 参考情報として残す、drop: 捨てる）。デフォルト: demote"),
+		),
+		mcp.WithString("symbol_resolver",
+			mcp.Enum(resolverAST, resolverLSP),
+			mcp.Description("find-symbol のシンボル解決方法（ast: go/ast による完全一致検索、lsp: gopls の workspace/symbol、修飾名・あいまい検索に対応）。デフォルト: ast"),
+		),
+		mcp.WithString("layering_rules",
+			mcp.Description("レイヤールールファイルのパス（プロジェクトルートからの相対パス）。違反は確定的な指摘としてレビュー結果に加わる。デフォルト: .llm-reviewer/layers.yaml（存在する場合）"),
+		),
+		mcp.WithString("api_diff",
+			mcp.Enum(apiDiffOff, apiDiffPublic, apiDiffAll),
+			mcp.Description("差分の比較元と比較先で公開 API を比較し、レビュー結果に独立したセクションとして加える（off: 比較しない、public: internal 以外のパッケージ、all: internal パッケージも含む）。比較元・比較先がリビジョンでない diff_mode=staged・unstaged とは併用できない。デフォルト: off"),
+		),
+		mcp.WithString("model",
+			mcp.Description("使用するモデル名（例: gemini-2.5-pro）。デフォルト: ペルソナの指定、なければサーバーの設定"),
+		),
+		mcp.WithNumber("temperature",
+			mcp.Min(0), mcp.Max(2),
+			mcp.Description("サンプリングの温度。デフォルト: ペルソナの指定、なければサーバーの設定"),
+		),
+		mcp.WithNumber("top_p",
+			mcp.Min(0), mcp.Max(1),
+			mcp.Description("top-p サンプリングの値。デフォルト: ペルソナの指定、なければサーバーの設定"),
+		),
+		mcp.WithNumber("max_output_tokens",
+			mcp.Min(1),
+			mcp.Description("1回の応答の最大出力トークン数。デフォルト: ペルソナの指定、なければサーバーの設定"),
+		),
+		mcp.WithNumber("thinking_budget",
+			mcp.Min(-1),
+			mcp.Description("思考に使うトークン数（0: 思考しない、-1: モデルに任せる。Gemini のみ）。デフォルト: ペルソナの指定、なければサーバーの設定"),
+		),
+		mcp.WithObject("safety",
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+			mcp.Description("有害カテゴリごとのブロックのしきい値（例: {\"dangerous_content\": \"block_only_high\"}。Gemini のみ）。指定したカテゴリだけをペルソナ・サーバーの設定に重ねる"),
+		),
+		mcp.WithString("format",
+			mcp.Enum(formatMarkdown, formatSARIF),
+			mcp.Description("出力形式（markdown: 構造化結果＋Markdown、sarif: SARIF 2.1.0）。デフォルト: markdown"),
+		),
 	)
 
 	s.AddTool(reviewTool, handler.Handle)
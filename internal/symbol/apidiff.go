@@ -0,0 +1,296 @@
+package symbol
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Checkout materializes a revision of the project in a directory.
+// workspace.GitDiff satisfies it with a temporary git worktree.
+type Checkout interface {
+	Checkout(rev string) (dir string, cleanup func() error, err error)
+}
+
+// APIChangeKind classifies an APIChange.
+type APIChangeKind string
+
+const (
+	APIAdded   APIChangeKind = "added"
+	APIRemoved APIChangeKind = "removed"
+	APIChanged APIChangeKind = "changed"
+)
+
+// APIChange is an exported identifier that differs between two revisions.
+type APIChange struct {
+	Package  string // インポートパス
+	Name     string // T, T.Method, T.Field など。パッケージ全体の追加・削除では空
+	Kind     string // Kind* のいずれか（変更後、削除なら変更前）
+	Change   APIChangeKind
+	Old, New string // 変更前後のシグネチャ（追加・削除では片方が空）
+	Breaking bool   // 既存の利用者のビルドを壊しうる
+}
+
+func (c APIChange) String() string {
+	name := c.Package
+	if c.Name != "" {
+		name += "." + c.Name
+	}
+	switch c.Change {
+	case APIAdded:
+		return fmt.Sprintf("+ %s: %s", name, c.New)
+	case APIRemoved:
+		return fmt.Sprintf("- %s: %s", name, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", name, c.Old, c.New)
+	}
+}
+
+// APIDiff is the difference between the exported APIs of two revisions.
+type APIDiff struct {
+	Base, Head string // Head が空なら作業ツリー
+	Changes    []APIChange
+	Incomplete []string // 読み込みや型検査に失敗し、結果が不完全かもしれないパッケージ
+}
+
+// Breaking returns the incompatible changes.
+func (d *APIDiff) Breaking() []APIChange {
+	var breaking []APIChange
+	for _, c := range d.Changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+// HeadName returns Head, or "working tree" when it is empty.
+func (d *APIDiff) HeadName() string {
+	if d.Head == "" {
+		return "working tree"
+	}
+	return d.Head
+}
+
+// APIDiffOptions selects what an APIDiffer compares.
+type APIDiffOptions struct {
+	Base            string // 比較元のリビジョン
+	Head            string // 比較先のリビジョン。空なら作業ツリー
+	Package         string // インポートパスまたはモジュールルートからのディレクトリ。末尾 /... で配下すべて。空なら全パッケージ
+	IncludeInternal bool   // internal パッケージも比較する（モジュール外からは import できないため既定では除外）
+}
+
+// APIComparer compares the exported API of two revisions of the project.
+type APIComparer interface {
+	Diff(ctx context.Context, opts APIDiffOptions) (*APIDiff, error)
+}
+
+var _ APIComparer = (*APIDiffer)(nil)
+
+// APIDiffer compares the exported API of the project's packages between
+// revisions. Each revision is type-checked once, in a temporary checkout, and
+// cached for the lifetime of the APIDiffer.
+type APIDiffer struct {
+	rootPath string
+	checkout Checkout
+
+	mu   sync.Mutex
+	apis map[string]*revisionAPI // リビジョンで引く。作業ツリーは空文字列
+}
+
+// revisionAPI is the exported API of every package of one revision.
+type revisionAPI struct {
+	packages   map[string][]APIEntry // インポートパスで引く
+	incomplete []string
+}
+
+func NewAPIDiffer(rootPath string, checkout Checkout) *APIDiffer {
+	return &APIDiffer{rootPath: rootPath, checkout: checkout, apis: make(map[string]*revisionAPI)}
+}
+
+// Diff compares the exported API of opts.Base and opts.Head.
+func (d *APIDiffer) Diff(ctx context.Context, opts APIDiffOptions) (*APIDiff, error) {
+	if opts.Base == "" {
+		return nil, fmt.Errorf("base revision is required")
+	}
+	base, err := d.api(ctx, opts.Base)
+	if err != nil {
+		return nil, err
+	}
+	head, err := d.api(ctx, opts.Head)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &APIDiff{Base: opts.Base, Head: opts.Head}
+	include := func(pkgPath string) bool {
+		if !opts.IncludeInternal && isInternal(pkgPath) {
+			return false
+		}
+		return opts.Package == "" || matchPackage(opts.Package, pkgPath)
+	}
+
+	pkgPaths := make(map[string]bool)
+	for path := range base.packages {
+		pkgPaths[path] = true
+	}
+	for path := range head.packages {
+		pkgPaths[path] = true
+	}
+	for path := range pkgPaths {
+		if include(path) {
+			diff.Changes = append(diff.Changes, compareAPI(path, base.packages[path], head.packages[path])...)
+		}
+	}
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i], diff.Changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+
+	seen := make(map[string]bool)
+	for _, path := range append(base.incomplete, head.incomplete...) {
+		if include(path) && !seen[path] {
+			seen[path] = true
+			diff.Incomplete = append(diff.Incomplete, path)
+		}
+	}
+	sort.Strings(diff.Incomplete)
+	return diff, nil
+}
+
+// api returns the exported API of rev, checking it out on first use.
+func (d *APIDiffer) api(ctx context.Context, rev string) (*revisionAPI, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if api, ok := d.apis[rev]; ok {
+		return api, nil
+	}
+
+	dir := d.rootPath
+	if rev != "" {
+		checkoutDir, cleanup, err := d.checkout.Checkout(rev)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				fmt.Fprintf(os.Stderr, "symbol: failed to remove checkout of %s: %v\n", rev, err)
+			}
+		}()
+		dir = checkoutDir
+	}
+
+	api, err := loadAPI(ctx, dir)
+	if err != nil {
+		if rev == "" {
+			return nil, fmt.Errorf("working tree: %w", err)
+		}
+		return nil, fmt.Errorf("revision %s: %w", rev, err)
+	}
+	d.apis[rev] = api
+	return api, nil
+}
+
+// loadAPI type-checks the importable packages under dir. Main packages are
+// skipped since nothing can import them.
+func loadAPI(ctx context.Context, dir string) (*revisionAPI, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Dir:     dir,
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedModule,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %w", err)
+	}
+
+	api := &revisionAPI{packages: make(map[string][]APIEntry)}
+	for _, p := range pkgs {
+		if p.Name == "main" || p.Types == nil || p.Module == nil || !p.Module.Main {
+			continue
+		}
+		if len(p.Errors) > 0 || p.IllTyped {
+			api.incomplete = append(api.incomplete, p.PkgPath)
+		}
+		entries := ExportedAPI(p.Types)
+		if entries == nil {
+			entries = []APIEntry{} // 公開 API のないパッケージも「存在しない」とは区別する
+		}
+		api.packages[p.PkgPath] = entries
+	}
+	return api, nil
+}
+
+// compareAPI diffs the exported API of one package. A package that exists on
+// only one side is reported as a single package-level change.
+func compareAPI(pkgPath string, base, head []APIEntry) []APIChange {
+	switch {
+	case base == nil:
+		return []APIChange{{Package: pkgPath, Kind: KindPackage, Change: APIAdded, New: fmt.Sprintf("new package (%d exported identifiers)", len(head))}}
+	case head == nil:
+		return []APIChange{{Package: pkgPath, Kind: KindPackage, Change: APIRemoved, Old: fmt.Sprintf("package (%d exported identifiers)", len(base)), Breaking: true}}
+	}
+
+	baseByName := make(map[string]APIEntry, len(base))
+	for _, e := range base {
+		baseByName[e.Name] = e
+	}
+	headByName := make(map[string]APIEntry, len(head))
+	for _, e := range head {
+		headByName[e.Name] = e
+	}
+
+	var changes []APIChange
+	for _, e := range base {
+		h, ok := headByName[e.Name]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Package: pkgPath, Name: e.Name, Kind: e.Kind, Change: APIRemoved, Old: e.Signature, Breaking: true})
+		case h.Kind != e.Kind || h.shape != e.shape:
+			changes = append(changes, APIChange{Package: pkgPath, Name: e.Name, Kind: h.Kind, Change: APIChanged, Old: e.Signature, New: h.Signature, Breaking: true})
+		}
+	}
+	for _, e := range head {
+		if _, ok := baseByName[e.Name]; ok {
+			continue
+		}
+		// 既存の interface へのメソッド追加は、その interface の実装側を壊す
+		owner, _, isMember := strings.Cut(e.Name, ".")
+		breaking := isMember && e.Kind == KindMethod && baseByName[owner].Kind == KindInterface && headByName[owner].Kind == KindInterface
+		changes = append(changes, APIChange{Package: pkgPath, Name: e.Name, Kind: e.Kind, Change: APIAdded, New: e.Signature, Breaking: breaking})
+	}
+	return changes
+}
+
+// isInternal reports whether pkgPath can only be imported from inside its module tree.
+func isInternal(pkgPath string) bool {
+	return strings.HasSuffix(pkgPath, "/internal") || strings.Contains(pkgPath, "/internal/") || strings.HasPrefix(pkgPath, "internal/")
+}
+
+// matchPackage reports whether pkgPath matches spec: an import path or a
+// suffix of one (internal/lsp), optionally followed by "/..." for subpackages.
+func matchPackage(spec, pkgPath string) bool {
+	spec = strings.TrimSuffix(strings.TrimPrefix(spec, "./"), "/")
+	if prefix, ok := strings.CutSuffix(spec, "/..."); ok {
+		for p := pkgPath; p != ""; {
+			if p == prefix || strings.HasSuffix(p, "/"+prefix) {
+				return true
+			}
+			i := strings.LastIndex(p, "/")
+			if i < 0 {
+				break
+			}
+			p = p[:i]
+		}
+		return false
+	}
+	return pkgPath == spec || strings.HasSuffix(pkgPath, "/"+spec)
+}
@@ -0,0 +1,139 @@
+package symbol
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+)
+
+// exportedAPI type-checks src as package p and returns its exported API,
+// empty rather than nil when there is none, as loadAPI does.
+func exportedAPI(t *testing.T, src string) []APIEntry {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", "package p\n"+src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	pkg, err := (&types.Config{}).Check("example.com/p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("type-check: %v", err)
+	}
+	if api := ExportedAPI(pkg); api != nil {
+		return api
+	}
+	return []APIEntry{}
+}
+
+func TestCompareAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, head string
+		want       []string // APIChange.String()、互換性を壊すものは "!" を前に付ける
+	}{
+		{
+			name: "unchanged",
+			base: "func F(a int) error { return nil }",
+			head: "func F(a int) error { return nil }",
+		},
+		{
+			name: "parameter rename is compatible",
+			base: "func F(a int) {}",
+			head: "func F(b int) {}",
+		},
+		{
+			name: "added function",
+			base: "",
+			head: "func F() {}",
+			want: []string{"+ example.com/p.F: func F()"},
+		},
+		{
+			name: "removed function",
+			base: "func F() {}\nfunc unexported() {}",
+			head: "",
+			want: []string{"!- example.com/p.F: func F()"},
+		},
+		{
+			name: "signature change",
+			base: "func F(a int) {}",
+			head: "func F(a int64) {}",
+			want: []string{"!~ example.com/p.F: func F(a int) -> func F(a int64)"},
+		},
+		{
+			name: "type parameter constraint",
+			base: "func F[T any](v T) {}",
+			head: "func F[T comparable](v T) {}",
+			want: []string{"!~ example.com/p.F: func F[T any](v T) -> func F[T comparable](v T)"},
+		},
+		{
+			name: "pointer receiver",
+			base: "type S struct{}\nfunc (S) M() {}",
+			head: "type S struct{}\nfunc (*S) M() {}",
+			want: []string{"!~ example.com/p.S.M: func (S).M() -> func (*S).M()"},
+		},
+		{
+			name: "method added to an interface",
+			base: "type I interface{ A() }",
+			head: "type I interface{ A(); B() }",
+			want: []string{"!+ example.com/p.I.B: B()"},
+		},
+		{
+			name: "method added to a struct",
+			base: "type S struct{}",
+			head: "type S struct{}\nfunc (S) M() {}",
+			want: []string{"+ example.com/p.S.M: func (S).M()"},
+		},
+		{
+			name: "kind change",
+			base: "const X = 1",
+			head: "var X = 1",
+			want: []string{"!~ example.com/p.X: const X untyped int -> var X int"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := compareAPI("example.com/p", exportedAPI(t, tt.base), exportedAPI(t, tt.head))
+			var got []string
+			for _, c := range changes {
+				s := c.String()
+				if c.Breaking {
+					s = "!" + s
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changes =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareAPIPackages(t *testing.T) {
+	entries := []APIEntry{{Name: "F", Kind: KindFunction}}
+	tests := []struct {
+		name       string
+		base, head []APIEntry
+		want       string
+		breaking   bool
+	}{
+		{"added package", nil, entries, "+ example.com/p: new package (1 exported identifiers)", false},
+		{"removed package", entries, nil, "- example.com/p: package (1 exported identifiers)", true},
+		{"package without exports", []APIEntry{}, nil, "- example.com/p: package (0 exported identifiers)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := compareAPI("example.com/p", tt.base, tt.head)
+			if len(changes) != 1 {
+				t.Fatalf("got %d changes, want 1", len(changes))
+			}
+			c := changes[0]
+			if got := fmt.Sprint(c); got != tt.want || c.Breaking != tt.breaking || c.Kind != KindPackage {
+				t.Errorf("change = %q (breaking=%v, kind=%s), want %q (breaking=%v)", got, c.Breaking, c.Kind, tt.want, tt.breaking)
+			}
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package symbol
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -14,20 +21,68 @@ var _ Resolver = (*ASTResolver)(nil)
 // ASTResolver resolves symbol names to source locations using go/ast.
 type ASTResolver struct {
 	rootPath string
+	index    *Index
 }
 
-func NewASTResolver(rootPath string) *ASTResolver {
-	return &ASTResolver{rootPath: rootPath}
+// NewASTResolver returns a resolver that answers from index when one is
+// given, and otherwise parses the tree on every query.
+func NewASTResolver(rootPath string, index *Index) *ASTResolver {
+	return &ASTResolver{rootPath: rootPath, index: index}
 }
 
-func (r *ASTResolver) FindSymbol(name string) ([]SymbolLocation, error) {
+// FindSymbol accepts a plain name (Close), a member of a type (Client.Close,
+// (*Client).Close) or a package-qualified name (lsp.Client.Close, lsp.NewClient).
+func (r *ASTResolver) FindSymbol(ctx context.Context, name string) ([]SymbolLocation, error) {
+	q := parseQuery(name)
+
+	if r.index != nil {
+		err := r.index.Update(ctx)
+		if err == nil {
+			return r.index.lookup(q), nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// 索引が使えなければ従来どおりツリー全体を解析する
+		fmt.Fprintf(os.Stderr, "symbol: index unavailable, scanning %s: %v\n", r.rootPath, err)
+	}
+	return r.scan(ctx, q)
+}
+
+// scan parses every source file under the root.
+func (r *ASTResolver) scan(ctx context.Context, q query) ([]SymbolLocation, error) {
+	modulePath := readModulePath(r.rootPath)
+
 	var results []SymbolLocation
 	fset := token.NewFileSet()
 
-	err := filepath.WalkDir(r.rootPath, func(path string, d os.DirEntry, err error) error {
+	err := walkSources(ctx, r.rootPath, func(path, relPath string, _ os.DirEntry) error {
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+
+		for _, sym := range fileSymbols(fset, f, relPath, packagePath(modulePath, relPath)) {
+			if q.matches(sym) {
+				results = append(results, sym)
+			}
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// walkSources calls fn for every non-test .go file under rootPath, skipping
+// vendor, .git and node_modules.
+func walkSources(ctx context.Context, rootPath string, fn func(path, relPath string, d os.DirEntry) error) error {
+	return filepath.WalkDir(rootPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// ディレクトリのスキップ
 		if d.IsDir() {
@@ -43,51 +98,189 @@ func (r *ASTResolver) FindSymbol(name string) ([]SymbolLocation, error) {
 			return nil
 		}
 
-		f, err := parser.ParseFile(fset, path, nil, 0)
-		if err != nil {
-			return nil
+		relPath, _ := filepath.Rel(rootPath, path)
+		return fn(path, relPath, d)
+	})
+}
+
+// sortLocations orders symbols by file and position, matching the order of a
+// tree walk.
+func sortLocations(locs []SymbolLocation) {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].FilePath != locs[j].FilePath {
+			return locs[i].FilePath < locs[j].FilePath
+		}
+		if locs[i].Line != locs[j].Line {
+			return locs[i].Line < locs[j].Line
 		}
+		return locs[i].Character < locs[j].Character
+	})
+}
 
-		ast.Inspect(f, func(n ast.Node) bool {
-			switch node := n.(type) {
-			case *ast.FuncDecl:
-				if node.Name.Name == name {
-					pos := fset.Position(node.Name.Pos())
-					relPath, _ := filepath.Rel(r.rootPath, pos.Filename)
-					results = append(results, SymbolLocation{
-						FilePath:  relPath,
-						Line:      pos.Line,
-						Character: pos.Column,
-					})
-				}
-			case *ast.TypeSpec:
-				if node.Name.Name == name {
-					pos := fset.Position(node.Name.Pos())
-					relPath, _ := filepath.Rel(r.rootPath, pos.Filename)
-					results = append(results, SymbolLocation{
-						FilePath:  relPath,
-						Line:      pos.Line,
-						Character: pos.Column,
-					})
-				}
-			case *ast.ValueSpec:
-				for _, ident := range node.Names {
-					if ident.Name == name {
-						pos := fset.Position(ident.Pos())
-						relPath, _ := filepath.Rel(r.rootPath, pos.Filename)
-						results = append(results, SymbolLocation{
-							FilePath:  relPath,
-							Line:      pos.Line,
-							Character: pos.Column,
-						})
+// fileSymbols collects the functions, methods, types, struct fields, interface
+// methods, variables and constants declared in f.
+func fileSymbols(fset *token.FileSet, f *ast.File, relPath, pkgPath string) []SymbolLocation {
+	var symbols []SymbolLocation
+	add := func(ident *ast.Ident, kind, receiver, signature string) {
+		pos := fset.Position(ident.Pos())
+		name := f.Name.Name + "." + ident.Name
+		if receiver != "" {
+			name = f.Name.Name + "." + receiver + "." + ident.Name
+		}
+		symbols = append(symbols, SymbolLocation{
+			FilePath:  relPath,
+			Line:      pos.Line,
+			Character: pos.Column,
+			Name:      name,
+			Kind:      kind,
+			Receiver:  receiver,
+			Package:   pkgPath,
+			Signature: signature,
+		})
+	}
+
+	// 関数本体の中の宣言（ローカル変数・型など）はパッケージのシンボルではないので、Outline と同じく最上位の宣言だけを見る
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				add(d.Name, KindFunction, "", funcSignature(fset, d))
+			} else if len(d.Recv.List) > 0 {
+				add(d.Name, KindMethod, receiverName(d.Recv.List[0].Type), funcSignature(fset, d))
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					typeSymbols(fset, spec, add)
+				case *ast.ValueSpec:
+					kind, keyword := KindVariable, "var "
+					if d.Tok == token.CONST {
+						kind, keyword = KindConstant, "const "
+					}
+					for _, ident := range spec.Names {
+						signature := keyword + ident.Name
+						if spec.Type != nil {
+							signature += " " + exprString(fset, spec.Type)
+						}
+						add(ident, kind, "", signature)
 					}
 				}
 			}
-			return true
-		})
+		}
+	}
+	return symbols
+}
 
-		return nil
-	})
+// typeSymbols adds a type declaration and, for structs and interfaces, its
+// fields and methods.
+func typeSymbols(fset *token.FileSet, spec *ast.TypeSpec, add func(ident *ast.Ident, kind, receiver, signature string)) {
+	typeName := spec.Name.Name
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		add(spec.Name, KindStruct, "", "type "+typeName+" struct")
+		for _, field := range t.Fields.List {
+			for _, ident := range fieldNames(field) {
+				add(ident, KindField, typeName, ident.Name+" "+exprString(fset, field.Type))
+			}
+		}
+	case *ast.InterfaceType:
+		add(spec.Name, KindInterface, "", "type "+typeName+" interface")
+		for _, m := range t.Methods.List {
+			ft, ok := m.Type.(*ast.FuncType)
+			if !ok {
+				continue // 埋め込み interface
+			}
+			for _, ident := range m.Names {
+				add(ident, KindMethod, typeName, ident.Name+strings.TrimPrefix(exprString(fset, ft), "func"))
+			}
+		}
+	default:
+		add(spec.Name, KindType, "", "type "+typeName+" "+exprString(fset, spec.Type))
+	}
+}
 
-	return results, err
+// funcSignature renders the declaration without its body.
+func funcSignature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	decl := *fn
+	decl.Body = nil
+	decl.Doc = nil
+	return exprString(fset, &decl)
+}
+
+func exprString(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	// 複数行にわたる型（無名 struct など）は1行に詰める
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// receiverName returns the receiver's type name without pointer or type parameters.
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// fieldNames returns the names a struct field declares; for an embedded field
+// that is the name of the embedded type.
+func fieldNames(field *ast.Field) []*ast.Ident {
+	if len(field.Names) > 0 {
+		return field.Names
+	}
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return []*ast.Ident{e}
+	case *ast.SelectorExpr:
+		return []*ast.Ident{e.Sel}
+	}
+	return nil
+}
+
+// readModulePath returns the module path declared in rootPath/go.mod, or "".
+func readModulePath(rootPath string) string {
+	data, err := os.ReadFile(filepath.Join(rootPath, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// packagePath returns the import path of the package containing relPath.
+func packagePath(modulePath, relPath string) string {
+	dir := filepath.ToSlash(filepath.Dir(relPath))
+	switch {
+	case modulePath == "":
+		return dir
+	case dir == ".":
+		return modulePath
+	default:
+		return path.Join(modulePath, dir)
+	}
 }
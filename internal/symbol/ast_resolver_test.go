@@ -0,0 +1,156 @@
+package symbol
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testSource は internal/lsp/client.go として解析するソースです
+const testSource = `package lsp
+
+import "io"
+
+type Client struct {
+	io.Closer
+	*Conn
+	rootPath, name string
+}
+
+func NewClient() *Client { return nil }
+
+func (c *Client) Close() error {
+	type local struct{ x int }
+	var v int
+	_ = v
+	return nil
+}
+
+type Conn struct{}
+
+func (Conn) Close() {}
+
+type Provider interface {
+	io.Reader
+	Symbols(query string) ([]string, error)
+}
+
+type List[T any] struct{ items []T }
+
+func (l *List[T]) Len() int { return len(l.items) }
+
+func (l List[T]) At(i int) T { return l.items[i] }
+
+type Pair[K comparable, V any] struct{}
+
+func (p Pair[K, V]) Key() K { var k K; return k }
+
+const Version = "1"
+
+var (
+	Default *Client
+	x, y int = 1, 2
+)
+
+type ID int
+`
+
+// parseTestSource は testSource のシンボルを返します
+func parseTestSource(t *testing.T) []SymbolLocation {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "internal/lsp/client.go", testSource, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileSymbols(fset, f, "internal/lsp/client.go", "example.com/m/internal/lsp")
+}
+
+func TestFileSymbols(t *testing.T) {
+	var got []string
+	for _, s := range parseTestSource(t) {
+		if s.FilePath != "internal/lsp/client.go" || s.Package != "example.com/m/internal/lsp" {
+			t.Errorf("%s: FilePath %q, Package %q", s.Name, s.FilePath, s.Package)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s %s receiver=%s | %s", s.Line, s.Character, s.Name, s.Kind, s.Receiver, s.Signature))
+	}
+	want := []string{
+		"5:6 lsp.Client struct receiver= | type Client struct",
+		"6:5 lsp.Client.Closer field receiver=Client | Closer io.Closer", // 埋め込みフィールドは型名で呼ぶ
+		"7:3 lsp.Client.Conn field receiver=Client | Conn *Conn",
+		"8:2 lsp.Client.rootPath field receiver=Client | rootPath string",
+		"8:12 lsp.Client.name field receiver=Client | name string",
+		"11:6 lsp.NewClient function receiver= | func NewClient() *Client",
+		"13:18 lsp.Client.Close method receiver=Client | func (c *Client) Close() error",
+		// 関数本体の中の型や変数は含めない
+		"20:6 lsp.Conn struct receiver= | type Conn struct",
+		"22:13 lsp.Conn.Close method receiver=Conn | func (Conn) Close()",
+		"24:6 lsp.Provider interface receiver= | type Provider interface",
+		"26:2 lsp.Provider.Symbols method receiver=Provider | Symbols(query string) ([]string, error)", // 埋め込み interface は含めない
+		"29:6 lsp.List struct receiver= | type List struct",
+		"29:26 lsp.List.items field receiver=List | items []T",
+		"31:19 lsp.List.Len method receiver=List | func (l *List[T]) Len() int",
+		"33:18 lsp.List.At method receiver=List | func (l List[T]) At(i int) T",
+		"35:6 lsp.Pair struct receiver= | type Pair struct",
+		"37:21 lsp.Pair.Key method receiver=Pair | func (p Pair[K, V]) Key() K",
+		"39:7 lsp.Version constant receiver= | const Version",
+		"42:2 lsp.Default variable receiver= | var Default *Client",
+		"43:2 lsp.x variable receiver= | var x int",
+		"43:5 lsp.y variable receiver= | var y int",
+		"46:6 lsp.ID type receiver= | type ID int",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("fileSymbols =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestASTResolverFindSymbol(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                      "module example.com/m\n",
+		"internal/lsp/client.go":      testSource,
+		"internal/lsp/client_test.go": "package lsp\n\nfunc TestClose() {}\n",
+		"vendor/x/x.go":               "package x\n\nfunc Close() {}\n",
+		"broken.go":                   "package m\n\nfunc (",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locs, err := NewASTResolver(dir, nil).FindSymbol(context.Background(), "(*Client).Close")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	if len(locs) != 1 || locs[0] != (SymbolLocation{
+		FilePath: "internal/lsp/client.go", Line: 13, Character: 18,
+		Name: "lsp.Client.Close", Kind: KindMethod, Receiver: "Client",
+		Package: "example.com/m/internal/lsp", Signature: "func (c *Client) Close() error",
+	}) {
+		t.Errorf("FindSymbol = %+v", locs)
+	}
+
+	// テストファイル・vendor・解析できないファイルは見ない
+	locs, err = NewASTResolver(dir, nil).FindSymbol(context.Background(), "Close")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	var names []string
+	for _, l := range locs {
+		names = append(names, l.Name)
+	}
+	if want := []string{"lsp.Client.Close", "lsp.Conn.Close"}; !slices.Equal(names, want) {
+		t.Errorf("FindSymbol(Close) = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,298 @@
+package symbol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// indexVersion is bumped whenever the on-disk format or the set of collected
+// symbols changes, so stale caches are rebuilt instead of misread.
+const indexVersion = 2 // 2: 関数本体の中の宣言を索引しない
+
+// indexedFile is the cached symbol table of one source file.
+type indexedFile struct {
+	ModTime int64 // UnixNano
+	Size    int64
+	Hash    string // 内容の SHA-256（mtime だけ変わったファイルの再解析を避ける）
+	Package string
+	Symbols []indexedSymbol
+}
+
+// indexedSymbol is a SymbolLocation without the per-file fields, which keeps
+// the cache file small.
+type indexedSymbol struct {
+	Line, Character int
+	Name, Kind      string
+	Receiver        string
+	Signature       string
+}
+
+// indexData is what gets persisted to disk.
+type indexData struct {
+	Version    int
+	RootPath   string
+	ModulePath string
+	Files      map[string]*indexedFile // プロジェクトルートからの相対パスで引く
+}
+
+// Index is a persistent, incrementally updated symbol table of a project.
+// Update re-parses only files whose mtime/size and content hash changed, in
+// parallel, and saves the result so later reviews (and other processes) start
+// warm. An Index is safe for concurrent use.
+type Index struct {
+	rootPath  string
+	cachePath string // 空ならディスクに保存しない
+
+	updateMu sync.Mutex // Update を直列化する
+
+	mu     sync.RWMutex
+	data   *indexData
+	byName map[string][]SymbolLocation // シンボル自身の名前で引く
+}
+
+// OpenIndex loads the index of rootPath from cacheDir, or starts an empty one
+// if there is no usable cache. An empty cacheDir keeps the index in memory only.
+// Call Update before the first lookup.
+func OpenIndex(rootPath, cacheDir string) (*Index, error) {
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{rootPath: absRoot}
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return nil, fmt.Errorf("create index dir: %w", err)
+		}
+		sum := sha256.Sum256([]byte(absRoot))
+		idx.cachePath = filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".gob")
+	}
+
+	data := idx.load()
+	if data == nil {
+		data = &indexData{Version: indexVersion, RootPath: absRoot, Files: make(map[string]*indexedFile)}
+	}
+	idx.data = data
+	idx.byName = buildNameTable(data.Files)
+	return idx, nil
+}
+
+// load reads the cache file; a missing, corrupt or outdated cache yields nil.
+func (idx *Index) load() *indexData {
+	if idx.cachePath == "" {
+		return nil
+	}
+	f, err := os.Open(idx.cachePath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var data indexData
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		fmt.Fprintf(os.Stderr, "symbol: ignoring unreadable index %s: %v\n", idx.cachePath, err)
+		return nil
+	}
+	if data.Version != indexVersion || data.RootPath != idx.rootPath || data.Files == nil {
+		return nil
+	}
+	return &data
+}
+
+// save writes the index atomically (temp file + rename), so concurrent
+// readers never see a partial file.
+func (idx *Index) save(data *indexData) error {
+	if idx.cachePath == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idx.cachePath), filepath.Base(idx.cachePath)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), idx.cachePath)
+}
+
+// Update brings the index up to date with the working tree and persists it
+// if anything changed.
+func (idx *Index) Update(ctx context.Context) error {
+	idx.updateMu.Lock()
+	defer idx.updateMu.Unlock()
+
+	idx.mu.RLock()
+	old := idx.data
+	idx.mu.RUnlock()
+
+	next := &indexData{
+		Version:    indexVersion,
+		RootPath:   idx.rootPath,
+		ModulePath: readModulePath(idx.rootPath),
+		Files:      make(map[string]*indexedFile, len(old.Files)),
+	}
+	// モジュールパスが変わるとパッケージパスが全て変わるので作り直す
+	moduleChanged := next.ModulePath != old.ModulePath
+
+	type job struct {
+		relPath string
+		info    os.FileInfo
+		prev    *indexedFile
+	}
+	var jobs []job
+	err := walkSources(ctx, idx.rootPath, func(path, relPath string, d os.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		prev := old.Files[relPath]
+		if prev != nil && !moduleChanged && prev.ModTime == info.ModTime().UnixNano() && prev.Size == info.Size() {
+			next.Files[relPath] = prev
+			return nil
+		}
+		if moduleChanged {
+			prev = nil
+		}
+		jobs = append(jobs, job{relPath: relPath, info: info, prev: prev})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	changed := len(jobs) > 0 || len(next.Files) != len(old.Files)
+	if len(jobs) > 0 {
+		results := make([]*indexedFile, len(jobs))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+		for i, j := range jobs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if ctx.Err() != nil {
+					return
+				}
+				results[i] = indexFile(idx.rootPath, next.ModulePath, j.relPath, j.info, j.prev)
+			}()
+		}
+		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for i, j := range jobs {
+			if results[i] != nil {
+				next.Files[j.relPath] = results[i]
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	byName := buildNameTable(next.Files)
+	idx.mu.Lock()
+	idx.data = next
+	idx.byName = byName
+	idx.mu.Unlock()
+
+	if err := idx.save(next); err != nil {
+		// 保存できなくてもメモリ上の索引は使える
+		fmt.Fprintf(os.Stderr, "symbol: failed to save index %s: %v\n", idx.cachePath, err)
+	}
+	return nil
+}
+
+// indexFile parses one file. If the content is unchanged since prev, prev's
+// symbols are reused and only the stat fields are refreshed. Unreadable files
+// yield nil; unparsable ones are cached with no symbols until they change.
+func indexFile(rootPath, modulePath, relPath string, info os.FileInfo, prev *indexedFile) *indexedFile {
+	src, err := os.ReadFile(filepath.Join(rootPath, relPath))
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(src)
+	hash := hex.EncodeToString(sum[:])
+
+	entry := &indexedFile{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Hash: hash}
+	if prev != nil && prev.Hash == hash {
+		entry.Package, entry.Symbols = prev.Package, prev.Symbols
+		return entry
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(rootPath, relPath), src, parser.SkipObjectResolution)
+	if err != nil {
+		return entry
+	}
+	entry.Package = packagePath(modulePath, relPath)
+	for _, sym := range fileSymbols(fset, f, relPath, entry.Package) {
+		entry.Symbols = append(entry.Symbols, indexedSymbol{
+			Line:      sym.Line,
+			Character: sym.Character,
+			Name:      sym.Name,
+			Kind:      sym.Kind,
+			Receiver:  sym.Receiver,
+			Signature: sym.Signature,
+		})
+	}
+	return entry
+}
+
+func buildNameTable(files map[string]*indexedFile) map[string][]SymbolLocation {
+	byName := make(map[string][]SymbolLocation)
+	for relPath, f := range files {
+		for _, sym := range f.Symbols {
+			name := lastElem(sym.Name)
+			byName[name] = append(byName[name], SymbolLocation{
+				FilePath:  relPath,
+				Line:      sym.Line,
+				Character: sym.Character,
+				Name:      sym.Name,
+				Kind:      sym.Kind,
+				Receiver:  sym.Receiver,
+				Package:   f.Package,
+				Signature: sym.Signature,
+			})
+		}
+	}
+	return byName
+}
+
+// lookup returns the indexed symbols matching q, ordered by file and line.
+func (idx *Index) lookup(q query) []SymbolLocation {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []SymbolLocation
+	for _, sym := range idx.byName[q.name] {
+		if q.matches(sym) {
+			results = append(results, sym)
+		}
+	}
+	sortLocations(results)
+	return results
+}
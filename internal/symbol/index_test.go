@@ -0,0 +1,225 @@
+package symbol
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// t0 はテストのファイルの mtime です（書き込みの速さに左右されないよう固定する）
+var t0 = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeTree は files を root の下に書き、mtime を t0 に揃えます
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, t0, t0); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestIndex は小さなモジュールを作り、キャッシュ付きの索引を一度更新して返します
+func newTestIndex(t *testing.T) (idx *Index, root, cacheDir string) {
+	t.Helper()
+	root, cacheDir = t.TempDir(), t.TempDir()
+	writeTree(t, root, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"a.go":     "package m\n\nfunc Alpha() {}\n",
+		"sub/b.go": "package sub\n\nfunc Beta() {}\n",
+	})
+	idx = openIndex(t, root, cacheDir)
+	if err := idx.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	return idx, root, cacheDir
+}
+
+func openIndex(t *testing.T, root, cacheDir string) *Index {
+	t.Helper()
+	idx, err := OpenIndex(root, cacheDir)
+	if err != nil {
+		t.Fatalf("OpenIndex: %v", err)
+	}
+	return idx
+}
+
+// lookupOne は name に一致するシンボルがちょうど1つあることを確かめて返します
+func lookupOne(t *testing.T, idx *Index, name string) SymbolLocation {
+	t.Helper()
+	locs := idx.lookup(parseQuery(name))
+	if len(locs) != 1 {
+		t.Fatalf("lookup(%s) = %+v, want one symbol", name, locs)
+	}
+	return locs[0]
+}
+
+func TestIndexColdBuild(t *testing.T) {
+	idx, _, cacheDir := newTestIndex(t)
+
+	if got, want := lookupOne(t, idx, "Alpha"), (SymbolLocation{
+		FilePath: "a.go", Line: 3, Character: 6, Name: "m.Alpha", Kind: KindFunction,
+		Package: "example.com/m", Signature: "func Alpha()",
+	}); got != want {
+		t.Errorf("Alpha = %+v, want %+v", got, want)
+	}
+	if got := lookupOne(t, idx, "sub.Beta"); got.FilePath != filepath.Join("sub", "b.go") || got.Package != "example.com/m/sub" {
+		t.Errorf("Beta = %+v", got)
+	}
+	if entries, err := os.ReadDir(cacheDir); err != nil || len(entries) != 1 {
+		t.Errorf("cache dir has %d entries (%v), want the saved index only", len(entries), err)
+	}
+
+	// キャッシュなしの索引はメモリ上だけで動く
+	mem := openIndex(t, idx.rootPath, "")
+	if err := mem.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	lookupOne(t, mem, "Alpha")
+}
+
+func TestIndexWarmReopen(t *testing.T) {
+	_, root, cacheDir := newTestIndex(t)
+
+	// 大きさと mtime を変えずに中身を書き換える。再解析すれば Gamma が見える
+	writeTree(t, root, map[string]string{"a.go": "package m\n\nfunc Gamma() {}\n"})
+
+	idx := openIndex(t, root, cacheDir)
+	lookupOne(t, idx, "Alpha") // Update 前でもキャッシュから引ける
+	before := idx.data
+	if err := idx.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if idx.data != before {
+		t.Error("Update replaced the index although nothing changed")
+	}
+	lookupOne(t, idx, "Alpha")
+	if locs := idx.lookup(parseQuery("Gamma")); len(locs) != 0 {
+		t.Errorf("Gamma = %+v, want the file not to be re-parsed", locs)
+	}
+}
+
+func TestIndexReusesSymbolsByHash(t *testing.T) {
+	idx, root, cacheDir := newTestIndex(t)
+	prev := idx.data.Files["a.go"]
+
+	t1 := t0.Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "a.go"), t1, t1); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got := idx.data.Files["a.go"]
+	if got == prev || got.ModTime != t1.UnixNano() {
+		t.Errorf("a.go ModTime = %d, want the entry refreshed to %d", got.ModTime, t1.UnixNano())
+	}
+	// 内容が同じなので解析せず、前のシンボルをそのまま使う
+	if &got.Symbols[0] != &prev.Symbols[0] {
+		t.Error("a.go was re-parsed although its hash did not change")
+	}
+	if reopened := openIndex(t, root, cacheDir); reopened.data.Files["a.go"].ModTime != t1.UnixNano() {
+		t.Error("the refreshed mtime was not saved")
+	}
+}
+
+func TestIndexDropsDeletedFiles(t *testing.T) {
+	idx, root, cacheDir := newTestIndex(t)
+
+	if err := os.Remove(filepath.Join(root, "sub", "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if locs := idx.lookup(parseQuery("Beta")); len(locs) != 0 {
+		t.Errorf("Beta = %+v after its file was deleted", locs)
+	}
+	reopened := openIndex(t, root, cacheDir)
+	if _, ok := reopened.data.Files[filepath.Join("sub", "b.go")]; ok || len(reopened.data.Files) != 1 {
+		t.Errorf("saved files = %v, want a.go only", reopened.data.Files)
+	}
+}
+
+func TestIndexDiscardsStaleCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, idx *Index)
+	}{
+		{
+			name: "old version",
+			tamper: func(t *testing.T, idx *Index) {
+				stale := *idx.data
+				stale.Version = indexVersion - 1
+				if err := idx.save(&stale); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "other root",
+			tamper: func(t *testing.T, idx *Index) {
+				stale := *idx.data
+				stale.RootPath = "/elsewhere"
+				if err := idx.save(&stale); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "corrupt file",
+			tamper: func(t *testing.T, idx *Index) {
+				if err := os.WriteFile(idx.cachePath, []byte("not a gob"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, root, cacheDir := newTestIndex(t)
+			tt.tamper(t, idx)
+
+			reopened := openIndex(t, root, cacheDir)
+			if n := len(reopened.data.Files); n != 0 {
+				t.Fatalf("reopened index has %d files, want the cache discarded", n)
+			}
+			if err := reopened.Update(context.Background()); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			lookupOne(t, reopened, "Alpha")
+		})
+	}
+}
+
+func TestIndexModulePathChange(t *testing.T) {
+	idx, root, _ := newTestIndex(t)
+	prev := idx.data.Files["a.go"]
+
+	// .go ファイルは mtime も中身も変わらないが、パッケージパスが全て変わる
+	writeTree(t, root, map[string]string{"go.mod": "module example.com/renamed\n"})
+	if err := idx.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if got := lookupOne(t, idx, "Alpha"); got.Package != "example.com/renamed" {
+		t.Errorf("Alpha package = %q, want example.com/renamed", got.Package)
+	}
+	if got := lookupOne(t, idx, "renamed/sub.Beta"); got.Package != "example.com/renamed/sub" {
+		t.Errorf("Beta package = %q, want example.com/renamed/sub", got.Package)
+	}
+	if &idx.data.Files["a.go"].Symbols[0] == &prev.Symbols[0] {
+		t.Error("a.go was not re-parsed after the module path changed")
+	}
+}
@@ -0,0 +1,182 @@
+package symbol
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+var _ Resolver = (*LSPResolver)(nil)
+
+// maxFuzzyResults caps the candidates returned when nothing matches exactly.
+const maxFuzzyResults = 20
+
+// LSPResolver resolves symbol names with the language server's workspace/symbol
+// index. Unlike ASTResolver it does not re-parse the tree on every query, and it
+// accepts qualified names (lsp.Client.References, Client.References) as well as
+// fuzzy queries.
+type LSPResolver struct {
+	rootPath string
+	symbols  lsp.SymbolProvider
+}
+
+func NewLSPResolver(rootPath string, symbols lsp.SymbolProvider) *LSPResolver {
+	return &LSPResolver{rootPath: rootPath, symbols: symbols}
+}
+
+// FindSymbol returns the project symbols whose qualified name ends with name,
+// with their signatures. If there are none, it returns the server's closest
+// matches instead, marked Fuzzy.
+func (r *LSPResolver) FindSymbol(ctx context.Context, name string) ([]SymbolLocation, error) {
+	q := parseQuery(name)
+	infos, err := r.symbols.WorkspaceSymbols(ctx, q.raw)
+	if err != nil {
+		return nil, fmt.Errorf("workspace symbol %q: %w", name, err)
+	}
+
+	var exact, fuzzy []SymbolLocation
+	for _, info := range infos {
+		loc, ok := r.location(info)
+		if !ok {
+			continue
+		}
+		if q.matches(loc) {
+			exact = append(exact, loc)
+		} else if len(fuzzy) < maxFuzzyResults {
+			loc.Fuzzy = true
+			fuzzy = append(fuzzy, loc)
+		}
+	}
+
+	if len(exact) > 0 {
+		r.describe(ctx, exact)
+		return exact, nil
+	}
+	return fuzzy, nil
+}
+
+// describe fills in the signatures of locs from the textDocument/documentSymbol
+// results of their files. It is best effort: symbols of a file the server
+// cannot describe keep an empty signature.
+func (r *LSPResolver) describe(ctx context.Context, locs []SymbolLocation) {
+	files := make(map[string][]lsp.DocumentSymbol)
+	for i := range locs {
+		loc := &locs[i]
+		syms, ok := files[loc.FilePath]
+		if !ok {
+			// シグネチャは補助情報なので、取得できなくても検索結果は返す
+			syms, _ = r.symbols.DocumentSymbols(ctx, filepath.Join(r.rootPath, loc.FilePath))
+			files[loc.FilePath] = syms
+		}
+		if sym := findDocumentSymbol(syms, loc.Line-1, loc.Character-1); sym != nil {
+			loc.Signature = signatureOf(sym)
+		}
+	}
+}
+
+// findDocumentSymbol returns the symbol whose name starts at the 0-based position.
+func findDocumentSymbol(syms []lsp.DocumentSymbol, line, char int) *lsp.DocumentSymbol {
+	for i := range syms {
+		if start := syms[i].SelectionRange.Start; start.Line == line && start.Character == char {
+			return &syms[i]
+		}
+		if sym := findDocumentSymbol(syms[i].Children, line, char); sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// signatureOf renders a declaration from gopls' symbol detail, which holds
+// the type ("func(ctx context.Context) error", "struct{...}", "*exec.Cmd").
+// Method names come as "(*T).M" or "T.M".
+func signatureOf(sym *lsp.DocumentSymbol) string {
+	if sym.Detail == "" {
+		return ""
+	}
+	switch sym.Kind {
+	case lsp.SymbolFunction, lsp.SymbolMethod:
+		name := sym.Name
+		if recv, method, ok := strings.Cut(name, ")."); ok {
+			name = recv + ") " + method
+		} else if recv, method, ok := strings.Cut(name, "."); ok {
+			name = "(" + recv + ") " + method
+		}
+		return "func " + name + strings.TrimPrefix(sym.Detail, "func")
+	case lsp.SymbolStruct, lsp.SymbolInterface, lsp.SymbolClass:
+		return "type " + sym.Name + " " + sym.Detail
+	case lsp.SymbolField:
+		return sym.Name + " " + sym.Detail
+	case lsp.SymbolVariable:
+		return "var " + sym.Name + " " + sym.Detail
+	case lsp.SymbolConstant:
+		return "const " + sym.Name + " " + sym.Detail
+	default:
+		return ""
+	}
+}
+
+// location converts a workspace/symbol result; symbols outside the project
+// (standard library, dependencies) are skipped.
+func (r *LSPResolver) location(info lsp.SymbolInformation) (SymbolLocation, bool) {
+	filename := strings.TrimPrefix(info.Location.URI, "file://")
+	relPath, err := filepath.Rel(r.rootPath, filename)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return SymbolLocation{}, false
+	}
+
+	loc := SymbolLocation{
+		FilePath:  relPath,
+		Line:      info.Location.Range.Start.Line + 1,
+		Character: info.Location.Range.Start.Character + 1,
+		Name:      qualifiedName(info),
+		Kind:      kindOf(info.Kind),
+		Package:   info.ContainerName,
+	}
+	// pkg.Type.Member の Type がレシーバ
+	if parts := strings.Split(loc.Name, "."); len(parts) == 3 && (loc.Kind == KindMethod || loc.Kind == KindField) {
+		loc.Receiver = parts[1]
+	}
+	return loc, true
+}
+
+// qualifiedName normalizes the name to "pkg.Name" or "pkg.Type.Member".
+// gopls qualifies names differently depending on the query (Client.References,
+// lsp.Client.References, or the full import path).
+func qualifiedName(info lsp.SymbolInformation) string {
+	if info.ContainerName == "" {
+		return info.Name
+	}
+	pkg := path.Base(info.ContainerName)
+	name := strings.TrimPrefix(info.Name, info.ContainerName+".")
+	name = strings.TrimPrefix(name, pkg+".")
+	return pkg + "." + name
+}
+
+func kindOf(k lsp.SymbolKind) string {
+	switch k {
+	case lsp.SymbolFunction:
+		return KindFunction
+	case lsp.SymbolMethod:
+		return KindMethod
+	case lsp.SymbolStruct:
+		return KindStruct
+	case lsp.SymbolInterface:
+		return KindInterface
+	case lsp.SymbolClass, lsp.SymbolTypeParameter:
+		// gopls は struct・interface 以外の名前付き型を Class として返す
+		return KindType
+	case lsp.SymbolField:
+		return KindField
+	case lsp.SymbolVariable:
+		return KindVariable
+	case lsp.SymbolConstant:
+		return KindConstant
+	default:
+		return k.String()
+	}
+}
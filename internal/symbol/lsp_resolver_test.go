@@ -0,0 +1,183 @@
+package symbol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/0muji4/llm-reviewer/internal/lsp"
+)
+
+// stubSymbols answers workspace/symbol with a fixed list and
+// documentSymbol from a table keyed by absolute path.
+type stubSymbols struct {
+	workspace []lsp.SymbolInformation
+	documents map[string][]lsp.DocumentSymbol
+	err       error
+	queries   []string
+}
+
+func (s *stubSymbols) WorkspaceSymbols(_ context.Context, query string) ([]lsp.SymbolInformation, error) {
+	s.queries = append(s.queries, query)
+	return s.workspace, s.err
+}
+
+func (s *stubSymbols) DocumentSymbols(_ context.Context, filePath string) ([]lsp.DocumentSymbol, error) {
+	syms, ok := s.documents[filePath]
+	if !ok {
+		return nil, errors.New("no such document")
+	}
+	return syms, nil
+}
+
+// symbolInfo is a workspace/symbol result at a 1-based line in /project/path.
+func symbolInfo(name string, kind lsp.SymbolKind, container, path string, line int) lsp.SymbolInformation {
+	start := lsp.Position{Line: line - 1, Character: 5}
+	return lsp.SymbolInformation{
+		Name:          name,
+		Kind:          kind,
+		ContainerName: container,
+		Location:      lsp.Location{URI: "file://" + path, Range: lsp.Range{Start: start, End: start}},
+	}
+}
+
+func TestLSPResolverFindSymbol(t *testing.T) {
+	const pkg = "example.com/m/internal/lsp"
+	stub := &stubSymbols{
+		workspace: []lsp.SymbolInformation{
+			symbolInfo("Client.Close", lsp.SymbolMethod, pkg, "/project/internal/lsp/client.go", 100),
+			symbolInfo("lsp.Conn.Close", lsp.SymbolMethod, pkg, "/project/internal/lsp/jsonrpc.go", 20),
+			symbolInfo("Client.closed", lsp.SymbolField, pkg, "/project/internal/lsp/client.go", 30),
+			symbolInfo("io.Closer.Close", lsp.SymbolMethod, "io", "/usr/lib/go/src/io/io.go", 98),
+		},
+		documents: map[string][]lsp.DocumentSymbol{
+			"/project/internal/lsp/client.go": {{
+				Name: "Client", Kind: lsp.SymbolStruct, Detail: "struct{...}",
+				SelectionRange: lsp.Range{Start: lsp.Position{Line: 19, Character: 5}},
+				Children: []lsp.DocumentSymbol{{
+					Name: "(*Client).Close", Kind: lsp.SymbolMethod, Detail: "func() error",
+					SelectionRange: lsp.Range{Start: lsp.Position{Line: 99, Character: 5}},
+				}},
+			}},
+		},
+	}
+	r := NewLSPResolver("/project", stub)
+
+	got, err := r.FindSymbol(context.Background(), "(*Client).Close")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	want := []SymbolLocation{{
+		FilePath: "internal/lsp/client.go", Line: 100, Character: 6,
+		Name: "lsp.Client.Close", Kind: KindMethod, Receiver: "Client", Package: pkg,
+		Signature: "func (*Client) Close() error",
+	}}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindSymbol =\n%+v\nwant\n%+v", got, want)
+	}
+	if want := []string{"Client.Close"}; !slices.Equal(stub.queries, want) {
+		t.Errorf("workspace/symbol queries = %q, want %q", stub.queries, want)
+	}
+
+	// 完全一致がなければ候補を Fuzzy として返す。シグネチャは調べない。プロジェクト外は含めない
+	got, err = r.FindSymbol(context.Background(), "Clos")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	var names []string
+	for _, loc := range got {
+		if !loc.Fuzzy || loc.Signature != "" {
+			t.Errorf("%s: Fuzzy %v, Signature %q", loc.Name, loc.Fuzzy, loc.Signature)
+		}
+		names = append(names, loc.Name)
+	}
+	if want := []string{"lsp.Client.Close", "lsp.Conn.Close", "lsp.Client.closed"}; !slices.Equal(names, want) {
+		t.Errorf("fuzzy names = %v, want %v", names, want)
+	}
+
+	// 完全一致が1つでもあれば候補は返さない。documentSymbol に失敗してもシグネチャが空になるだけ
+	got, err = r.FindSymbol(context.Background(), "Conn.Close")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "lsp.Conn.Close" || got[0].Fuzzy || got[0].Signature != "" {
+		t.Errorf("FindSymbol(Conn.Close) = %+v", got)
+	}
+}
+
+func TestLSPResolverFuzzyCap(t *testing.T) {
+	stub := &stubSymbols{}
+	for i := range maxFuzzyResults + 5 {
+		stub.workspace = append(stub.workspace, symbolInfo(fmt.Sprintf("Handler%d", i), lsp.SymbolFunction, "example.com/m", "/project/h.go", i+1))
+	}
+	stub.workspace = append(stub.workspace, symbolInfo("Handle", lsp.SymbolFunction, "example.com/m", "/project/h.go", 200))
+	r := NewLSPResolver("/project", stub)
+
+	got, err := r.FindSymbol(context.Background(), "Handl")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	if len(got) != maxFuzzyResults || got[0].Name != "m.Handler0" || got[len(got)-1].Name != fmt.Sprintf("m.Handler%d", maxFuzzyResults-1) {
+		t.Errorf("got %d fuzzy results from %s to %s, want the first %d", len(got), got[0].Name, got[len(got)-1].Name, maxFuzzyResults)
+	}
+
+	// 上限を超えた後の完全一致も見落とさない
+	got, err = r.FindSymbol(context.Background(), "Handle")
+	if err != nil {
+		t.Fatalf("FindSymbol: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "m.Handle" || got[0].Fuzzy {
+		t.Errorf("FindSymbol(Handle) = %+v", got)
+	}
+}
+
+func TestLSPResolverError(t *testing.T) {
+	r := NewLSPResolver("/project", &stubSymbols{err: errors.New("gopls crashed")})
+	if _, err := r.FindSymbol(context.Background(), "X"); err == nil || err.Error() != `workspace symbol "X": gopls crashed` {
+		t.Errorf("FindSymbol error = %v", err)
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	tests := []struct {
+		name, container, want string
+	}{
+		{"Client.Close", "example.com/m/internal/lsp", "lsp.Client.Close"},
+		{"lsp.Client.Close", "example.com/m/internal/lsp", "lsp.Client.Close"},
+		{"example.com/m/internal/lsp.Client.Close", "example.com/m/internal/lsp", "lsp.Client.Close"},
+		{"NewClient", "example.com/m/internal/lsp", "lsp.NewClient"},
+		{"main", "", "main"},
+	}
+	for _, tt := range tests {
+		info := lsp.SymbolInformation{Name: tt.name, ContainerName: tt.container}
+		if got := qualifiedName(info); got != tt.want {
+			t.Errorf("qualifiedName(%q, %q) = %q, want %q", tt.name, tt.container, got, tt.want)
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		kind lsp.SymbolKind
+		want string
+	}{
+		{lsp.SymbolFunction, KindFunction},
+		{lsp.SymbolMethod, KindMethod},
+		{lsp.SymbolStruct, KindStruct},
+		{lsp.SymbolInterface, KindInterface},
+		{lsp.SymbolClass, KindType},
+		{lsp.SymbolTypeParameter, KindType},
+		{lsp.SymbolField, KindField},
+		{lsp.SymbolVariable, KindVariable},
+		{lsp.SymbolConstant, KindConstant},
+		{lsp.SymbolEnumMember, "enum member"},
+		{lsp.SymbolKind(99), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := kindOf(tt.kind); got != tt.want {
+			t.Errorf("kindOf(%d) = %q, want %q", tt.kind, got, tt.want)
+		}
+	}
+}
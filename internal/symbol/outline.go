@@ -0,0 +1,105 @@
+package symbol
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// KindImport is the OutlineEntry kind of an import declaration.
+const KindImport = "import"
+
+// maxOutlineNames caps how many names a var/const group entry lists.
+const maxOutlineNames = 5
+
+// OutlineEntry is a top-level declaration of a file and the lines it spans.
+type OutlineEntry struct {
+	Name      string // 関数名、T.Method、型名。var・const のグループは宣言する名前の列挙、import は空
+	Kind      string // Kind* のいずれか、または KindImport
+	StartLine int    // ドキュメントコメントがあればその先頭行
+	EndLine   int
+	Signature string // 関数本体や型の中身を除いた宣言
+}
+
+// Outline lists the top-level declarations of a Go source file in source
+// order. A file with syntax errors still yields the declarations that could be
+// parsed; an error is returned only if nothing could.
+func Outline(filename, src string) ([]OutlineEntry, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil {
+		return nil, err
+	}
+
+	span := func(doc *ast.CommentGroup, node ast.Node) (int, int) {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return fset.Position(start).Line, fset.Position(node.End()).Line
+	}
+
+	var entries []OutlineEntry
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			e := OutlineEntry{Name: d.Name.Name, Kind: KindFunction, Signature: funcSignature(fset, d)}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				e.Name, e.Kind = receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, KindMethod
+			}
+			e.StartLine, e.EndLine = span(d.Doc, d)
+			entries = append(entries, e)
+
+		case *ast.GenDecl:
+			switch d.Tok {
+			case token.IMPORT:
+				e := OutlineEntry{Kind: KindImport}
+				e.StartLine, e.EndLine = span(d.Doc, d)
+				entries = append(entries, e)
+
+			case token.TYPE:
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					e := OutlineEntry{Name: ts.Name.Name, Kind: KindType}
+					switch ts.Type.(type) {
+					case *ast.StructType:
+						e.Kind, e.Signature = KindStruct, "type "+ts.Name.Name+" struct"
+					case *ast.InterfaceType:
+						e.Kind, e.Signature = KindInterface, "type "+ts.Name.Name+" interface"
+					default:
+						e.Signature = "type " + ts.Name.Name + " " + exprString(fset, ts.Type)
+					}
+					// グループ化されていない宣言はドキュメントコメントが GenDecl 側に付く
+					if d.Lparen.IsValid() {
+						e.StartLine, e.EndLine = span(ts.Doc, ts)
+					} else {
+						e.StartLine, e.EndLine = span(d.Doc, d)
+					}
+					entries = append(entries, e)
+				}
+
+			case token.VAR, token.CONST:
+				e := OutlineEntry{Kind: KindVariable}
+				if d.Tok == token.CONST {
+					e.Kind = KindConstant
+				}
+				keyword := d.Tok.String() + " "
+				var names []string
+				for _, spec := range d.Specs {
+					for _, ident := range spec.(*ast.ValueSpec).Names {
+						names = append(names, ident.Name)
+					}
+				}
+				if len(names) > maxOutlineNames {
+					names = append(names[:maxOutlineNames], "...")
+				}
+				e.Name = strings.Join(names, ", ")
+				e.Signature = keyword + e.Name
+				e.StartLine, e.EndLine = span(d.Doc, d)
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
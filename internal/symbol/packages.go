@@ -0,0 +1,519 @@
+package symbol
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeChecker answers semantic questions that need type information.
+// Package arguments accept an import path (github.com/x/y/internal/lsp), a
+// directory relative to the project root (internal/lsp) or a package name (lsp).
+type TypeChecker interface {
+	// Implementers returns the project types that implement the named interface
+	// (Reader, io.Reader, lsp.CodeAnalyzer).
+	Implementers(ctx context.Context, iface string) ([]Implementer, error)
+	// TypeAt returns the type of the innermost expression at a 1-based position.
+	TypeAt(ctx context.Context, relPath string, line, char int) (*ExprType, error)
+	// ExportedAPI returns the exported API of a project package.
+	ExportedAPI(ctx context.Context, pkg string) ([]APIEntry, error)
+	// Importers returns the project packages that import pkg, directly or, if
+	// transitive is set, through other project packages.
+	Importers(ctx context.Context, pkg string, transitive bool) ([]string, error)
+}
+
+// Implementer is a type that satisfies an interface.
+type Implementer struct {
+	SymbolLocation
+	Pointer bool // *T だけが interface を満たす（ポインタレシーバのメソッドがある）
+}
+
+// ExprType describes the expression at a position.
+type ExprType struct {
+	Expr   string // 式のソース表現
+	Type   string // 式の型（パッケージ外の型はパッケージ名で修飾）
+	Object string // 識別子なら、その宣言（例: var x int）
+	Decl   string // 識別子の宣言位置（プロジェクトルートからの相対パス:行）。不明なら空
+}
+
+// APIEntry is one element of a package's exported API.
+type APIEntry struct {
+	Name      string // T, T.Method, T.Field など
+	Kind      string // Kind* のいずれか
+	Signature string
+
+	shape string // 引数名を除いた型。リビジョン間の比較に使う
+}
+
+var _ TypeChecker = (*PackageResolver)(nil)
+
+// packagesMode is what PackageResolver needs from go/packages. Dependencies are
+// type-checked from export data, so only the project's own packages are parsed.
+const packagesMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax | packages.NeedModule
+
+// PackageResolver type-checks the project with go/packages. Packages are
+// loaded once, on first use; create a new resolver to see later edits.
+type PackageResolver struct {
+	rootPath string
+
+	mu     sync.Mutex
+	loaded bool
+	pkgs   []*packages.Package
+	err    error
+}
+
+func NewPackageResolver(rootPath string) *PackageResolver {
+	return &PackageResolver{rootPath: rootPath}
+}
+
+// load type-checks ./... under the root. Packages with type errors are kept;
+// their partial type information is still useful.
+func (r *PackageResolver) load(ctx context.Context) ([]*packages.Package, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return r.pkgs, r.err
+	}
+
+	cfg := &packages.Config{
+		Context: ctx,
+		Dir:     r.rootPath,
+		Mode:    packagesMode,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		// キャンセルは一時的な失敗なので結果を記憶しない
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		err = fmt.Errorf("load packages: %w", err)
+	}
+	r.loaded, r.pkgs, r.err = true, pkgs, err
+	return r.pkgs, r.err
+}
+
+// findPackage resolves a package argument to a loaded project package.
+func findPackage(pkgs []*packages.Package, spec string) (*packages.Package, error) {
+	spec = strings.TrimSuffix(strings.TrimPrefix(spec, "./"), "/")
+	var byName []*packages.Package
+	for _, p := range pkgs {
+		if p.PkgPath == spec || strings.HasSuffix(p.PkgPath, "/"+spec) {
+			return p, nil
+		}
+		if p.Name == spec {
+			byName = append(byName, p)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return nil, fmt.Errorf("package %q not found in project", spec)
+	case 1:
+		return byName[0], nil
+	default:
+		paths := make([]string, len(byName))
+		for i, p := range byName {
+			paths[i] = p.PkgPath
+		}
+		return nil, fmt.Errorf("package name %q is ambiguous: %s", spec, strings.Join(paths, ", "))
+	}
+}
+
+// Implementers returns the named, non-generic project types (and interfaces)
+// that implement iface, ordered by location.
+func (r *PackageResolver) Implementers(ctx context.Context, iface string) ([]Implementer, error) {
+	pkgs, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target, err := lookupInterface(pkgs, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Implementer
+	for _, p := range pkgs {
+		if p.Types == nil {
+			continue
+		}
+		scope := p.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 || types.Identical(named.Underlying(), target) {
+				continue
+			}
+
+			impl := Implementer{}
+			switch {
+			case types.Implements(named, target):
+			case !types.IsInterface(named) && types.Implements(types.NewPointer(named), target):
+				impl.Pointer = true
+			default:
+				continue
+			}
+			impl.SymbolLocation = r.objectLocation(p, tn)
+			result = append(result, impl)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].SymbolLocation, result[j].SymbolLocation
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.Line < b.Line
+	})
+	return result, nil
+}
+
+// lookupInterface finds an interface by name in the project packages, in the
+// packages they import, or in the universe scope (error).
+func lookupInterface(pkgs []*packages.Package, name string) (*types.Interface, error) {
+	q := parseQuery(name)
+	qualifier, _, _ := strings.Cut(q.raw, ".")
+	if q.raw == q.name {
+		qualifier = ""
+	}
+
+	var candidates []types.Object
+	seen := make(map[*types.Package]bool)
+	consider := func(tp *types.Package) {
+		if tp == nil || seen[tp] {
+			return
+		}
+		seen[tp] = true
+		if qualifier != "" && tp.Name() != qualifier && tp.Path() != strings.TrimSuffix(q.raw, "."+q.name) {
+			return
+		}
+		if obj := tp.Scope().Lookup(q.name); obj != nil && types.IsInterface(obj.Type()) {
+			candidates = append(candidates, obj)
+		}
+	}
+	for _, p := range pkgs {
+		consider(p.Types)
+	}
+	if len(candidates) == 0 {
+		for _, p := range pkgs {
+			if p.Types == nil {
+				continue
+			}
+			for _, imp := range p.Types.Imports() {
+				consider(imp)
+			}
+		}
+	}
+	if len(candidates) == 0 && qualifier == "" {
+		if obj := types.Universe.Lookup(q.name); obj != nil && types.IsInterface(obj.Type()) {
+			candidates = append(candidates, obj)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("interface %q not found", name)
+	case 1:
+	default:
+		names := make([]string, len(candidates))
+		for i, c := range candidates {
+			names[i] = c.Pkg().Path() + "." + c.Name()
+		}
+		return nil, fmt.Errorf("interface %q is ambiguous: %s", name, strings.Join(names, ", "))
+	}
+
+	obj := candidates[0]
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("generic interface %q is not supported", name)
+	}
+	return obj.Type().Underlying().(*types.Interface), nil
+}
+
+// objectLocation converts a declared object to a SymbolLocation.
+func (r *PackageResolver) objectLocation(p *packages.Package, obj types.Object) SymbolLocation {
+	pos := p.Fset.Position(obj.Pos())
+	relPath, err := filepath.Rel(r.rootPath, pos.Filename)
+	if err != nil {
+		relPath = pos.Filename
+	}
+	kind := KindType
+	switch obj.Type().Underlying().(type) {
+	case *types.Struct:
+		kind = KindStruct
+	case *types.Interface:
+		kind = KindInterface
+	}
+	return SymbolLocation{
+		FilePath:  relPath,
+		Line:      pos.Line,
+		Character: pos.Column,
+		Name:      p.Name + "." + obj.Name(),
+		Kind:      kind,
+		Package:   p.PkgPath,
+		Signature: types.ObjectString(obj, types.RelativeTo(p.Types)),
+	}
+}
+
+// TypeAt finds the innermost typed expression enclosing the position.
+func (r *PackageResolver) TypeAt(ctx context.Context, relPath string, line, char int) (*ExprType, error) {
+	pkgs, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath := filepath.Join(r.rootPath, relPath)
+	for _, p := range pkgs {
+		for _, f := range p.Syntax {
+			tf := p.Fset.File(f.Pos())
+			if tf == nil || tf.Name() != absPath {
+				continue
+			}
+			if line < 1 || line > tf.LineCount() {
+				return nil, fmt.Errorf("line %d out of range (file has %d lines)", line, tf.LineCount())
+			}
+			pos := tf.LineStart(line) + token.Pos(max(char-1, 0))
+			return r.exprTypeAt(p, f, pos)
+		}
+	}
+	return nil, fmt.Errorf("%s is not part of a loaded package", relPath)
+}
+
+func (r *PackageResolver) exprTypeAt(p *packages.Package, f *ast.File, pos token.Pos) (*ExprType, error) {
+	qualifier := types.RelativeTo(p.Types)
+	path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+	for _, node := range path {
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			continue
+		}
+
+		if ident, ok := expr.(*ast.Ident); ok {
+			if obj := p.TypesInfo.ObjectOf(ident); obj != nil {
+				result := &ExprType{
+					Expr:   ident.Name,
+					Object: types.ObjectString(obj, qualifier),
+				}
+				if _, isPkg := obj.(*types.PkgName); !isPkg && obj.Type() != nil {
+					result.Type = types.TypeString(obj.Type(), qualifier)
+				}
+				if obj.Pos().IsValid() && obj.Pkg() != nil {
+					declPos := p.Fset.Position(obj.Pos())
+					if rel, err := filepath.Rel(r.rootPath, declPos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+						result.Decl = fmt.Sprintf("%s:%d", rel, declPos.Line)
+					}
+				}
+				return result, nil
+			}
+		}
+
+		if tv, ok := p.TypesInfo.Types[expr]; ok && tv.Type != nil {
+			return &ExprType{
+				Expr: types.ExprString(expr),
+				Type: types.TypeString(tv.Type, qualifier),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no typed expression at this position")
+}
+
+// ExportedAPI lists the exported API of a project package.
+func (r *PackageResolver) ExportedAPI(ctx context.Context, pkg string) ([]APIEntry, error) {
+	pkgs, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := findPackage(pkgs, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if p.Types == nil {
+		return nil, fmt.Errorf("package %s has no type information", p.PkgPath)
+	}
+	return ExportedAPI(p.Types), nil
+}
+
+// ExportedAPI lists the exported objects of pkg: functions, variables,
+// constants and types, plus the exported fields, methods and interface methods
+// of exported types. Entries are sorted by name.
+func ExportedAPI(pkg *types.Package) []APIEntry {
+	qualifier := types.RelativeTo(pkg)
+	var api []APIEntry
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Func:
+			api = append(api, APIEntry{Name: name, Kind: KindFunction, Signature: types.ObjectString(obj, qualifier), shape: shapeOf(obj.Type(), qualifier)})
+		case *types.Var:
+			api = append(api, APIEntry{Name: name, Kind: KindVariable, Signature: types.ObjectString(obj, qualifier), shape: shapeOf(obj.Type(), qualifier)})
+		case *types.Const:
+			sig := "const " + name + " " + types.TypeString(obj.Type(), qualifier)
+			api = append(api, APIEntry{Name: name, Kind: KindConstant, Signature: sig, shape: sig})
+		case *types.TypeName:
+			api = append(api, typeAPI(obj, qualifier)...)
+		}
+	}
+
+	sort.Slice(api, func(i, j int) bool { return api[i].Name < api[j].Name })
+	return api
+}
+
+// typeAPI describes an exported type and its exported members.
+func typeAPI(tn *types.TypeName, qualifier types.Qualifier) []APIEntry {
+	name := tn.Name()
+	if tn.IsAlias() {
+		sig := "type " + name + " = " + types.TypeString(tn.Type(), qualifier)
+		return []APIEntry{{Name: name, Kind: KindType, Signature: sig, shape: sig}}
+	}
+
+	var api []APIEntry
+	switch u := tn.Type().Underlying().(type) {
+	case *types.Struct:
+		sig := "type " + name + typeParams(tn, qualifier) + " struct"
+		api = append(api, APIEntry{Name: name, Kind: KindStruct, Signature: sig, shape: sig})
+		for i := range u.NumFields() {
+			f := u.Field(i)
+			if f.Exported() {
+				api = append(api, APIEntry{Name: name + "." + f.Name(), Kind: KindField, Signature: f.Name() + " " + types.TypeString(f.Type(), qualifier), shape: shapeOf(f.Type(), qualifier)})
+			}
+		}
+	case *types.Interface:
+		sig := "type " + name + typeParams(tn, qualifier) + " interface"
+		api = append(api, APIEntry{Name: name, Kind: KindInterface, Signature: sig, shape: sig})
+		for i := range u.NumMethods() {
+			m := u.Method(i)
+			if m.Exported() {
+				sig := strings.TrimPrefix(types.TypeString(m.Type(), qualifier), "func")
+				api = append(api, APIEntry{Name: name + "." + m.Name(), Kind: KindMethod, Signature: m.Name() + sig, shape: shapeOf(m.Type(), qualifier)})
+			}
+		}
+	default:
+		api = append(api, APIEntry{
+			Name:      name,
+			Kind:      KindType,
+			Signature: "type " + name + typeParams(tn, qualifier) + " " + types.TypeString(u, qualifier),
+			shape:     "type " + name + typeParams(tn, qualifier) + " " + shapeOf(u, qualifier),
+		})
+	}
+
+	if named, ok := tn.Type().(*types.Named); ok && !types.IsInterface(named) {
+		for i := range named.NumMethods() {
+			m := named.Method(i)
+			if m.Exported() {
+				api = append(api, APIEntry{Name: name + "." + m.Name(), Kind: KindMethod, Signature: types.ObjectString(m, qualifier), shape: shapeOf(m.Type(), qualifier)})
+			}
+		}
+	}
+	return api
+}
+
+// shapeOf renders t with the parameter and result names of a function type
+// dropped, since renaming them does not change the API. A function keeps its
+// type parameters with their constraints, and a method its receiver type, so
+// that tightening a constraint or switching between value and pointer
+// receivers shows up as a change.
+func shapeOf(t types.Type, qualifier types.Qualifier) string {
+	sig, ok := t.(*types.Signature)
+	if !ok {
+		return types.TypeString(t, qualifier)
+	}
+	unnamed := func(tuple *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, tuple.Len())
+		for i := range vars {
+			vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+
+	var b strings.Builder
+	if recv := sig.Recv(); recv != nil && !types.IsInterface(recv.Type()) {
+		// ポインタレシーバのメソッドは値のメソッドセットに含まれないので、実装側の互換性に関わる。
+		// interface のメソッドのレシーバは埋め込み元によって変わるだけなので含めない
+		fmt.Fprintf(&b, "(%s) ", types.TypeString(recv.Type(), qualifier))
+	}
+	if tps := sig.TypeParams(); tps.Len() > 0 {
+		params := make([]string, tps.Len())
+		for i := range params {
+			tp := tps.At(i)
+			params[i] = tp.Obj().Name() + " " + types.TypeString(tp.Constraint(), qualifier)
+		}
+		b.WriteString("[" + strings.Join(params, ", ") + "]")
+	}
+	stripped := types.NewSignatureType(nil, nil, nil, unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic())
+	b.WriteString(types.TypeString(stripped, qualifier))
+	return b.String()
+}
+
+func typeParams(tn *types.TypeName, qualifier types.Qualifier) string {
+	named, ok := tn.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() == 0 {
+		return ""
+	}
+	params := make([]string, named.TypeParams().Len())
+	for i := range params {
+		tp := named.TypeParams().At(i)
+		params[i] = tp.Obj().Name() + " " + types.TypeString(tp.Constraint(), qualifier)
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// Importers returns the import paths of project packages importing pkg. pkg
+// may also be a package outside the project (net/http), given by import path.
+func (r *PackageResolver) Importers(ctx context.Context, pkg string, transitive bool) ([]string, error) {
+	pkgs, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := pkg
+	if p, err := findPackage(pkgs, pkg); err == nil {
+		target = p.PkgPath
+	}
+
+	// 逆向きの依存グラフ（import される側 -> import する側）
+	importers := make(map[string][]string)
+	for _, p := range pkgs {
+		for path := range p.Imports {
+			importers[path] = append(importers[path], p.PkgPath)
+		}
+	}
+	if _, ok := importers[target]; !ok {
+		if _, err := findPackage(pkgs, pkg); err != nil {
+			return nil, fmt.Errorf("package %q is neither in the project nor imported by it", pkg)
+		}
+	}
+
+	seen := map[string]bool{target: true}
+	var result []string
+	queue := []string{target}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, imp := range importers[next] {
+			if seen[imp] {
+				continue
+			}
+			seen[imp] = true
+			result = append(result, imp)
+			if transitive {
+				queue = append(queue, imp)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
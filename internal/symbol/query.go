@@ -0,0 +1,53 @@
+package symbol
+
+import "strings"
+
+// query is a parsed FindSymbol argument.
+type query struct {
+	raw  string // "(*T).M" を "T.M" に正規化したもの
+	name string // 最後の要素（シンボル自身の名前）
+}
+
+// receiverDecoration strips the parentheses and pointer of "(*Type).Method".
+var receiverDecoration = strings.NewReplacer("(", "", ")", "", "*", "")
+
+// parseQuery normalizes Name, Type.Member, (*Type).Method, pkg.Name and
+// import/path.Type.Member.
+func parseQuery(s string) query {
+	s = strings.TrimSpace(s)
+	s = receiverDecoration.Replace(s)
+	return query{raw: s, name: lastElem(s)}
+}
+
+// lastElem returns the part of a qualified name after the last dot.
+func lastElem(qualified string) string {
+	if i := strings.LastIndex(qualified, "."); i >= 0 {
+		return qualified[i+1:]
+	}
+	return qualified
+}
+
+// matches reports whether sym is what the query names. Each qualifier in the
+// query must match the symbol's package or receiver exactly.
+func (q query) matches(sym SymbolLocation) bool {
+	if q.raw == "" || !strings.HasSuffix(sym.Name, q.name) {
+		return false
+	}
+	if sym.Name == q.raw || strings.HasSuffix(sym.Name, "."+q.raw) {
+		return true
+	}
+
+	// インポートパスで修飾されたクエリ（internal/lsp.Client.Close など）
+	if sym.Package == "" || !strings.Contains(q.raw, "/") {
+		return false
+	}
+	local := strings.TrimPrefix(sym.Name, packageName(sym.Name)+".")
+	full := sym.Package + "." + local
+	return full == q.raw || strings.HasSuffix(full, "/"+q.raw)
+}
+
+// packageName returns the first element of a qualified "pkg.Name".
+func packageName(qualified string) string {
+	pkg, _, _ := strings.Cut(qualified, ".")
+	return pkg
+}
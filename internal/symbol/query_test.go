@@ -0,0 +1,77 @@
+package symbol
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want query
+	}{
+		{"Close", query{raw: "Close", name: "Close"}},
+		{" Client.Close ", query{raw: "Client.Close", name: "Close"}},
+		{"(*Client).Close", query{raw: "Client.Close", name: "Close"}},
+		{"(Client).Close", query{raw: "Client.Close", name: "Close"}},
+		{"lsp.(*Client).Close", query{raw: "lsp.Client.Close", name: "Close"}},
+		{"example.com/m/internal/lsp.Client", query{raw: "example.com/m/internal/lsp.Client", name: "Client"}},
+		{"", query{}},
+	}
+	for _, tt := range tests {
+		if got := parseQuery(tt.in); got != tt.want {
+			t.Errorf("parseQuery(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	symbols := parseTestSource(t)
+
+	tests := []struct {
+		query string
+		want  []string // 一致するシンボルの Name
+	}{
+		{"Close", []string{"lsp.Client.Close", "lsp.Conn.Close"}},
+		{"Client.Close", []string{"lsp.Client.Close"}},
+		{"(*Client).Close", []string{"lsp.Client.Close"}},
+		{"lsp.Client.Close", []string{"lsp.Client.Close"}},
+		{"lsp.(*Client).Close", []string{"lsp.Client.Close"}},
+		{"internal/lsp.Client.Close", []string{"lsp.Client.Close"}},
+		{"example.com/m/internal/lsp.Client.Close", []string{"lsp.Client.Close"}},
+		{"m/internal/lsp.NewClient", []string{"lsp.NewClient"}},
+		{"other/lsp.Client.Close", nil},
+		{"al/lsp.Client.Close", nil}, // パスの途中からは一致しない
+		{"ient.Close", nil},          // 修飾子は要素単位で一致する
+		{"lsp.Close", nil},
+		{"Client", []string{"lsp.Client"}},
+		{"lsp.Client", []string{"lsp.Client"}},
+		// フィールドと埋め込みフィールド
+		{"Client.rootPath", []string{"lsp.Client.rootPath"}},
+		{"Client.Conn", []string{"lsp.Client.Conn"}},
+		{"Conn", []string{"lsp.Client.Conn", "lsp.Conn"}},
+		// interface のメソッド
+		{"Provider.Symbols", []string{"lsp.Provider.Symbols"}},
+		{"Provider.Reader", nil},
+		// 型パラメータを持つレシーバー
+		{"(*List).Len", []string{"lsp.List.Len"}},
+		{"List.At", []string{"lsp.List.At"}},
+		{"Pair.Key", []string{"lsp.Pair.Key"}},
+		{"Version", []string{"lsp.Version"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := parseQuery(tt.query)
+			var got []string
+			for _, sym := range symbols {
+				if q.matches(sym) {
+					got = append(got, sym.Name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matches %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
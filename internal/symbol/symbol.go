@@ -1,13 +1,34 @@
 package symbol
 
+import "context"
+
+// Kinds reported in SymbolLocation.Kind.
+const (
+	KindFunction  = "function"
+	KindMethod    = "method"
+	KindStruct    = "struct"
+	KindInterface = "interface"
+	KindType      = "type" // struct・interface 以外の型定義
+	KindField     = "field"
+	KindVariable  = "variable"
+	KindConstant  = "constant"
+	KindPackage   = "package" // 公開 API の比較でパッケージ全体の追加・削除を表す
+)
+
 // SymbolLocation represents where a symbol is defined.
 type SymbolLocation struct {
 	FilePath  string // プロジェクトルートからの相対パス
 	Line      int    // 1-based
 	Character int    // 1-based
+	Name      string // 分かる場合は修飾名（例: lsp.Client.References）
+	Kind      string // Kind* のいずれか。不明なら空
+	Receiver  string // メソッド・フィールドが属する型名（ポインタは外す）
+	Package   string // パッケージのインポートパス
+	Signature string // 宣言のシグネチャ（例: func (c *Client) Close() error）。不明なら空
+	Fuzzy     bool   // 名前が完全には一致せず、近い候補として返されたもの
 }
 
 // Resolver finds symbol definitions by name.
 type Resolver interface {
-	FindSymbol(name string) ([]SymbolLocation, error)
+	FindSymbol(ctx context.Context, name string) ([]SymbolLocation, error)
 }
@@ -0,0 +1,382 @@
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileStatus describes how a file changed in a diff.
+type FileStatus string
+
+const (
+	FileModified FileStatus = "modified"
+	FileAdded    FileStatus = "added"
+	FileDeleted  FileStatus = "deleted"
+	FileRenamed  FileStatus = "renamed"
+	FileCopied   FileStatus = "copied"
+)
+
+// LineKind classifies a line inside a hunk.
+type LineKind byte
+
+const (
+	LineContext LineKind = ' '
+	LineAdded   LineKind = '+'
+	LineDeleted LineKind = '-'
+)
+
+// DiffLine is one line of a hunk. OldLine is 0 for added lines and NewLine
+// is 0 for deleted lines.
+type DiffLine struct {
+	Kind    LineKind
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+// Hunk is a contiguous block of changes.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string // @@ 行の後ろに付く関数名などの見出し
+	Lines    []DiffLine
+}
+
+// Header returns the hunk's "@@ -a,b +c,d @@" line.
+func (h *Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// FileDiff is the parsed diff of a single file.
+type FileDiff struct {
+	OldPath string // 追加されたファイルでは空
+	NewPath string // 削除されたファイルでは空
+	Status  FileStatus
+	Binary  bool
+	Header  []string // "diff --git" から "+++" までの生のヘッダ行
+	Hunks   []Hunk
+}
+
+// Path returns the path the file has after the change, or before it for deletions.
+func (f *FileDiff) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Stats returns the number of added and deleted lines.
+func (f *FileDiff) Stats() (added, deleted int) {
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded:
+				added++
+			case LineDeleted:
+				deleted++
+			}
+		}
+	}
+	return added, deleted
+}
+
+// LineRange is an inclusive range of 1-based line numbers.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// HunkRanges returns the new-side line ranges the hunks cover, including
+// their context lines, merged where they overlap or abut. A hunk that only
+// deletes lines covers the lines on either side of the deletion.
+func (f *FileDiff) HunkRanges() []LineRange {
+	var ranges []LineRange
+	for _, h := range f.Hunks {
+		r := LineRange{Start: h.NewStart, End: h.NewStart + h.NewLines - 1}
+		if h.NewLines == 0 {
+			// 削除だけのハンクの NewStart は削除位置の直前の行（先頭なら0）
+			r = LineRange{Start: max(h.NewStart, 1), End: h.NewStart + 1}
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 >= r.Start {
+			ranges[n-1].End = max(ranges[n-1].End, r.End)
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// Touches reports whether the new-side line range [start, end] overlaps a
+// hunk (see HunkRanges). A start of 0 means "the whole file". A deleted
+// file has no new side, so any range of it counts.
+func (f *FileDiff) Touches(start, end int) bool {
+	if start <= 0 || f.Status == FileDeleted {
+		return len(f.Hunks) > 0 || f.Binary
+	}
+	end = max(end, start)
+	for _, r := range f.HunkRanges() {
+		if r.Start <= end && start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// String re-renders the file's diff in unified format.
+func (f *FileDiff) String() string {
+	var b strings.Builder
+	for _, h := range f.Header {
+		b.WriteString(h)
+		b.WriteByte('\n')
+	}
+	for _, h := range f.Hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			b.WriteByte(byte(l.Kind))
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Diff is a parsed multi-file unified diff.
+type Diff []*FileDiff
+
+// File returns the diff of the file at path (matched against both sides), or nil.
+func (d Diff) File(path string) *FileDiff {
+	path = strings.TrimPrefix(path, "./")
+	for _, f := range d {
+		if f.NewPath == path || f.OldPath == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// Touches reports whether lines [start, end] of path fall inside a changed hunk.
+func (d Diff) Touches(path string, start, end int) bool {
+	f := d.File(path)
+	return f != nil && f.Touches(start, end)
+}
+
+// ParseDiff parses the output of git diff (unified format, as produced with
+// the default "a/" and "b/" prefixes).
+func ParseDiff(text string) (Diff, error) {
+	var (
+		diff Diff
+		file *FileDiff
+		hunk *Hunk
+		// 現在のハンクで残っている行数
+		oldLeft, newLeft int
+		oldLine, newLine int
+	)
+
+	flushHunk := func() {
+		if hunk != nil {
+			file.Hunks = append(file.Hunks, *hunk)
+			hunk = nil
+		}
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		line := sc.Text()
+		lineNo++
+
+		// ハンク本文
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				// 末尾空白が削られた空のコンテキスト行
+				line = " "
+			}
+			kind := LineKind(line[0])
+			dl := DiffLine{Kind: kind, Text: line[1:]}
+			switch kind {
+			case LineContext:
+				dl.OldLine, dl.NewLine = oldLine, newLine
+				oldLine++
+				newLine++
+				oldLeft--
+				newLeft--
+			case LineDeleted:
+				dl.OldLine = oldLine
+				oldLine++
+				oldLeft--
+			case LineAdded:
+				dl.NewLine = newLine
+				newLine++
+				newLeft--
+			case '\\':
+				// "\ No newline at end of file"
+				continue
+			default:
+				return nil, fmt.Errorf("diff line %d: unexpected line in hunk: %q", lineNo, line)
+			}
+			hunk.Lines = append(hunk.Lines, dl)
+			continue
+		}
+		if strings.HasPrefix(line, `\`) {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if file != nil {
+				flushHunk()
+			}
+			file = &FileDiff{Status: FileModified}
+			file.OldPath, file.NewPath = parseGitHeaderPaths(strings.TrimPrefix(line, "diff --git "))
+			file.Header = append(file.Header, line)
+			diff = append(diff, file)
+
+		case file == nil:
+			// "diff --git" より前の行（コミットメッセージ等）は無視する
+
+		case strings.HasPrefix(line, "@@ "):
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("diff line %d: %w", lineNo, err)
+			}
+			hunk = h
+			oldLeft, newLeft = h.OldLines, h.NewLines
+			oldLine, newLine = h.OldStart, h.NewStart
+
+		case hunk == nil:
+			file.Header = append(file.Header, line)
+			parseExtendedHeader(file, line)
+
+		default:
+			return nil, fmt.Errorf("diff line %d: unexpected line after hunk: %q", lineNo, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read diff: %w", err)
+	}
+	if file != nil {
+		flushHunk()
+	}
+	return diff, nil
+}
+
+func parseExtendedHeader(f *FileDiff, line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode"):
+		f.Status = FileAdded
+		f.OldPath = ""
+	case strings.HasPrefix(line, "deleted file mode"):
+		f.Status = FileDeleted
+		f.NewPath = ""
+	case strings.HasPrefix(line, "rename from "):
+		f.Status = FileRenamed
+		f.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		f.Status = FileRenamed
+		f.NewPath = unquotePath(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy from "):
+		f.Status = FileCopied
+		f.OldPath = unquotePath(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		f.Status = FileCopied
+		f.NewPath = unquotePath(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+		f.Binary = true
+	case strings.HasPrefix(line, "--- "):
+		if p := stripPrefix(strings.TrimPrefix(line, "--- "), "a/"); p != "" {
+			f.OldPath = p
+		}
+	case strings.HasPrefix(line, "+++ "):
+		if p := stripPrefix(strings.TrimPrefix(line, "+++ "), "b/"); p != "" {
+			f.NewPath = p
+		}
+	}
+}
+
+// parseGitHeaderPaths splits "a/x b/y" from a "diff --git" line. Paths that
+// contain " b/" are ambiguous; the ---/+++ or rename lines correct them later.
+func parseGitHeaderPaths(s string) (oldPath, newPath string) {
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `" `); end >= 0 {
+			return stripPrefix(s[:end+2], "a/"), stripPrefix(s[end+3:], "b/")
+		}
+	}
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return stripPrefix(s[:i], "a/"), stripPrefix(s[i+1:], "b/")
+	}
+	return "", ""
+}
+
+// stripPrefix removes git's a/ or b/ prefix; /dev/null becomes "".
+func stripPrefix(p, prefix string) string {
+	p = unquotePath(strings.TrimRight(p, "\t"))
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+func unquotePath(p string) string {
+	if strings.HasPrefix(p, `"`) {
+		if s, err := strconv.Unquote(p); err == nil {
+			return s
+		}
+	}
+	return p
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@ section".
+func parseHunkHeader(line string) (*Hunk, error) {
+	rest := strings.TrimPrefix(line, "@@ ")
+	end := strings.Index(rest, " @@")
+	if end < 0 {
+		return nil, fmt.Errorf("malformed hunk header %q", line)
+	}
+	ranges := strings.Fields(rest[:end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return nil, fmt.Errorf("malformed hunk header %q", line)
+	}
+
+	h := &Hunk{Section: strings.TrimSpace(rest[end+3:])}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(ranges[0][1:]); err != nil {
+		return nil, fmt.Errorf("hunk header %q: %w", line, err)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(ranges[1][1:]); err != nil {
+		return nil, fmt.Errorf("hunk header %q: %w", line, err)
+	}
+	return h, nil
+}
+
+// parseRange parses "start,count" or "start" (count defaults to 1).
+func parseRange(s string) (start, count int, err error) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
@@ -0,0 +1,238 @@
+package workspace
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `commit message lines before the diff are ignored
+diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,5 @@ package main
+ package main
+-import "fmt"
++import (
++	"fmt"
++)
+
+\ No newline at end of file
+@@ -10 +11,0 @@ func main() {
+-	fmt.Println("bye")
+diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package main
++
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 4444444..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/a.go b/b.go
+similarity index 90%
+rename from a.go
+rename to b.go
+diff --git a/logo.png b/logo.png
+index 5555555..6666666 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git "a/with space.go" "b/with space.go"
+index 7777777..8888888 100644
+--- "a/with space.go"
++++ "b/with space.go"
+@@ -2 +2 @@
+-x
++y
+`
+
+func TestParseDiff(t *testing.T) {
+	diff, err := ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatalf("ParseDiff: %v", err)
+	}
+
+	type file struct {
+		oldPath, newPath string
+		status           FileStatus
+		binary           bool
+		hunks            int
+		added, deleted   int
+	}
+	want := []file{
+		{"main.go", "main.go", FileModified, false, 2, 3, 2},
+		{"", "new.go", FileAdded, false, 1, 2, 0},
+		{"old.go", "", FileDeleted, false, 1, 0, 1},
+		{"a.go", "b.go", FileRenamed, false, 0, 0, 0},
+		{"logo.png", "logo.png", FileModified, true, 0, 0, 0},
+		{"with space.go", "with space.go", FileModified, false, 1, 1, 1},
+	}
+	var got []file
+	for _, f := range diff {
+		added, deleted := f.Stats()
+		got = append(got, file{f.OldPath, f.NewPath, f.Status, f.Binary, len(f.Hunks), added, deleted})
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("files =\n%+v\nwant\n%+v", got, want)
+	}
+
+	main := diff.File("./main.go")
+	if main == nil {
+		t.Fatal(`File("./main.go") = nil`)
+	}
+	h := main.Hunks[0]
+	if h.Header() != "@@ -1,3 +1,5 @@ package main" {
+		t.Errorf("Header = %q", h.Header())
+	}
+	wantLines := []DiffLine{
+		{LineContext, 1, 1, "package main"},
+		{LineDeleted, 2, 0, `import "fmt"`},
+		{LineAdded, 0, 2, "import ("},
+		{LineAdded, 0, 3, "\t\"fmt\""},
+		{LineAdded, 0, 4, ")"},
+		{LineContext, 3, 5, ""},
+	}
+	if !slices.Equal(h.Lines, wantLines) {
+		t.Errorf("lines =\n%+v\nwant\n%+v", h.Lines, wantLines)
+	}
+	if h := main.Hunks[1]; h.OldStart != 10 || h.OldLines != 1 || h.NewStart != 11 || h.NewLines != 0 {
+		t.Errorf("deletion hunk = %+v", h)
+	}
+
+	if diff.File("a.go") != diff.File("b.go") || diff.File("b.go") == nil {
+		t.Error("renamed file is not found by both paths")
+	}
+}
+
+func TestParseDiffRoundTrip(t *testing.T) {
+	text := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@ package main
+ package main
+-var x = 1
++var x = 2
+`
+	diff, err := ParseDiff(text)
+	if err != nil {
+		t.Fatalf("ParseDiff: %v", err)
+	}
+	if got := diff[0].String(); got != text {
+		t.Errorf("String =\n%s\nwant\n%s", got, text)
+	}
+}
+
+func TestParseDiffErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "malformed hunk header",
+			text: "diff --git a/x b/x\n@@ -1 +1\n",
+			want: "malformed hunk header",
+		},
+		{
+			name: "bad range",
+			text: "diff --git a/x b/x\n@@ -a,1 +1 @@\n",
+			want: "hunk header",
+		},
+		{
+			name: "unexpected line in hunk",
+			text: "diff --git a/x b/x\n@@ -1 +1 @@\n?x\n",
+			want: "unexpected line in hunk",
+		},
+		{
+			name: "trailing line after hunk",
+			text: "diff --git a/x b/x\n@@ -1 +1 @@\n-a\n+b\n+c\n",
+			want: "unexpected line after hunk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDiff(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseDiff error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunkRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		hunks []Hunk
+		want  []LineRange
+	}{
+		{
+			name:  "context lines are included",
+			hunks: []Hunk{{NewStart: 10, NewLines: 7}},
+			want:  []LineRange{{10, 16}},
+		},
+		{
+			name:  "separate hunks",
+			hunks: []Hunk{{NewStart: 1, NewLines: 3}, {NewStart: 20, NewLines: 2}},
+			want:  []LineRange{{1, 3}, {20, 21}},
+		},
+		{
+			name:  "abutting hunks merge",
+			hunks: []Hunk{{NewStart: 1, NewLines: 3}, {NewStart: 4, NewLines: 2}},
+			want:  []LineRange{{1, 5}},
+		},
+		{
+			name:  "deletion covers the lines around it",
+			hunks: []Hunk{{NewStart: 7, NewLines: 0}},
+			want:  []LineRange{{7, 8}},
+		},
+		{
+			name:  "deletion at the top of the file",
+			hunks: []Hunk{{NewStart: 0, NewLines: 0}},
+			want:  []LineRange{{1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &FileDiff{Hunks: tt.hunks}
+			if got := f.HunkRanges(); !slices.Equal(got, tt.want) {
+				t.Errorf("HunkRanges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTouches(t *testing.T) {
+	diff, err := ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatalf("ParseDiff: %v", err)
+	}
+
+	tests := []struct {
+		path       string
+		start, end int
+		want       bool
+	}{
+		{"main.go", 3, 3, true},
+		{"main.go", 0, 0, true}, // ファイル全体
+		{"main.go", 6, 10, false},
+		{"main.go", 6, 11, true}, // 削除だけのハンクの直後の行
+		{"main.go", 4, 0, true},  // end が start より前なら1行
+		{"main.go", 13, 20, false},
+		{"old.go", 50, 60, true}, // 削除されたファイルは新しい側の行を持たない
+		{"logo.png", 0, 0, true},
+		{"b.go", 0, 0, false}, // 内容の変わらない名前の変更
+		{"other.go", 0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := diff.Touches(tt.path, tt.start, tt.end); got != tt.want {
+			t.Errorf("Touches(%q, %d, %d) = %v, want %v", tt.path, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
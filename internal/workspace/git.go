@@ -1,7 +1,12 @@
 package workspace
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 var _ DiffProvider = (*GitDiff)(nil)
@@ -15,13 +20,187 @@ func NewGitDiff(rootPath string) *GitDiff {
 	return &GitDiff{rootPath: rootPath}
 }
 
-func (g *GitDiff) Diff() (string, error) {
-	cmd := exec.Command("git", "diff", "HEAD")
+// Diff returns the unified diff selected by opts. Paths in it are relative
+// to the root path, and changes outside the root are left out.
+func (g *GitDiff) Diff(opts DiffOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	base := opts.Base
+	if opts.MergeBase != "" {
+		mb, err := g.git("merge-base", opts.MergeBase, "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("merge-base %s: %w", opts.MergeBase, err)
+		}
+		base = strings.TrimSpace(mb)
+	}
+
+	// 利用者の git 設定（color.diff=always、外部 diff ツール、diff.noprefix など）に出力形式を左右されないよう固定する。
+	// --relative でパスをルートからの相対にし、ルートがリポジトリのサブディレクトリでもプロジェクト内のファイルだけを対象にする
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--relative"}
+	switch {
+	case opts.Range != "":
+		args = append(args, opts.Range)
+	case opts.Mode == DiffModeStaged:
+		args = append(args, "--cached", orHEAD(base))
+	case opts.Mode == DiffModeUnstaged:
+		// 作業ツリーとインデックスの比較なので比較元は取らない
+	default:
+		args = append(args, orHEAD(base))
+	}
+	args = append(args, "--")
+	args = append(args, opts.Paths...)
+
+	return g.git(args...)
+}
+
+// Revisions returns the two revisions a diff compares. An empty head means
+// the working tree. Staged and unstaged diffs have the index on one side,
+// which is not a revision, so they are rejected.
+func (g *GitDiff) Revisions(opts DiffOptions) (base, head string, err error) {
+	if err := opts.Validate(); err != nil {
+		return "", "", err
+	}
+	if opts.Mode != DiffModeAll {
+		return "", "", fmt.Errorf("%s mode compares against the index, which has no revision", opts.Mode)
+	}
+
+	if opts.Range != "" {
+		from, to, symmetric := strings.Cut(opts.Range, "...")
+		if !symmetric {
+			from, to, _ = strings.Cut(opts.Range, "..")
+		}
+		from, to = orHEAD(from), orHEAD(to)
+		if !symmetric {
+			return from, to, nil
+		}
+		mb, err := g.git("merge-base", from, to)
+		if err != nil {
+			return "", "", fmt.Errorf("merge-base %s %s: %w", from, to, err)
+		}
+		return strings.TrimSpace(mb), to, nil
+	}
+
+	if opts.MergeBase != "" {
+		mb, err := g.git("merge-base", opts.MergeBase, "HEAD")
+		if err != nil {
+			return "", "", fmt.Errorf("merge-base %s: %w", opts.MergeBase, err)
+		}
+		return strings.TrimSpace(mb), "", nil
+	}
+	return orHEAD(opts.Base), "", nil
+}
+
+// Checkout checks rev out into a temporary detached worktree and returns the
+// directory in it that corresponds to the root path. The caller must call
+// cleanup to remove the worktree.
+func (g *GitDiff) Checkout(rev string) (dir string, cleanup func() error, err error) {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return "", nil, fmt.Errorf("invalid ref %q", rev)
+	}
+	// ルートがリポジトリのサブディレクトリでも同じ場所を返す
+	prefix, err := g.git("rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "llm-reviewer-worktree-")
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := g.git("worktree", "add", "--detach", "--quiet", tmp, rev); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, fmt.Errorf("checkout %s: %w", rev, err)
+	}
+
+	cleanup = func() error {
+		_, err := g.git("worktree", "remove", "--force", tmp)
+		if rmErr := os.RemoveAll(tmp); err == nil {
+			err = rmErr
+		}
+		return err
+	}
+	return filepath.Join(tmp, strings.TrimSpace(prefix)), cleanup, nil
+}
+
+func (g *GitDiff) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
 	cmd.Dir = g.rootPath
 
 	out, err := cmd.Output()
 	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
 		return "", err
 	}
 	return string(out), nil
 }
+
+func orHEAD(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
+
+// Validate rejects contradictory option combinations and refs that git
+// would interpret as command-line flags.
+func (o DiffOptions) Validate() error {
+	for _, ref := range []string{o.Base, o.MergeBase, o.Range} {
+		if strings.HasPrefix(ref, "-") {
+			return fmt.Errorf("invalid ref %q", ref)
+		}
+	}
+	switch o.Mode {
+	case DiffModeAll, DiffModeStaged, DiffModeUnstaged:
+	default:
+		return fmt.Errorf("unknown diff mode %q", o.Mode)
+	}
+	if o.Range != "" {
+		if !strings.Contains(o.Range, "..") {
+			return fmt.Errorf("range %q must be of the form A..B or A...B", o.Range)
+		}
+		if o.Base != "" || o.MergeBase != "" || o.Mode != DiffModeAll {
+			return errors.New("range cannot be combined with base, merge base or mode")
+		}
+	}
+	if o.Base != "" && o.MergeBase != "" {
+		return errors.New("base and merge base are mutually exclusive")
+	}
+	if o.Mode == DiffModeUnstaged && (o.Base != "" || o.MergeBase != "") {
+		return errors.New("unstaged mode compares against the index and takes no base")
+	}
+	return nil
+}
+
+// HasRevision reports whether any of the revision-selecting fields are set.
+func (o DiffOptions) HasRevision() bool {
+	return o.Base != "" || o.MergeBase != "" || o.Range != "" || o.Mode != DiffModeAll
+}
+
+// String describes the diff in git command form, e.g. "git diff --cached main -- internal/".
+func (o DiffOptions) String() string {
+	base := o.Base
+	if o.MergeBase != "" {
+		base = "$(git merge-base " + o.MergeBase + " HEAD)"
+	}
+
+	parts := []string{"git diff"}
+	switch {
+	case o.Range != "":
+		parts = append(parts, o.Range)
+	case o.Mode == DiffModeStaged:
+		parts = append(parts, "--cached", orHEAD(base))
+	case o.Mode == DiffModeUnstaged:
+	default:
+		parts = append(parts, orHEAD(base))
+	}
+	if len(o.Paths) > 0 {
+		parts = append(parts, "--")
+		parts = append(parts, o.Paths...)
+	}
+	return strings.Join(parts, " ")
+}
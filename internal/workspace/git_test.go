@@ -0,0 +1,238 @@
+package workspace
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testRepo is a temporary git repository driven through the git command.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// 利用者の git 設定に結果を左右されないようにする
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "--quiet", "--initial-branch=main")
+	r.git("config", "user.name", "test")
+	r.git("config", "user.email", "test@example.com")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) commit(msg string) {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "--quiet", "-m", msg)
+}
+
+// diffRepo builds a repository whose HEAD, index and working tree differ:
+//
+//	base:    a.go, b.go, sub/c.go
+//	feature: base + f.go
+//	main:    base + a.go and sub/c.go changed
+//	index:   b.go changed (staged)
+//	worktree: a.go changed again (unstaged)
+func diffRepo(t *testing.T) *testRepo {
+	r := newTestRepo(t)
+	r.write("a.go", "a1\n")
+	r.write("b.go", "b1\n")
+	r.write("sub/c.go", "c1\n")
+	r.commit("base")
+	r.git("branch", "base")
+	r.git("checkout", "--quiet", "-b", "feature")
+	r.write("f.go", "f1\n")
+	r.commit("feature")
+	r.git("checkout", "--quiet", "main")
+	r.write("a.go", "a2\n")
+	r.write("sub/c.go", "c2\n")
+	r.commit("main")
+	r.write("b.go", "b2\n")
+	r.git("add", "b.go")
+	r.write("a.go", "a3\n")
+	return r
+}
+
+var diffFileRe = regexp.MustCompile(`(?m)^diff --git a/(\S+) b/`)
+
+func diffFiles(patch string) []string {
+	var files []string
+	for _, m := range diffFileRe.FindAllStringSubmatch(patch, -1) {
+		files = append(files, m[1])
+	}
+	return files
+}
+
+func TestGitDiff(t *testing.T) {
+	r := diffRepo(t)
+	// 出力形式を変える設定があっても解析できる形式で出力される
+	r.git("config", "diff.noprefix", "true")
+	r.git("config", "color.diff", "always")
+
+	tests := []struct {
+		name string
+		root string // リポジトリからの相対パス
+		opts DiffOptions
+		want []string
+	}{
+		{name: "uncommitted", opts: DiffOptions{}, want: []string{"a.go", "b.go"}},
+		{name: "staged", opts: DiffOptions{Mode: DiffModeStaged}, want: []string{"b.go"}},
+		{name: "unstaged", opts: DiffOptions{Mode: DiffModeUnstaged}, want: []string{"a.go"}},
+		{name: "base", opts: DiffOptions{Base: "base"}, want: []string{"a.go", "b.go", "sub/c.go"}},
+		{name: "staged against base", opts: DiffOptions{Base: "base", Mode: DiffModeStaged}, want: []string{"a.go", "b.go", "sub/c.go"}},
+		{name: "merge base", opts: DiffOptions{MergeBase: "feature"}, want: []string{"a.go", "b.go", "sub/c.go"}},
+		{name: "range", opts: DiffOptions{Range: "base..main"}, want: []string{"a.go", "sub/c.go"}},
+		{name: "symmetric range", opts: DiffOptions{Range: "main...feature"}, want: []string{"f.go"}},
+		{name: "paths", opts: DiffOptions{Base: "base", Paths: []string{"sub/"}}, want: []string{"sub/c.go"}},
+		{name: "subdirectory root", root: "sub", opts: DiffOptions{Base: "base"}, want: []string{"c.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := NewGitDiff(filepath.Join(r.dir, tt.root)).Diff(tt.opts)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if got := diffFiles(patch); !slices.Equal(got, tt.want) {
+				t.Errorf("files = %v, want %v\n%s", got, tt.want, patch)
+			}
+			if strings.Contains(patch, "\x1b[") {
+				t.Errorf("patch contains color escapes:\n%q", patch)
+			}
+		})
+	}
+
+	patch, err := NewGitDiff(r.dir).Diff(DiffOptions{Mode: DiffModeUnstaged})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !strings.Contains(patch, "-a2\n+a3\n") {
+		t.Errorf("unstaged diff does not compare the index with the working tree:\n%s", patch)
+	}
+}
+
+func TestGitDiffRevisions(t *testing.T) {
+	r := diffRepo(t)
+	g := NewGitDiff(r.dir)
+	baseSHA := r.git("rev-parse", "base")
+
+	tests := []struct {
+		name       string
+		opts       DiffOptions
+		base, head string
+		err        string
+	}{
+		{name: "uncommitted", opts: DiffOptions{}, base: "HEAD"},
+		{name: "base", opts: DiffOptions{Base: "base"}, base: "base"},
+		{name: "merge base", opts: DiffOptions{MergeBase: "feature"}, base: baseSHA},
+		{name: "range", opts: DiffOptions{Range: "base..feature"}, base: "base", head: "feature"},
+		{name: "open range", opts: DiffOptions{Range: "base.."}, base: "base", head: "HEAD"},
+		{name: "symmetric range", opts: DiffOptions{Range: "main...feature"}, base: baseSHA, head: "feature"},
+		{name: "staged", opts: DiffOptions{Mode: DiffModeStaged}, err: "staged mode compares against the index"},
+		{name: "unstaged", opts: DiffOptions{Mode: DiffModeUnstaged}, err: "unstaged mode compares against the index"},
+		{name: "unknown merge base", opts: DiffOptions{MergeBase: "nope"}, err: "merge-base nope"},
+		{name: "invalid", opts: DiffOptions{Base: "--output=x"}, err: `invalid ref "--output=x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, head, err := g.Revisions(tt.opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Revisions error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Revisions: %v", err)
+			}
+			if base != tt.base || head != tt.head {
+				t.Errorf("Revisions = %q, %q; want %q, %q", base, head, tt.base, tt.head)
+			}
+		})
+	}
+}
+
+func TestDiffOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DiffOptions
+		want string // 空ならエラーにならない
+	}{
+		{name: "zero", opts: DiffOptions{}},
+		{name: "base and staged", opts: DiffOptions{Base: "main", Mode: DiffModeStaged}},
+		{name: "merge base and paths", opts: DiffOptions{MergeBase: "main", Paths: []string{"internal/"}}},
+		{name: "range", opts: DiffOptions{Range: "v1.0...main"}},
+		{name: "flag as base", opts: DiffOptions{Base: "-p"}, want: `invalid ref "-p"`},
+		{name: "flag as merge base", opts: DiffOptions{MergeBase: "--all"}, want: `invalid ref "--all"`},
+		{name: "flag as range", opts: DiffOptions{Range: "--a..b"}, want: `invalid ref "--a..b"`},
+		{name: "unknown mode", opts: DiffOptions{Mode: "cached"}, want: `unknown diff mode "cached"`},
+		{name: "range without dots", opts: DiffOptions{Range: "main"}, want: `range "main" must be of the form A..B or A...B`},
+		{name: "range and base", opts: DiffOptions{Range: "a..b", Base: "c"}, want: "range cannot be combined with base, merge base or mode"},
+		{name: "range and mode", opts: DiffOptions{Range: "a..b", Mode: DiffModeStaged}, want: "range cannot be combined with base, merge base or mode"},
+		{name: "base and merge base", opts: DiffOptions{Base: "a", MergeBase: "b"}, want: "base and merge base are mutually exclusive"},
+		{name: "unstaged with base", opts: DiffOptions{Base: "a", Mode: DiffModeUnstaged}, want: "unstaged mode compares against the index and takes no base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.want == "" && err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			if tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Errorf("Validate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffOptionsString(t *testing.T) {
+	tests := []struct {
+		opts DiffOptions
+		want string
+	}{
+		{DiffOptions{}, "git diff HEAD"},
+		{DiffOptions{Base: "main"}, "git diff main"},
+		{DiffOptions{Mode: DiffModeStaged}, "git diff --cached HEAD"},
+		{DiffOptions{Base: "main", Mode: DiffModeStaged, Paths: []string{"internal/"}}, "git diff --cached main -- internal/"},
+		{DiffOptions{Mode: DiffModeUnstaged}, "git diff"},
+		{DiffOptions{MergeBase: "main"}, "git diff $(git merge-base main HEAD)"},
+		{DiffOptions{Range: "v1..v2", Paths: []string{"a.go", "b.go"}}, "git diff v1..v2 -- a.go b.go"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
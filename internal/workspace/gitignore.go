@@ -0,0 +1,107 @@
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreRule is one pattern line of a .gitignore file.
+type ignoreRule struct {
+	base     string   // .gitignore のあるディレクトリ（ルートからの相対パス、ルートは空）
+	segments []string // "/" で分割したパターン
+	negate   bool     // "!pattern"
+	dirOnly  bool     // "pattern/"
+}
+
+// readIgnoreFile parses a .gitignore-style file. A missing file yields no rules.
+// base is the directory the patterns are relative to.
+func readIgnoreFile(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate, line = true, rest
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // \# や \! で始まるパターン
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			rule.dirOnly, line = true, rest
+		}
+		// 途中に "/" を含むパターンは .gitignore の場所に固定され、含まないパターンはどの階層の名前にも一致する
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		if !anchored {
+			line = "**/" + line
+		}
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether relPath (slash-separated, relative to the root) is
+// excluded by rules. Later rules take precedence, as in git.
+func ignored(rules []ignoreRule, relPath string, isDir bool) bool {
+	result := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel := relPath
+		if r.base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(relPath, r.base+"/"); !ok {
+				continue
+			}
+		}
+		if matchSegments(r.segments, strings.Split(rel, "/")) {
+			result = !r.negate
+		}
+	}
+	return result
+}
+
+// matchGlob matches a slash-separated path against a glob where "**" matches
+// any number of path segments and the other segments follow path.Match.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
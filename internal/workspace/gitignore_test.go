@@ -0,0 +1,91 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/server/main.go", true},
+		{"internal/**", "internal/agent/agent.go", true},
+		{"internal/**", "internal", true},
+		{"internal/**/*_test.go", "internal/a/b/x_test.go", true},
+		{"internal/**/*_test.go", "internal/x_test.go", true},
+		{"internal/**/*_test.go", "cmd/x_test.go", false},
+		{"cmd/?ain.go", "cmd/main.go", true},
+		{"cmd/[a-l]*.go", "cmd/main.go", false},
+		{"[", "[", false}, // 不正なパターンは一致しない
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, ".gitignore")
+	sub := filepath.Join(dir, "sub.gitignore")
+	write := func(file, text string) {
+		if err := os.WriteFile(file, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(root, `# comment
+
+*.log
+!keep.log
+/build
+vendor/
+docs/*.md
+\#notes
+`)
+	write(sub, "generated.go\n/local.txt\n")
+	rules := append(readIgnoreFile(root, ""), readIgnoreFile(sub, "pkg")...)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false}, // 後のルールが優先する
+		{"logs/keep.log", false, false},
+		{"build", true, true},
+		{"build/out.bin", false, false}, // ディレクトリ自体が除外されるので中身は辿られない
+		{"cmd/build", true, false},      // 先頭の "/" はルートに固定する
+		{"vendor", true, true},
+		{"vendor", false, false}, // "vendor/" はディレクトリにだけ一致する
+		{"pkg/vendor", true, true},
+		{"docs/a.md", false, true},
+		{"docs/sub/a.md", false, false},
+		{"#notes", false, true},
+		{"pkg/generated.go", false, true},
+		{"pkg/x/generated.go", false, true},
+		{"generated.go", false, false}, // サブディレクトリの .gitignore はその外に効かない
+		{"pkg/local.txt", false, true},
+		{"pkg/x/local.txt", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := ignored(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestReadIgnoreFileMissing(t *testing.T) {
+	if rules := readIgnoreFile(filepath.Join(t.TempDir(), ".gitignore"), ""); rules != nil {
+		t.Errorf("readIgnoreFile of a missing file = %v, want nil", rules)
+	}
+}
@@ -0,0 +1,57 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	_ FileReader     = (*GitReader)(nil)
+	_ RevisionReader = (*GitReader)(nil)
+)
+
+// GitReader reads files from the git object database instead of the working
+// tree, so it sees committed content even when the file has since changed or
+// been deleted.
+type GitReader struct {
+	rootPath string
+	rev      string // ReadFile が読むリビジョン
+}
+
+// NewGitReader returns a reader whose ReadFile reads files as of rev.
+func NewGitReader(rootPath, rev string) *GitReader {
+	return &GitReader{rootPath: rootPath, rev: rev}
+}
+
+func (r *GitReader) ReadFile(relPath string) (string, error) {
+	return r.ReadFileAt(r.rev, relPath)
+}
+
+// ReadFileAt reads relPath (relative to the root path) as of rev.
+func (r *GitReader) ReadFileAt(rev, relPath string) (string, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") || strings.ContainsAny(rev, ":\n") {
+		return "", fmt.Errorf("invalid revision %q", rev)
+	}
+	// パストラバーサル防止（ルート外のオブジェクトも同じリポジトリにあれば読めてしまう）
+	clean := path.Clean(filepath.ToSlash(relPath))
+	if clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+		return "", fmt.Errorf("path %q is outside project root", relPath)
+	}
+
+	// "rev:./path" はリポジトリルートではなく作業ディレクトリ（プロジェクトルート）からの相対パスになる
+	cmd := exec.Command("git", "cat-file", "blob", rev+":./"+clean)
+	cmd.Dir = r.rootPath
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("read %s at %s: %s", relPath, rev, strings.TrimSpace(strings.TrimPrefix(string(exitErr.Stderr), "fatal: ")))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitReaderReadFileAt(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.go", "old\n")
+	r.write("gone.go", "deleted later\n")
+	r.write("sub/b.go", "b\n")
+	r.write("secret.txt", "outside the project\n")
+	r.commit("first")
+	r.write("a.go", "new\n")
+	if err := os.Remove(filepath.Join(r.dir, "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewGitReader(r.dir, "HEAD")
+	sub := NewGitReader(filepath.Join(r.dir, "sub"), "HEAD")
+
+	tests := []struct {
+		name   string
+		reader *GitReader
+		rev    string
+		path   string
+		want   string
+		err    string
+	}{
+		{name: "committed content", reader: reader, rev: "HEAD", path: "a.go", want: "old\n"},
+		{name: "deleted in the working tree", reader: reader, rev: "HEAD", path: "gone.go", want: "deleted later\n"},
+		{name: "relative to a subdirectory root", reader: sub, rev: "HEAD", path: "b.go", want: "b\n"},
+		{name: "cleaned path", reader: reader, rev: "HEAD", path: "sub/../a.go", want: "old\n"},
+		{name: "missing file", reader: reader, rev: "HEAD", path: "none.go", err: "read none.go at HEAD: "},
+		{name: "unknown revision", reader: reader, rev: "nope", path: "a.go", err: "read a.go at nope: "},
+		{name: "empty revision", reader: reader, rev: "", path: "a.go", err: `invalid revision ""`},
+		{name: "revision as flag", reader: reader, rev: "-p", path: "a.go", err: `invalid revision "-p"`},
+		{name: "revision with path", reader: reader, rev: "HEAD:secret.txt", path: "a.go", err: `invalid revision "HEAD:secret.txt"`},
+		{name: "parent directory", reader: sub, rev: "HEAD", path: "../secret.txt", err: `path "../secret.txt" is outside project root`},
+		{name: "parent directory itself", reader: sub, rev: "HEAD", path: "..", err: `path ".." is outside project root`},
+		{name: "absolute path", reader: reader, rev: "HEAD", path: "/etc/passwd", err: `path "/etc/passwd" is outside project root`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.reader.ReadFileAt(tt.rev, tt.path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ReadFileAt error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFileAt: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadFileAt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// ReadFile はリーダーのリビジョンを読む
+	if got, err := reader.ReadFile("a.go"); err != nil || got != "old\n" {
+		t.Errorf("ReadFile = %q, %v; want %q", got, err, "old\n")
+	}
+}
@@ -0,0 +1,256 @@
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Searcher defines operations for searching the contents of project files.
+type Searcher interface {
+	Search(ctx context.Context, opts SearchOptions) (*SearchResult, error)
+}
+
+// SearchOptions selects what a Searcher looks for.
+type SearchOptions struct {
+	Pattern    string
+	Regex      bool     // Pattern を正規表現（RE2）として扱う。false なら文字列として検索する
+	IgnoreCase bool     // 大文字・小文字を区別しない
+	Paths      []string // 対象を絞り込む glob（例: *.go, internal/**/*_test.go, internal/agent）。空なら全ファイル
+	MaxResults int      // 返す一致の上限。0 以下なら DefaultMaxResults
+}
+
+// DefaultMaxResults is the match limit used when SearchOptions.MaxResults is not set.
+const DefaultMaxResults = 100
+
+// Match is a line that matched a search.
+type Match struct {
+	Path string // プロジェクトルートからの相対パス
+	Line int    // 1-based
+	Text string // 一致した行（長い行は一致箇所の周辺だけ）
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%s:%d: %s", m.Path, m.Line, m.Text)
+}
+
+// SearchResult is the outcome of a search.
+type SearchResult struct {
+	Matches   []Match
+	Truncated bool // 上限に達したため打ち切った
+	Files     int  // 検索したファイル数
+}
+
+// 検索しないファイルの条件です。
+const (
+	maxSearchFileSize = 1 << 20 // 生成物などの巨大なファイル
+	binarySniffLen    = 8000    // 先頭にNULバイトがあればバイナリとみなす（git と同じ判定）
+	maxSnippetLen     = 200
+)
+
+var _ Searcher = (*FSSearcher)(nil)
+
+// FSSearcher searches the files under a root directory in pure Go, skipping
+// .git, files excluded by .gitignore (and .git/info/exclude), binary files and
+// files larger than 1 MiB. Files are visited in lexical order, so results are
+// stable.
+type FSSearcher struct {
+	rootPath string
+}
+
+func NewFSSearcher(rootPath string) *FSSearcher {
+	return &FSSearcher{rootPath: rootPath}
+}
+
+func (s *FSSearcher) Search(ctx context.Context, opts SearchOptions) (*SearchResult, error) {
+	match, err := opts.matcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range opts.Paths {
+		if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid path glob %q: %w", g, err)
+		}
+	}
+	limit := opts.MaxResults
+	if limit <= 0 {
+		limit = DefaultMaxResults
+	}
+
+	w := &searchWalk{ctx: ctx, root: s.rootPath, opts: opts, match: match, limit: limit, result: &SearchResult{}}
+	rules := readIgnoreFile(filepath.Join(s.rootPath, ".git", "info", "exclude"), "")
+	if err := w.dir("", rules); err != nil && !errors.Is(err, errSearchLimit) {
+		return nil, err
+	}
+	return w.result, nil
+}
+
+// matcher returns a function that finds the first match in a line, as a
+// [start, end) byte range.
+func (o SearchOptions) matcher() (func(line string) (int, int, bool), error) {
+	if o.Pattern == "" {
+		return nil, errors.New("search pattern is empty")
+	}
+
+	if !o.Regex && !o.IgnoreCase {
+		return func(line string) (int, int, bool) {
+			i := strings.Index(line, o.Pattern)
+			return i, i + len(o.Pattern), i >= 0
+		}, nil
+	}
+
+	expr := o.Pattern
+	if !o.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if o.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return func(line string) (int, int, bool) {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			return 0, 0, false
+		}
+		return loc[0], loc[1], true
+	}, nil
+}
+
+// errSearchLimit stops the walk once enough matches were found.
+var errSearchLimit = errors.New("search limit reached")
+
+type searchWalk struct {
+	ctx    context.Context
+	root   string
+	opts   SearchOptions
+	match  func(line string) (int, int, bool)
+	limit  int
+	result *SearchResult
+}
+
+// dir searches the directory relDir ("" for the root) recursively. rules are
+// the ignore rules inherited from parent directories.
+func (w *searchWalk) dir(relDir string, rules []ignoreRule) error {
+	absDir := filepath.Join(w.root, filepath.FromSlash(relDir))
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return nil // 読めないディレクトリは飛ばす
+	}
+	// 親の規則を書き換えないようにコピーしてから追加する
+	rules = append(rules[:len(rules):len(rules)], readIgnoreFile(filepath.Join(absDir, ".gitignore"), relDir)...)
+
+	for _, e := range entries {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		relPath := path.Join(relDir, e.Name())
+		if e.IsDir() {
+			if e.Name() == ".git" || ignored(rules, relPath, true) {
+				continue
+			}
+			if err := w.dir(relPath, rules); err != nil {
+				return err
+			}
+			continue
+		}
+		if !e.Type().IsRegular() || ignored(rules, relPath, false) || !w.selected(relPath) {
+			continue
+		}
+		if err := w.file(relPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selected reports whether relPath matches one of the path globs. A glob
+// without "/" matches the file name; otherwise it matches the path or one of
+// its parent directories.
+func (w *searchWalk) selected(relPath string) bool {
+	if len(w.opts.Paths) == 0 {
+		return true
+	}
+	for _, g := range w.opts.Paths {
+		g = strings.TrimSuffix(strings.TrimPrefix(g, "./"), "/")
+		if !strings.Contains(g, "/") {
+			if ok, _ := path.Match(g, path.Base(relPath)); ok {
+				return true
+			}
+		}
+		for p := relPath; p != "."; p = path.Dir(p) {
+			if matchGlob(g, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *searchWalk) file(relPath string) error {
+	f, err := os.Open(filepath.Join(w.root, filepath.FromSlash(relPath)))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || info.Size() > maxSearchFileSize {
+		return nil
+	}
+
+	br := bufio.NewReader(f)
+	if head, _ := br.Peek(binarySniffLen); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+	w.result.Files++
+
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 0, 64<<10), maxSearchFileSize)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		start, end, ok := w.match(line)
+		if !ok {
+			continue
+		}
+		if len(w.result.Matches) == w.limit {
+			w.result.Truncated = true
+			return errSearchLimit
+		}
+		w.result.Matches = append(w.result.Matches, Match{Path: relPath, Line: n, Text: snippet(line, start, end)})
+	}
+	return nil
+}
+
+// snippet trims the line and, if it is long, keeps only the part around the
+// match.
+func snippet(line string, start, end int) string {
+	if len(line) <= maxSnippetLen {
+		return strings.TrimSpace(line)
+	}
+	from := max(0, min(start-(maxSnippetLen-(end-start))/2, len(line)-maxSnippetLen))
+	to := min(len(line), from+maxSnippetLen)
+	// UTF-8 の文字の途中で切らない
+	for from > 0 && !utf8.RuneStart(line[from]) {
+		from--
+	}
+	for to < len(line) && !utf8.RuneStart(line[to]) {
+		to++
+	}
+	s := strings.TrimSpace(line[from:to])
+	if from > 0 {
+		s = "..." + s
+	}
+	if to < len(line) {
+		s += "..."
+	}
+	return s
+}
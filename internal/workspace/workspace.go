@@ -5,7 +5,31 @@ type FileReader interface {
 	ReadFile(path string) (string, error)
 }
 
+// RevisionReader defines operations for reading source files as of a git revision.
+type RevisionReader interface {
+	ReadFileAt(rev, path string) (string, error)
+}
+
 // DiffProvider defines operations for retrieving git diffs.
 type DiffProvider interface {
-	Diff() (string, error)
+	Diff(opts DiffOptions) (string, error)
+}
+
+// DiffMode selects which side of the index a diff looks at.
+type DiffMode string
+
+const (
+	DiffModeAll      DiffMode = ""         // 作業ツリー（ステージ済み＋未ステージ）と base の比較
+	DiffModeStaged   DiffMode = "staged"   // インデックスと base の比較（git diff --cached）
+	DiffModeUnstaged DiffMode = "unstaged" // 作業ツリーとインデックスの比較（git diff）
+)
+
+// DiffOptions selects what a DiffProvider compares. The zero value means
+// "all uncommitted changes against HEAD".
+type DiffOptions struct {
+	Base      string   // 比較元の ref（省略時は HEAD）
+	MergeBase string   // 指定時はこのブランチと HEAD の merge-base を比較元にする
+	Range     string   // "A..B" / "A...B" 形式のコミット範囲。Base・MergeBase・Mode とは併用不可
+	Mode      DiffMode // ステージ済み／未ステージのみに絞る
+	Paths     []string // 対象パスの絞り込み（pathspec）
 }